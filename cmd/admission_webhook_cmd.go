@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/alcideio/rbac-tool/pkg/admission"
+	"github.com/alcideio/rbac-tool/pkg/analysis"
+	"github.com/alcideio/rbac-tool/pkg/cache"
+	"github.com/alcideio/rbac-tool/pkg/utils"
+)
+
+func NewCommandAdmissionWebhook() *cobra.Command {
+
+	input := &inputSource{}
+	listen := ":8443"
+	certFile := ""
+	keyFile := ""
+	customConfig := ""
+	warn := false
+	implicitGroups := true
+	reviews := []string{}
+
+	// Support overrides
+	cmd := &cobra.Command{
+		Use:     "admission-webhook",
+		Aliases: []string{"webhook"},
+		Short:   "Validating admission webhook that rejects RBAC changes producing new analysis findings",
+		Long: `
+A validating admission webhook for Role, ClusterRole, RoleBinding and ClusterRoleBinding create/update requests.
+The subject permissions that would result from the change are evaluated against the analysis rules
+(see 'rbac-tool analysis') - a request that produces a finding the current cluster state does not have is
+rejected, or admitted with warnings when --warn is set. The exclusions of the analysis config apply.
+
+The current cluster state is kept up to date with shared informers - or read from a snapshot archive or resource files.
+AdmissionReview requests are served on /validate.
+
+Examples:
+
+# Serve the webhook with the cluster pointed by current context as the current state
+rbac-tool admission-webhook --tls-cert-file tls.crt --tls-private-key-file tls.key
+
+# Warn instead of reject - with custom analysis rules
+rbac-tool admission-webhook --tls-cert-file tls.crt --tls-private-key-file tls.key --warn --config rules.yaml
+
+# Evaluate an AdmissionReview locally against a snapshot archive
+rbac-tool admission-webhook -f cluster-snapshot.tar.gz --admission-review review.json
+
+`,
+		Args:          cobra.ExactArgs(0),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Hidden:        false,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := input.Validate(); err != nil {
+				return err
+			}
+
+			if (certFile == "") != (keyFile == "") {
+				return fmt.Errorf("Both --tls-cert-file and --tls-private-key-file are required to serve TLS")
+			}
+
+			var err error
+			analysisConfig := analysis.DefaultAnalysisConfig()
+
+			//Override Rules (if provided)
+			if customConfig != "" {
+				analysisConfig, err = analysis.LoadAnalysisConfig(customConfig)
+				if err != nil {
+					return err
+				}
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			var objects func() ([]runtime.Object, error)
+			var generation func() uint64
+			if input.IsOffline() {
+				if _, _, err := input.Load(); err != nil {
+					return err
+				}
+
+				objects = input.Objects
+			} else {
+				client, err := input.NewClient()
+				if err != nil {
+					return err
+				}
+
+				permsCache := cache.NewCache(client.Client, 0)
+				if err := permsCache.Start(ctx); err != nil {
+					return err
+				}
+
+				objects = permsCache.Objects
+				generation = permsCache.Generation
+			}
+
+			reviewer := admission.NewReviewer(objects, analysisConfig, implicitGroups)
+			reviewer.Warn = warn
+			reviewer.Generation = generation
+
+			if len(reviews) > 0 {
+				return printAdmissionReviews(reviewer, reviews)
+			}
+
+			mux := http.NewServeMux()
+			mux.Handle("POST /validate", reviewer)
+			mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			})
+
+			httpServer := &http.Server{Addr: listen, Handler: mux}
+			go func() {
+				<-ctx.Done()
+
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				httpServer.Shutdown(shutdownCtx)
+			}()
+
+			utils.ConsolePrinter(fmt.Sprintf("Serving admission reviews on %v", listen))
+
+			if certFile != "" {
+				err = httpServer.ListenAndServeTLS(certFile, keyFile)
+			} else {
+				err = httpServer.ListenAndServe()
+			}
+
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("Failed to serve - %v", err)
+			}
+
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	input.AddFlags(flags)
+	flags.StringVar(&listen, "listen", listen, "The address to serve on")
+	flags.StringVar(&certFile, "tls-cert-file", "", "TLS certificate file - the API server only calls webhooks over TLS")
+	flags.StringVar(&keyFile, "tls-private-key-file", "", "TLS private key file")
+	flags.StringVar(&customConfig, "config", "", "Load analysis rules configuration from file")
+	flags.BoolVar(&warn, "warn", false, "Admit the requests that produce new findings with warnings instead of rejecting them")
+	flags.BoolVar(&implicitGroups, "implicit-groups", true, "Credit ServiceAccounts with the permissions of their implicit groups (system:serviceaccounts, system:serviceaccounts:<namespace>, system:authenticated)")
+	flags.StringArrayVar(&reviews, "admission-review", reviews, "Evaluate AdmissionReview requests read from the file and print the responses instead of serving")
+
+	return cmd
+}
+
+func printAdmissionReviews(reviewer *admission.Reviewer, fnames []string) error {
+	for _, fname := range fnames {
+		data, err := os.ReadFile(fname)
+		if err != nil {
+			return err
+		}
+
+		review := &admissionv1.AdmissionReview{}
+		if err := json.Unmarshal(data, review); err != nil || review.Request == nil {
+			return fmt.Errorf("Failed to read AdmissionReview '%v' - %v", fname, err)
+		}
+
+		data, err = json.Marshal(reviewer.ReviewAdmission(review))
+		if err != nil {
+			return fmt.Errorf("Processing error - %v", err)
+		}
+
+		fmt.Fprintln(os.Stdout, string(data))
+	}
+
+	return nil
+}
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"os"
 	"sort"
@@ -10,6 +12,8 @@ import (
 	"github.com/alcideio/rbac-tool/pkg/analysis"
 	"github.com/alcideio/rbac-tool/pkg/kube"
 	"github.com/alcideio/rbac-tool/pkg/rbac"
+	"github.com/alcideio/rbac-tool/pkg/utils"
+	"github.com/alcideio/rbac-tool/pkg/visualize"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/yaml"
@@ -17,9 +21,14 @@ import (
 
 func NewCommandAnalysis() *cobra.Command {
 
-	clusterContext := ""
+	input := &inputSource{}
 	customConfig := ""
 	output := "table"
+	implicitGroups := true
+	withWorkloads := false
+	failOn := ""
+	baselineFile := ""
+	writeBaseline := ""
 
 	// Support overrides
 	cmd := &cobra.Command{
@@ -37,11 +46,47 @@ Examples:
 # Analyze RBAC permissions of the cluster pointed by current context
 rbac-tool analyze
 
+# Analyze RBAC permissions of rendered manifests
+helm template ./mychart | rbac-tool analyze -f -
+
+# Analyze RBAC permissions and map the findings to the workloads that run as each ServiceAccount
+rbac-tool analyze --workloads -o table
+
+# Analyze the RBAC resources of a manifests directory as SARIF 2.1.0 - the results point at the bindings that granted the access
+rbac-tool analyze -f manifests/ -o sarif > rbac.sarif
+
+# Gate a pipeline on the analysis - exit with an error on HIGH or CRITICAL findings, with a JUnit report for the CI
+rbac-tool analyze -f manifests/ -o junit --fail-on HIGH > rbac-analysis.xml
+
+# Generate an HTML report of the findings - with the RBAC subgraph of each flagged subject
+rbac-tool analyze -o html > rbac-analysis.html
+
+# Report only the findings that are not in the baseline - and save the current findings as the next baseline
+rbac-tool analyze -f manifests/ --baseline rbac-baseline.json --write-baseline rbac-baseline.json -o table
+
+# Analyze RBAC permissions of every cluster in the kubeconfig - with a combined summary of findings by rule and severity
+rbac-tool analyze --all-contexts -o table
+
+With --workloads the Pods and their owners (Deployments, StatefulSets, DaemonSets, Jobs, etc.) are loaded:
+  * Each ServiceAccount finding lists the workloads that run as the ServiceAccount and how many of their
+    Pods mount its token
+  * Rules can reference the workloads with 'subject.workloads' - a list of {kind, namespace, name, pods, tokenMountedPods}
+  * The severity of findings of ServiceAccounts that are not used by any workload is lowered
+
+With --baseline the findings that are already in a previous report (-o json or --write-baseline) are suppressed:
+  * Only the new findings are reported - and checked against --fail-on
+  * The findings of the baseline that are no longer found are reported as resolved
+  * Findings are matched by their fingerprint - the rule, the subject and the bindings that grant the access
+
 `,
 		Hidden: false,
 		RunE: func(c *cobra.Command, args []string) error {
 			var err error
 
+			if failOn != "" && !analysis.IsSeverity(failOn) {
+				return fmt.Errorf("Unsupported --fail-on severity '%v' - use CRITICAL, HIGH, MEDIUM, LOW or INFO", failOn)
+			}
+
 			analysisConfig := analysis.DefaultAnalysisConfig()
 
 			//Override Rules (if provided)
@@ -52,76 +97,235 @@ rbac-tool analyze
 				}
 			}
 
-			client, err := kube.NewClient(clusterContext)
+			clusters, err := input.Clusters()
 			if err != nil {
-				return fmt.Errorf("Failed to create kubernetes client - %v", err)
+				return err
 			}
 
-			perms, err := rbac.NewPermissionsFromCluster(client)
-			if err != nil {
-				return err
+			if output == "html" && input.IsMultiCluster() {
+				return fmt.Errorf("The html output supports a single cluster - use one --cluster-context")
 			}
 
-			permsPerSubject := rbac.NewSubjectPermissions(perms)
-			policies := rbac.NewSubjectPermissionsList(permsPerSubject)
+			if (baselineFile != "" || writeBaseline != "") && input.IsMultiCluster() {
+				return fmt.Errorf("A baseline supports a single cluster - use one --cluster-context")
+			}
 
-			analyzer := analysis.CreateAnalyzer(analysisConfig, policies)
-			if analyzer == nil {
-				return fmt.Errorf("Failed to create analyzer")
+			var baseline *analysis.AnalysisReport
+			if baselineFile != "" {
+				baseline, err = analysis.LoadBaseline(baselineFile)
+				if err != nil {
+					return err
+				}
 			}
 
-			report, err := analyzer.Analyze()
-			if err != nil {
-				return err
+			//analyze returns the report and the RBAC resources of the cluster - the html report renders the subgraph of each flagged subject
+			analyze := func(input *inputSource) (*analysis.AnalysisReport, *rbac.Permissions, error) {
+				client, perms, err := input.Load()
+				if err != nil {
+					return nil, nil, err
+				}
+
+				var permsPerSubject []rbac.SubjectPermissions
+				if implicitGroups {
+					permsPerSubject = rbac.NewEffectiveSubjectPermissions(perms)
+				} else {
+					permsPerSubject = rbac.NewSubjectPermissions(perms)
+				}
+				policies := rbac.NewSubjectPermissionsList(permsPerSubject)
+
+				var workloads map[string][]analysis.Workload
+				if withWorkloads {
+					objs, err := input.ListWorkloads(client)
+					if err != nil {
+						return nil, nil, err
+					}
+
+					workloads = analysis.NewWorkloads(objs, perms.ServiceAccounts)
+				}
+
+				analyzer := analysis.CreateWorkloadAnalyzer(analysisConfig, policies, workloads)
+				if analyzer == nil {
+					return nil, nil, fmt.Errorf("Failed to create analyzer")
+				}
+
+				report, err := analyzer.Analyze()
+				return report, perms, err
 			}
 
-			switch output {
-			case "table":
-				rows := [][]string{}
+			query := func(input *inputSource) (*analysis.AnalysisReport, error) {
+				report, _, err := analyze(input)
+				return report, err
+			}
+
+			header := []string{"TYPE", "SUBJECT", "NAMESPACE", "RULE", "SEVERITY", "INFO", "RECOMMENDATION", "REFERENCES"}
+			if withWorkloads {
+				header = append(header, "USED BY WORKLOADS")
+			}
+
+			if input.IsMultiCluster() {
+				results := queryClusters(clusters, query)
+
+				reports := map[string]*analysis.AnalysisReport{}
+				for _, r := range results {
+					reports[r.Cluster] = r.Result
+
+					if r.Result != nil {
+						utils.ConsolePrinter(fmt.Sprintf("[%v] %v", r.Cluster, analysis.StatsSummary(r.Result)))
+					}
+				}
+
+				multiClusterReport := struct {
+					Clusters []clusterResult[*analysis.AnalysisReport]
+					Summary  []analysis.RuleSummary
+				}{
+					Clusters: results,
+					Summary:  analysis.Summarize(reports),
+				}
+
+				switch output {
+				case "table":
+					rows := [][]string{}
+					for _, r := range results {
+						if r.Result == nil {
+							continue
+						}
+
+						for _, row := range analysisRows(r.Result, withWorkloads) {
+							rows = append(rows, append([]string{r.Cluster}, row...))
+						}
+					}
+
+					renderAnalysisTable(append([]string{"CLUSTER"}, header...), rows)
+
+					summaryRows := [][]string{}
+					for _, s := range multiClusterReport.Summary {
+						summaryRows = append(summaryRows, []string{
+							s.RuleName,
+							s.Severity,
+							fmt.Sprintf("%v", s.Findings),
+							strings.Join(s.Clusters, ","),
+						})
+					}
+
+					fmt.Fprintln(os.Stdout)
+					renderAnalysisTable([]string{"RULE", "SEVERITY", "FINDINGS", "CLUSTERS"}, summaryRows)
+
+				case "yaml":
+					data, err := yaml.Marshal(&multiClusterReport)
+					if err != nil {
+						return fmt.Errorf("Processing error - %v", err)
+					}
+					fmt.Fprintln(os.Stdout, string(data))
+
+				case "json":
+					data, err := json.Marshal(&multiClusterReport)
+					if err != nil {
+						return fmt.Errorf("Processing error - %v", err)
+					}
+
+					fmt.Fprintln(os.Stdout, string(data))
+
+				case "junit":
+					suites := []analysis.JUnitTestSuite{}
+					for _, r := range results {
+						if r.Result == nil {
+							continue
+						}
+
+						suites = append(suites, analysis.NewJUnitTestSuite(r.Result, analysisConfig, r.Cluster))
+					}
+
+					if err := printJUnit(analysis.NewJUnitTestSuites(suites...)); err != nil {
+						return err
+					}
+
+				case "csv":
+					rows := [][]string{}
+					for _, r := range results {
+						if r.Result == nil {
+							continue
+						}
 
-				for _, f := range report.Findings {
+						for _, row := range analysisRows(r.Result, withWorkloads) {
+							rows = append(rows, append([]string{r.Cluster}, row...))
+						}
+					}
+
+					if err := printCSV(append([]string{"CLUSTER"}, header...), rows); err != nil {
+						return err
+					}
 
-					row := []string{
-						f.Subject.Kind,
-						f.Subject.Name,
-						f.Subject.Namespace,
-						f.Finding.RuleName,
-						strings.ToUpper(f.Finding.Severity),
+				case "sarif":
+					runs := []analysis.SarifRun{}
+					for _, r := range results {
+						if r.Result == nil {
+							continue
+						}
+
+						runs = append(runs, analysis.NewSarifRun(r.Result, analysisConfig, nil, Version, "rbac-tool/analysis/"+r.Cluster+"/"))
+					}
 
-						f.Finding.Message,
-						f.Finding.Recommendation,
-						strings.Join(f.Finding.References, ","),
+					if err := printSarif(analysis.NewSarifLog(runs...)); err != nil {
+						return err
 					}
-					rows = append(rows, row)
+
+				default:
+					return fmt.Errorf("Unsupported output format")
 				}
 
-				sort.Slice(rows, func(i, j int) bool {
-					if strings.Compare(rows[i][0], rows[j][0]) == 0 {
-						return (strings.Compare(rows[i][1], rows[j][1]) < 0)
+				if err := reportClusterFailures(results); err != nil {
+					return err
+				}
+
+				findings := []analysis.AnalysisReportFinding{}
+				for _, r := range results {
+					if r.Result != nil {
+						findings = append(findings, r.Result.Findings...)
 					}
+				}
+
+				return failOnFindings(findings, failOn)
+			}
+
+			report, perms, err := analyze(clusters[0])
+			if err != nil {
+				return err
+			}
+
+			//The updated baseline has all the findings - before the baseline findings are suppressed
+			if writeBaseline != "" {
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
 
-					return (strings.Compare(rows[i][0], rows[j][0]) < 0)
-				})
+				if err := utils.WriteFile(writeBaseline, string(data)); err != nil {
+					return fmt.Errorf("Failed to write baseline '%v' - %v", writeBaseline, err)
+				}
+			}
+
+			if baseline != nil {
+				analysis.ApplyBaseline(report, baseline, baselineFile)
+				utils.ConsolePrinter(analysis.BaselineSummary(report))
+			}
+
+			utils.ConsolePrinter(analysis.StatsSummary(report))
 
-				table := tablewriter.NewWriter(os.Stdout)
-				table.SetHeader([]string{"TYPE", "SUBJECT", "NAMESPACE", "RULE", "SEVERITY", "INFO", "RECOMMENDATION", "REFERENCES"})
-				table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
-				//table.SetAutoMergeCells(true)
-				table.SetBorder(false)
-				table.SetAlignment(tablewriter.ALIGN_LEFT)
-				//table.SetAutoMergeCells(true)
+			switch output {
+			case "table":
+				renderAnalysisTable(header, analysisRows(report, withWorkloads))
 
-				table.AppendBulk(rows)
-				table.Render()
+				if report.Baseline != nil && len(report.Baseline.Resolved) > 0 {
+					fmt.Fprintln(os.Stdout, "\nResolved Findings:")
+					renderAnalysisTable(header, analysisRows(&analysis.AnalysisReport{Findings: report.Baseline.Resolved}, withWorkloads))
+				}
 
-				return nil
 			case "yaml":
 				data, err := yaml.Marshal(report)
 				if err != nil {
 					return fmt.Errorf("Processing error - %v", err)
 				}
 				fmt.Fprintln(os.Stdout, string(data))
-				return nil
 
 			case "json":
 				data, err := json.Marshal(report)
@@ -130,19 +334,59 @@ rbac-tool analyze
 				}
 
 				fmt.Fprintln(os.Stdout, string(data))
-				return nil
+
+			case "sarif":
+				locate, err := bindingLocator(clusters[0])
+				if err != nil {
+					return err
+				}
+
+				if err := printSarif(analysis.NewSarifLog(analysis.NewSarifRun(report, analysisConfig, locate, Version, ""))); err != nil {
+					return err
+				}
+
+			case "junit":
+				if err := printJUnit(analysis.NewJUnitTestSuites(analysis.NewJUnitTestSuite(report, analysisConfig, analysisConfig.Name))); err != nil {
+					return err
+				}
+
+			case "csv":
+				if err := printCSV(header, analysisRows(report, withWorkloads)); err != nil {
+					return err
+				}
+
+			case "html":
+				htmlReport := visualize.AnalysisHtmlReport{
+					Title:       analysisTitle(clusters[0]),
+					Report:      report,
+					Permissions: perms,
+				}
+
+				out, err := htmlReport.Generate()
+				if err != nil {
+					return err
+				}
+
+				fmt.Fprintln(os.Stdout, out)
 
 			default:
 				return fmt.Errorf("Unsupported output format")
 			}
+
+			return failOnFindings(report.Findings, failOn)
 		},
 	}
 
 	flags := cmd.Flags()
 	flags.StringVarP(&customConfig, "config", "c", "", "Load custom analysis customConfig")
 
-	flags.StringVar(&clusterContext, "cluster-context", "", "Cluster Context .use 'kubectl config get-contexts' to list available contexts")
-	flags.StringVarP(&output, "output", "o", "yaml", "Output type: table | json | yaml")
+	input.AddMultiClusterFlags(flags)
+	flags.StringVarP(&output, "output", "o", "yaml", "Output type: table | json | yaml | sarif | junit | csv | html")
+	flags.StringVar(&baselineFile, "baseline", "", "A previous analysis report (JSON or YAML) - report only the findings that are not in it, and the findings it has that were resolved")
+	flags.StringVar(&writeBaseline, "write-baseline", "", "Save the findings of this run as a baseline for the next runs (may be the --baseline file)")
+	flags.StringVar(&failOn, "fail-on", "", "Exit with an error when there are findings at or above the severity (CRITICAL, HIGH, MEDIUM, LOW or INFO)")
+	flags.BoolVar(&withWorkloads, "workloads", false, "Load the Pods and their owners - attach the workloads that run as each ServiceAccount to its findings and lower the severity of unused ServiceAccounts")
+	flags.BoolVar(&implicitGroups, "implicit-groups", true, "Credit ServiceAccounts with the permissions of their implicit groups (system:serviceaccounts, system:serviceaccounts:<namespace>, system:authenticated)")
 
 	cmd.AddCommand(
 		NewCommandGenerateAnalysisConfig(),
@@ -151,6 +395,143 @@ rbac-tool analyze
 	return cmd
 }
 
+func analysisRows(report *analysis.AnalysisReport, withWorkloads bool) [][]string {
+	rows := [][]string{}
+
+	for _, f := range report.Findings {
+
+		row := []string{
+			f.Subject.Kind,
+			f.Subject.Name,
+			f.Subject.Namespace,
+			f.Finding.RuleName,
+			strings.ToUpper(f.Finding.Severity),
+
+			f.Finding.Message,
+			f.Finding.Recommendation,
+			strings.Join(f.Finding.References, ","),
+		}
+
+		if withWorkloads {
+			workloads := make([]string, 0, len(f.Workloads))
+			for _, w := range f.Workloads {
+				workloads = append(workloads, w.String())
+			}
+			row = append(row, strings.Join(workloads, "\n"))
+		}
+
+		rows = append(rows, row)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if strings.Compare(rows[i][0], rows[j][0]) == 0 {
+			return (strings.Compare(rows[i][1], rows[j][1]) < 0)
+		}
+
+		return (strings.Compare(rows[i][0], rows[j][0]) < 0)
+	})
+
+	return rows
+}
+
+// analysisTitle names what was analyzed - the manifests or the cluster context
+func analysisTitle(input *inputSource) string {
+	if input.IsOffline() {
+		return input.Infile
+	}
+
+	if input.ClusterContext != "" {
+		return input.ClusterContext
+	}
+
+	context, err := kube.CurrentContext()
+	if err != nil {
+		return ""
+	}
+
+	return context
+}
+
+// bindingLocator locates the bindings in the manifest files of the input - nil for clusters and snapshot archives
+func bindingLocator(input *inputSource) (analysis.BindingLocator, error) {
+	if !input.IsOffline() {
+		return nil, nil
+	}
+
+	snap, err := input.Snapshot()
+	if err != nil || snap != nil {
+		return nil, err
+	}
+
+	locations, err := utils.LocateObjects(input.Infile)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to locate the resources of '%v' - %v", input.Infile, err)
+	}
+
+	return func(binding rbac.BindingRef) (string, int, bool) {
+		l, found := locations[utils.ObjectLocationKey(binding.Kind, binding.Namespace, binding.Name)]
+		return l.File, l.Line, found
+	}, nil
+}
+
+// failOnFindings returns an error when there are findings at or above the severity
+func failOnFindings(findings []analysis.AnalysisReportFinding, severity string) error {
+	if severity == "" {
+		return nil
+	}
+
+	if count := analysis.CountAtSeverity(findings, severity); count > 0 {
+		return fmt.Errorf("Found %v findings at or above %v severity", count, strings.ToUpper(severity))
+	}
+
+	return nil
+}
+
+func printJUnit(suites *analysis.JUnitTestSuites) error {
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Processing error - %v", err)
+	}
+
+	fmt.Fprintln(os.Stdout, xml.Header+string(data))
+	return nil
+}
+
+func printCSV(header []string, rows [][]string) error {
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write(header); err != nil {
+		return fmt.Errorf("Processing error - %v", err)
+	}
+
+	if err := w.WriteAll(rows); err != nil {
+		return fmt.Errorf("Processing error - %v", err)
+	}
+
+	return nil
+}
+
+func printSarif(log *analysis.SarifLog) error {
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Processing error - %v", err)
+	}
+
+	fmt.Fprintln(os.Stdout, string(data))
+	return nil
+}
+
+func renderAnalysisTable(header []string, rows [][]string) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(header)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	//table.SetAutoMergeCells(true)
+	table.SetBorder(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	table.AppendBulk(rows)
+	table.Render()
+}
+
 func NewCommandGenerateAnalysisConfig() *cobra.Command {
 	return &cobra.Command{
 		Use:     "generate",
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apiserver/pkg/apis/audit"
+	"k8s.io/klog"
+	"sigs.k8s.io/yaml"
+
+	auditutil "github.com/alcideio/rbac-tool/pkg/audit"
+	"github.com/alcideio/rbac-tool/pkg/utils"
+)
+
+func NewCommandAuditReplay() *cobra.Command {
+
+	input := &inputSource{}
+	auditSources := []string{}
+	skipUsers := []string{}
+	skipGroups := []string{}
+	output := "table"
+
+	// Support overrides
+	cmd := &cobra.Command{
+		Use:     "audit-replay",
+		Aliases: []string{"replay"},
+		Short:   "Replay audit events against a proposed set of RBAC resources and report the requests that would be denied",
+		Long: `
+Replay the requests recorded in Kubernetes audit events against a proposed set of Roles, ClusterRoles and bindings,
+and report every request that was allowed in the log but would be denied under the proposed RBAC -
+grouped by subject and verb/resource. A dry run for permission reductions, e.g. of roles generated by 'rbac-tool auditgen'.
+
+Requests of system:masters members, and of the nodes (system:node:<name> users and system:nodes members, authorized by
+the Node authorizer), are skipped - they are not subject to RBAC.
+Requests authorized by other authorizers (e.g. a webhook) would be reported as denied - skip their users and groups
+with --skip-user and --skip-group.
+
+Examples:
+
+# Would the tightened roles in proposed/ break any request recorded in audit.log
+rbac-tool audit-replay -f audit.log --rbac-file proposed/
+
+# Skip the requests of the CI users - authorized by a webhook
+rbac-tool audit-replay -f audit.log --rbac-file proposed/ --skip-group ci-bots --skip-user deployer
+
+# Replay the roles generated by auditgen
+rbac-tool auditgen -f audit.log > generated.yaml && rbac-tool audit-replay -f audit.log --rbac-file generated.yaml
+
+`,
+		Args:          cobra.ExactArgs(0),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(auditSources) == 0 {
+				return fmt.Errorf("--filename is required")
+			}
+
+			if input.Infile == "" {
+				return fmt.Errorf("--rbac-file is required")
+			}
+
+			_, perms, err := input.Load()
+			if err != nil {
+				return err
+			}
+
+			replayer := auditutil.NewReplayer(perms)
+			replayer.SkipUsers.Insert(skipUsers...)
+			replayer.SkipGroups.Insert(skipGroups...)
+
+			errs := []error{}
+			results, err := auditutil.ReadAuditEvents(auditSources, auditutil.IsCompleted)
+			if err != nil {
+				errs = append(errs, err)
+			}
+
+			for result := range results {
+				if result.Err != nil {
+					errs = append(errs, result.Err)
+					klog.V(7).Infof("skipping %v", result.Err)
+					continue
+				}
+
+				replayer.Replay(result.Obj.(*audit.Event))
+			}
+
+			report := replayer.Report()
+
+			switch output {
+			case "table":
+				denied := 0
+				rows := [][]string{}
+				for _, s := range report {
+					for _, d := range s.Denied {
+						denied += d.Count
+
+						resource := d.Path
+						if d.Resource != "" {
+							resource = d.Resource
+							if d.APIGroup != "" {
+								resource = fmt.Sprintf("%v.%v", d.Resource, d.APIGroup)
+							}
+							if d.Subresource != "" {
+								resource = fmt.Sprintf("%v/%v", resource, d.Subresource)
+							}
+						}
+
+						rows = append(rows, []string{
+							s.Kind,
+							s.Name,
+							s.Namespace,
+							d.Verb,
+							resource,
+							strings.Join(d.Namespaces, ","),
+							fmt.Sprintf("%v", d.Count),
+						})
+					}
+				}
+
+				table := tablewriter.NewWriter(os.Stdout)
+				table.SetHeader([]string{"TYPE", "SUBJECT", "NAMESPACE", "VERB", "RESOURCE", "IN NAMESPACES", "REQUESTS"})
+				table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+				table.SetBorder(false)
+				table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+				table.AppendBulk(rows)
+				table.Render()
+
+				utils.ConsolePrinter(fmt.Sprintf("%v of %v allowed requests would be denied", denied, replayer.Replayed))
+
+			case "yaml":
+				data, err := yaml.Marshal(&report)
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
+				fmt.Fprintln(os.Stdout, string(data))
+
+			case "json":
+				data, err := json.Marshal(&report)
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
+
+				fmt.Fprintln(os.Stdout, string(data))
+
+			default:
+				return fmt.Errorf("Unsupported output format")
+			}
+
+			return errors.NewAggregate(errs)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringArrayVarP(&auditSources, "filename", "f", auditSources, "File, Directory, URL, or - for STDIN to read audit events from")
+	flags.StringVar(&input.Infile, "rbac-file", "", "The proposed RBAC resources - a file, a directory or a snapshot archive (see 'rbac-tool snapshot')")
+	flags.StringSliceVar(&skipUsers, "skip-user", skipUsers, "Users whose requests are not replayed - e.g. users authorized by a webhook")
+	flags.StringSliceVar(&skipGroups, "skip-group", skipGroups, "Groups whose members' requests are not replayed - e.g. groups authorized by a webhook")
+	flags.StringVarP(&output, "output", "o", "table", "Output type: table | json | yaml")
+
+	return cmd
+}
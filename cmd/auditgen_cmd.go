@@ -223,19 +223,19 @@ func (a *AuditGenOpts) Run() error {
 		defer f.Flush()
 
 		for _, obj := range generated.Roles {
-			fmt.Fprintln(f, "\n---\n")
+			fmt.Fprint(f, "\n---\n\n")
 			auditutil.Output(f, obj, a.OutputFormat)
 		}
 		for _, obj := range generated.ClusterRoles {
-			fmt.Fprintln(f, "\n---\n")
+			fmt.Fprint(f, "\n---\n\n")
 			auditutil.Output(f, obj, a.OutputFormat)
 		}
 		for _, obj := range generated.RoleBindings {
-			fmt.Fprintln(f, "\n---\n")
+			fmt.Fprint(f, "\n---\n\n")
 			auditutil.Output(f, obj, a.OutputFormat)
 		}
 		for _, obj := range generated.ClusterRoleBindings {
-			fmt.Fprintln(f, "\n---\n")
+			fmt.Fprint(f, "\n---\n\n")
 			auditutil.Output(f, obj, a.OutputFormat)
 		}
 	}
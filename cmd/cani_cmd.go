@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	rbacv1 "k8s.io/api/rbac/v1"
+	validation_helper "k8s.io/component-helpers/auth/rbac/validation"
+	"k8s.io/klog"
+	rbacv1helpers "k8s.io/kubernetes/pkg/apis/rbac/v1"
+	"k8s.io/kubernetes/pkg/registry/rbac/validation"
+	"sigs.k8s.io/yaml"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+func NewCommandCanI() *cobra.Command {
+
+	input := &inputSource{}
+	implicitGroups := true
+	namespace := "default"
+	list := false
+	output := "table"
+
+	// Support overrides
+	cmd := &cobra.Command{
+		Use:           "can-i --list <SUBJECT>",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Short:         "List the effective rules of a subject in a namespace - like 'kubectl auth can-i --list --as'",
+		Long: `
+List the effective rules of any User, Group or ServiceAccount in a namespace - the cluster-wide rules and the namespace rules,
+including the rules of the implicit groups (system:authenticated, and the ServiceAccount groups).
+Users and Groups are evaluated as the authenticated requests of 'kubectl --as' - who-can, explain and the permission
+reports credit only ServiceAccounts with their implicit groups.
+
+The output has the same shape as 'kubectl auth can-i --list --as', but is computed from the RBAC resources
+and requires no impersonation rights on the cluster.
+
+* SUBJECT is one of sa:<namespace>/<name>, user:<name> or group:<name>
+
+Examples:
+
+# What can the ServiceAccount ci/deployer do in namespace payments
+rbac-tool can-i --list sa:ci/deployer -n payments
+
+# What can the group devs do - based on a snapshot archive
+rbac-tool can-i --list group:devs -f cluster-snapshot.tar.gz
+
+`,
+		Hidden: false,
+		RunE: func(c *cobra.Command, args []string) error {
+			if !list {
+				return fmt.Errorf("Only --list is supported - use 'rbac-tool explain' to check a specific action")
+			}
+
+			subject, err := rbac.ParseSubject(args[0])
+			if err != nil {
+				return err
+			}
+
+			_, perms, err := input.Load()
+			if err != nil {
+				return err
+			}
+
+			authz := rbac.NewAuthorizer(perms)
+
+			rules, err := authz.RulesFor(rbac.RequestUser(subject, implicitGroups), namespace)
+			if err != nil {
+				klog.V(3).Infof("Some rules could not be resolved - %v", err)
+			}
+
+			rules, err = compactPolicyRules(rules)
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "table":
+				return printAccess(os.Stdout, rules)
+
+			case "yaml":
+				data, err := yaml.Marshal(&rules)
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
+				fmt.Fprintln(os.Stdout, string(data))
+				return nil
+
+			case "json":
+				data, err := json.Marshal(&rules)
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
+
+				fmt.Fprintln(os.Stdout, string(data))
+				return nil
+
+			default:
+				return fmt.Errorf("Unsupported output format")
+			}
+		},
+	}
+
+	flags := cmd.Flags()
+	input.AddFlags(flags)
+	flags.BoolVar(&list, "list", false, "List all the rules of the subject in the namespace")
+	flags.StringVarP(&namespace, "namespace", "n", "default", "The namespace to list the rules in")
+	flags.StringVarP(&output, "output", "o", "table", "Output type: table | json | yaml")
+	flags.BoolVar(&implicitGroups, "implicit-groups", true, "Credit the subject with the permissions of the groups the API server adds to its requests (system:authenticated - and system:serviceaccounts, system:serviceaccounts:<namespace> for ServiceAccounts)")
+
+	return cmd
+}
+
+// compactPolicyRules breaks down and compacts the rules the same way 'kubectl auth can-i --list' does
+func compactPolicyRules(rules []rbacv1.PolicyRule) ([]rbacv1.PolicyRule, error) {
+	breakdownRules := []rbacv1.PolicyRule{}
+	for _, rule := range rules {
+		breakdownRules = append(breakdownRules, validation_helper.BreakdownRule(rule)...)
+	}
+
+	compactRules, err := validation.CompactRules(breakdownRules)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Stable(rbacv1helpers.SortableRuleSlice(compactRules))
+
+	return compactRules, nil
+}
+
+func printAccess(out io.Writer, rules []rbacv1.PolicyRule) error {
+	w := tabwriter.NewWriter(out, 6, 4, 3, ' ', 0)
+
+	fmt.Fprintln(w, "Resources\tNon-Resource URLs\tResource Names\tVerbs")
+
+	for _, r := range rules {
+		fmt.Fprintf(w, "%s\t%v\t%v\t%v\n", combineResourceGroup(r.Resources, r.APIGroups), r.NonResourceURLs, r.ResourceNames, r.Verbs)
+	}
+
+	return w.Flush()
+}
+
+// combineResourceGroup renders the resource the way kubectl does, e.g. deployments.apps/scale
+func combineResourceGroup(resource, group []string) string {
+	if len(resource) == 0 {
+		return ""
+	}
+
+	parts := strings.SplitN(resource[0], "/", 2)
+	combine := parts[0]
+
+	if len(group) > 0 && group[0] != "" {
+		combine = combine + "." + group[0]
+	}
+
+	if len(parts) == 2 {
+		combine = combine + "/" + parts[1]
+	}
+
+	return combine
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/alcideio/rbac-tool/pkg/diff"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+func NewCommandDiff() *cobra.Command {
+
+	output := "table"
+	implicitGroups := true
+
+	// Support overrides
+	cmd := &cobra.Command{
+		Use:   "diff <left> <right>",
+		Short: "Show the effective RBAC permission changes between two clusters, snapshots or manifests",
+		Long: `
+Compare the effective permissions of two RBAC permission sets and report the subjects
+that gained or lost permissions, along with the bindings and roles responsible.
+
+Each side is one of:
+  * A path - a resource file, a directory of manifests or a snapshot archive (see 'rbac-tool snapshot')
+  * A kubeconfig context - 'context:<name>', or 'context:' for the current context
+
+Examples:
+
+# Compare two clusters
+rbac-tool diff context:staging context:production
+
+# Compare a snapshot taken last week with the current cluster
+rbac-tool diff last-week.tar.gz context:
+
+# Review the effective access change of a proposed manifest change - as a Markdown PR comment
+rbac-tool diff ./rbac-main ./rbac-pr -o markdown
+
+`,
+		Args:   cobra.ExactArgs(2),
+		Hidden: false,
+		RunE: func(c *cobra.Command, args []string) error {
+			left, err := loadDiffSide(args[0], implicitGroups)
+			if err != nil {
+				return fmt.Errorf("Failed to load '%v' - %v", args[0], err)
+			}
+
+			right, err := loadDiffSide(args[1], implicitGroups)
+			if err != nil {
+				return fmt.Errorf("Failed to load '%v' - %v", args[1], err)
+			}
+
+			diffs := diff.Diff(left, right)
+
+			switch output {
+			case "table":
+				renderDiffTable(os.Stdout, diffs)
+				return nil
+
+			case "markdown", "md":
+				renderDiffMarkdown(os.Stdout, args[0], args[1], diffs)
+				return nil
+
+			case "yaml":
+				data, err := yaml.Marshal(&diffs)
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
+				fmt.Fprintln(os.Stdout, string(data))
+				return nil
+
+			case "json":
+				data, err := json.Marshal(&diffs)
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
+
+				fmt.Fprintln(os.Stdout, string(data))
+				return nil
+
+			default:
+				return fmt.Errorf("Unsupported output format")
+			}
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&output, "output", "o", "table", "Output type: table | json | yaml | markdown")
+	flags.BoolVar(&implicitGroups, "implicit-groups", true, "Credit ServiceAccounts with the permissions of their implicit groups (system:serviceaccounts, system:serviceaccounts:<namespace>, system:authenticated)")
+
+	return cmd
+}
+
+// newDiffInputSource resolves a diff argument into a kubeconfig context ('context:<name>') or a path
+func newDiffInputSource(arg string) (*inputSource, error) {
+	if strings.HasPrefix(arg, "context:") {
+		return &inputSource{ClusterContext: strings.TrimPrefix(arg, "context:")}, nil
+	}
+
+	if arg == "-" {
+		return &inputSource{Infile: arg}, nil
+	}
+
+	if _, err := os.Stat(arg); err != nil {
+		return nil, fmt.Errorf("%v - use 'context:%v' to compare a kubeconfig context", err, arg)
+	}
+
+	return &inputSource{Infile: arg}, nil
+}
+
+func loadDiffSide(arg string, implicitGroups bool) ([]rbac.SubjectPolicyList, error) {
+	input, err := newDiffInputSource(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	_, perms, err := input.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if implicitGroups {
+		return rbac.NewSubjectPermissionsList(rbac.NewEffectiveSubjectPermissions(perms)), nil
+	}
+
+	return rbac.NewSubjectPermissionsList(rbac.NewSubjectPermissions(perms)), nil
+}
+
+var diffColumns = []string{"CHANGE", "TYPE", "SUBJECT", "VERBS", "NAMESPACE", "API GROUP", "KIND", "NAMES", "NonResourceURI", "GRANTED BY", "ORIGINATED FROM"}
+
+func diffRows(diffs []diff.SubjectDiff) [][]string {
+	rows := [][]string{}
+
+	for _, d := range diffs {
+		var subject string
+		if d.Kind == "ServiceAccount" {
+			subject = fmt.Sprintf("%v/%v", d.Namespace, d.Name)
+		} else {
+			subject = d.Name
+		}
+
+		changes := []struct {
+			change string
+			rules  []rbac.NamespacedPolicyRule
+		}{
+			{"+", d.Added},
+			{"-", d.Removed},
+		}
+
+		for _, change := range changes {
+			for _, rule := range change.rules {
+				originatedFrom := renderOriginatedFromColumn(rule.Namespace, rule.OriginatedFrom)
+				if rule.InheritedFrom != "" {
+					originatedFrom = fmt.Sprintf("%v (via Group>>%v)", originatedFrom, rule.InheritedFrom)
+				}
+
+				rows = append(rows, []string{
+					change.change,
+					d.Kind,
+					subject,
+					rule.Verb,
+					rule.Namespace,
+					rule.APIGroup,
+					rule.Resource,
+					strings.Join(rule.ResourceNames, ","),
+					strings.Join(rule.NonResourceURLs, ","),
+					rule.GrantedBy.String(),
+					originatedFrom,
+				})
+			}
+		}
+	}
+
+	return rows
+}
+
+func renderDiffTable(w io.Writer, diffs []diff.SubjectDiff) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(diffColumns)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetBorder(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	table.AppendBulk(diffRows(diffs))
+	table.Render()
+}
+
+func renderDiffMarkdown(w io.Writer, left string, right string, diffs []diff.SubjectDiff) {
+	added, removed := diff.Summary(diffs)
+
+	fmt.Fprintf(w, "### RBAC Diff `%v` → `%v`\n\n", left, right)
+
+	if len(diffs) == 0 {
+		fmt.Fprintln(w, "No effective permission changes.")
+		return
+	}
+
+	fmt.Fprintf(w, "**%v** subjects changed, **%v** permissions added, **%v** permissions removed\n\n", len(diffs), added, removed)
+
+	fmt.Fprintf(w, "| %v |\n", strings.Join(diffColumns, " | "))
+	fmt.Fprintf(w, "|%v\n", strings.Repeat(" --- |", len(diffColumns)))
+
+	for _, row := range diffRows(diffs) {
+		for i := range row {
+			row[i] = strings.ReplaceAll(row[i], "|", "\\|")
+		}
+		row[0] = fmt.Sprintf("`%v`", row[0])
+
+		fmt.Fprintf(w, "| %v |\n", strings.Join(row, " | "))
+	}
+}
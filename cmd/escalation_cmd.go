@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/alcideio/rbac-tool/pkg/escalation"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+	"github.com/alcideio/rbac-tool/pkg/utils"
+	"github.com/alcideio/rbac-tool/pkg/visualize"
+)
+
+func NewCommandEscalation() *cobra.Command {
+
+	input := &inputSource{}
+	implicitGroups := true
+	output := "table"
+	outfile := ""
+	maxLength := 0
+	includeSubjects := ".*"
+	excludedNamespaces := "kube-system"
+
+	// Support overrides
+	cmd := &cobra.Command{
+		Use:     "escalation-paths",
+		Aliases: []string{"escalations", "paths"},
+		Short:   "Find the privilege escalation paths that lead to cluster-admin",
+		Long: `
+Find the subjects that can become cluster-admin by chaining RBAC permissions, for example:
+create pods in a namespace >> run as a ServiceAccount of the namespace >> the ServiceAccount reads
+token Secrets in kube-system >> a token of a ServiceAccount bound to cluster-admin.
+
+Escalation techniques:
+  * create-pods  - create Pods (or Deployments, DaemonSets, StatefulSets, ReplicaSets, Jobs, CronJobs, ReplicationControllers)
+                   in a namespace and mount the token of any ServiceAccount of the namespace
+  * read-secrets - read the token Secrets of the ServiceAccounts of a namespace
+  * create-token - request a token for a ServiceAccount (serviceaccounts/token)
+  * impersonate  - impersonate a user, a group (including system:masters) or a ServiceAccount
+  * bind         - create a ClusterRoleBinding to the cluster-admin ClusterRole
+  * escalate     - update a bound ClusterRole with any permission
+
+Every subject is reported with its cheapest path. Paths are sorted by length -
+SCORE (0-100) is higher for paths that are easier to exploit.
+
+Examples:
+
+# Who can become cluster-admin
+rbac-tool escalation-paths
+
+# Escalation paths of a snapshot archive - as JSON
+rbac-tool escalation-paths -f cluster-snapshot.tar.gz -o json
+
+# Render the escalation paths graph - the highest scored paths are highlighted
+rbac-tool escalation-paths -o html --outfile escalation.html
+
+`,
+		Hidden: false,
+		RunE: func(c *cobra.Command, args []string) error {
+			subjectsRegex, err := regexp.Compile(includeSubjects)
+			if err != nil {
+				return fmt.Errorf("Failed to compile --include-subjects regular expression - %v", err)
+			}
+
+			_, perms, err := input.Load()
+			if err != nil {
+				return err
+			}
+
+			inNs, exNs := utils.GetNamespaceSets("*", excludedNamespaces)
+
+			paths := []escalation.Path{}
+			for _, p := range escalation.NewGraph(perms, implicitGroups).Paths() {
+				if maxLength > 0 && p.Length > maxLength {
+					continue
+				}
+
+				if p.Subject.Kind == rbacv1.ServiceAccountKind && !utils.IsNamespaceIncluded(p.Subject.Namespace, inNs, exNs) {
+					continue
+				}
+
+				if !subjectsRegex.MatchString(p.Subject.Name) {
+					continue
+				}
+
+				paths = append(paths, p)
+			}
+
+			switch output {
+			case "table":
+				rows := [][]string{}
+				for i, p := range paths {
+					rows = append(rows, []string{
+						fmt.Sprintf("%v", i+1),
+						fmt.Sprintf("%v", p.Length),
+						fmt.Sprintf("%v", p.Score),
+						p.Subject.Kind,
+						p.Subject.Name,
+						p.Subject.Namespace,
+						formatEscalationSteps(p.Steps),
+					})
+				}
+
+				table := tablewriter.NewWriter(os.Stdout)
+				table.SetHeader([]string{"#", "LENGTH", "SCORE", "TYPE", "SUBJECT", "NAMESPACE", "PATH"})
+				table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+				table.SetBorder(false)
+				table.SetAlignment(tablewriter.ALIGN_LEFT)
+				table.SetAutoWrapText(false)
+				table.SetRowLine(true)
+
+				table.AppendBulk(rows)
+				table.Render()
+
+				return nil
+
+			case "dot", "html":
+				if outfile == "" {
+					outfile = "escalation-paths." + output
+				}
+
+				return visualize.CreateEscalationGraph(paths, outfile, output)
+
+			case "yaml":
+				data, err := yaml.Marshal(&paths)
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
+				fmt.Fprintln(os.Stdout, string(data))
+				return nil
+
+			case "json":
+				data, err := json.Marshal(&paths)
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
+
+				fmt.Fprintln(os.Stdout, string(data))
+				return nil
+
+			default:
+				return fmt.Errorf("Unsupported output format")
+			}
+		},
+	}
+
+	flags := cmd.Flags()
+	input.AddFlags(flags)
+	flags.StringVarP(&output, "output", "o", "table", "Output type: table | json | yaml | dot | html")
+	flags.StringVar(&outfile, "outfile", "", "Output file of the dot/html graph (default escalation-paths.<output>)")
+	flags.IntVar(&maxLength, "max-length", 0, "Only report paths with up to this number of steps (0 - no limit)")
+	flags.StringVar(&includeSubjects, "include-subjects", ".*", "A regular expression to limit the subjects we report")
+	flags.StringVar(&excludedNamespaces, "exclude-namespaces", "kube-system", "Comma-delimited list of namespaces whose ServiceAccounts are not reported (they still take part in paths)")
+	flags.BoolVar(&implicitGroups, "implicit-groups", true, "Credit ServiceAccounts with the permissions of their implicit groups (system:serviceaccounts, system:serviceaccounts:<namespace>, system:authenticated)")
+
+	return cmd
+}
+
+// formatEscalationSteps renders a step per line, e.g. 'create pods in namespace foo (RoleBinding>>foo/deployer) >> ServiceAccount foo/builder'
+func formatEscalationSteps(steps []escalation.Step) string {
+	lines := make([]string, 0, len(steps))
+
+	for _, step := range steps {
+		to := "cluster-admin"
+		if step.To != nil {
+			to = fmt.Sprintf("%v %v", step.To.Kind, rbac.SubjectName(*step.To))
+		}
+
+		if step.Technique == escalation.HoldsClusterAdmin.Name {
+			lines = append(lines, fmt.Sprintf("%v (%v)", step.Description, step.GrantedBy.String()))
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("%v (%v) >> %v", step.Description, step.GrantedBy.String(), to))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/klog"
+	"sigs.k8s.io/yaml"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+type explanation struct {
+	Subject rbacv1.Subject `json:"subject"`
+	Request string         `json:"request"`
+	Allowed bool           `json:"allowed"`
+
+	//Every binding>>role>>rule chain that allows the request
+	Chains []rbac.Grant `json:"chains,omitempty"`
+}
+
+func NewCommandExplain() *cobra.Command {
+
+	input := &inputSource{}
+	implicitGroups := true
+	namespace := ""
+	output := "table"
+
+	// Support overrides
+	cmd := &cobra.Command{
+		Use:           "explain <SUBJECT> <VERB> ( KIND | KIND/NAME | KIND/SUBRESOURCE | KIND/SUBRESOURCE/NAME | NON-RESOURCE-URL )",
+		Args:          cobra.ExactArgs(3),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Short:         "Explain why a subject can perform an action",
+		Long: `
+Explain why a subject can perform an action - print every chain of
+subject (or group) >> RoleBinding/ClusterRoleBinding >> Role/ClusterRole >> rule that allows it.
+
+* SUBJECT is one of sa:<namespace>/<name>, user:<name> or group:<name>
+* VERB, KIND, SUBRESOURCE, NAME and NON-RESOURCE-URL are as in 'rbac-tool who-can'
+
+Examples:
+
+# Why can the ServiceAccount foo/deployer create pods in namespace foo
+rbac-tool explain sa:foo/deployer create pods -n foo
+
+# Why can the user alice read the secret db-password in any namespace
+rbac-tool explain user:alice get secrets/db-password
+
+# Why can members of the group devs exec into pods - based on a snapshot archive
+rbac-tool explain group:devs create pods/exec -f cluster-snapshot.tar.gz
+
+`,
+		Hidden: false,
+		RunE: func(c *cobra.Command, args []string) error {
+			subject, err := rbac.ParseSubject(args[0])
+			if err != nil {
+				return err
+			}
+
+			client, perms, err := input.Load()
+			if err != nil {
+				return err
+			}
+
+			attrs, err := rbac.NewRequestAttributes(client, args[1], args[2], namespace)
+			if err != nil {
+				return err
+			}
+
+			authz := rbac.NewAuthorizer(perms)
+			namespaces := rbac.RequestNamespaces(client, authz, attrs)
+
+			attrs.User = rbac.SubjectUser(subject, implicitGroups)
+
+			klog.V(8).Infof("explain %#v in %v", attrs, namespaces)
+
+			e := explanation{
+				Subject: subject,
+				Request: describeRequest(attrs),
+				Chains:  authz.GrantsIn(attrs, namespaces),
+			}
+			e.Allowed = len(e.Chains) > 0
+
+			switch output {
+			case "table":
+				verdict := "cannot"
+				if e.Allowed {
+					verdict = "can"
+				}
+
+				fmt.Fprintf(os.Stdout, "%v %v %v %v\n\n", subject.Kind, rbac.SubjectName(subject), verdict, e.Request)
+
+				if !e.Allowed {
+					return nil
+				}
+
+				rows := [][]string{}
+				for i, chain := range e.Chains {
+					scope := chain.Namespace
+					if scope == "" {
+						scope = "cluster-wide"
+					}
+
+					role := fmt.Sprintf("%v>>%v", chain.RoleRef.Kind, chain.RoleRef.Name)
+					if chain.RoleRef.Kind == "ClusterRole" {
+						if from, aggregated := perms.AggregatedFrom(chain.RoleRef.Name, chain.Rule); aggregated {
+							role = fmt.Sprintf("%v (aggregated from %v>>%v)", role, from.Kind, from.Name)
+						}
+					}
+
+					rows = append(rows, []string{
+						fmt.Sprintf("%v", i+1),
+						fmt.Sprintf("%v>>%v", chain.BindingSubject.Kind, chain.BindingSubject.Name),
+						scope,
+						chain.Binding.String(),
+						role,
+						formatPolicyRule(chain.Rule),
+					})
+				}
+
+				table := tablewriter.NewWriter(os.Stdout)
+				table.SetHeader([]string{"#", "SUBJECT", "SCOPE", "GRANTED BY", "ROLE", "RULE"})
+				table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+				table.SetBorder(false)
+				table.SetAlignment(tablewriter.ALIGN_LEFT)
+				table.SetAutoWrapText(false)
+
+				table.AppendBulk(rows)
+				table.Render()
+
+				return nil
+			case "yaml":
+				data, err := yaml.Marshal(&e)
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
+				fmt.Fprintln(os.Stdout, string(data))
+				return nil
+
+			case "json":
+				data, err := json.Marshal(&e)
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
+
+				fmt.Fprintln(os.Stdout, string(data))
+				return nil
+
+			default:
+				return fmt.Errorf("Unsupported output format")
+			}
+		},
+	}
+
+	flags := cmd.Flags()
+	input.AddFlags(flags)
+	flags.StringVarP(&namespace, "namespace", "n", "", "The namespace of the action - when not specified namespaced actions are explained in every namespace")
+	flags.StringVarP(&output, "output", "o", "table", "Output type: table | json | yaml")
+	flags.BoolVar(&implicitGroups, "implicit-groups", true, "Credit ServiceAccounts with the permissions of their implicit groups (system:serviceaccounts, system:serviceaccounts:<namespace>, system:authenticated)")
+
+	return cmd
+}
+
+// describeRequest renders the request attributes, e.g. 'create pods/exec in namespace foo'
+func describeRequest(attrs authorizer.AttributesRecord) string {
+	if !attrs.ResourceRequest {
+		return fmt.Sprintf("%v %v", attrs.Verb, attrs.Path)
+	}
+
+	resource := attrs.Resource
+	if attrs.APIGroup != "" {
+		resource = fmt.Sprintf("%v.%v", attrs.Resource, attrs.APIGroup)
+	}
+	if attrs.Subresource != "" {
+		resource = fmt.Sprintf("%v/%v", resource, attrs.Subresource)
+	}
+	if attrs.Name != "" {
+		resource = fmt.Sprintf("%v '%v'", resource, attrs.Name)
+	}
+
+	if attrs.Namespace != "" {
+		return fmt.Sprintf("%v %v in namespace %v", attrs.Verb, resource, attrs.Namespace)
+	}
+
+	return fmt.Sprintf("%v %v", attrs.Verb, resource)
+}
+
+// formatPolicyRule renders the rule fields that are set, e.g. 'verbs=[get,list] apiGroups=[""] resources=[pods]'
+func formatPolicyRule(rule rbacv1.PolicyRule) string {
+	fields := []string{}
+
+	format := func(name string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+
+		quoted := make([]string, len(values))
+		for i, v := range values {
+			if v == "" {
+				v = `""`
+			}
+			quoted[i] = v
+		}
+
+		fields = append(fields, fmt.Sprintf("%v=[%v]", name, strings.Join(quoted, ",")))
+	}
+
+	format("verbs", rule.Verbs)
+	format("apiGroups", rule.APIGroups)
+	format("resources", rule.Resources)
+	format("resourceNames", rule.ResourceNames)
+	format("nonResourceURLs", rule.NonResourceURLs)
+
+	return strings.Join(fields, " ")
+}
@@ -21,6 +21,7 @@ import (
 
 func NewCommandGenerateClusterRole() *cobra.Command {
 	clusterContext := ""
+	snapshotFile := ""
 	generateKind := ""
 	allowedGroups := []string{}
 	//expandGroups := []string{}
@@ -50,15 +51,31 @@ rbac-tool gen --generated-type=ClusterRole --deny-resources=secrets., --allowed-
 # Generate a Role and customize the metadata of the generated object
 rbac-tool gen --generated-type=Role --deny-resources=secrets.,ingresses.extensions --allowed-verbs=get,list --metadata='{"name": "my-role", "namespace":"my-namespace", "labels": {"app": "myapp"}, "annotations": {"generated-by": "rbac-tool"}}'
 
+# Generate a ClusterRole from the discovery data captured in a snapshot archive
+rbac-tool gen --file cluster-snapshot.tar.gz --allowed-verbs=get,list
+
 `,
 		Hidden: false,
 		RunE: func(c *cobra.Command, args []string) error {
-			kubeClient, err := kube.NewClient(clusterContext)
-			if err != nil {
-				return fmt.Errorf("Failed to create kubernetes client - %v", err)
+			var preferredResources []*metav1.APIResourceList
+
+			if snapshotFile != "" {
+				snap, err := loadSnapshot(clusterContext, snapshotFile)
+				if err != nil {
+					return err
+				}
+
+				preferredResources = snap.ServerPreferredResources
+			} else {
+				kubeClient, err := kube.NewClient(clusterContext)
+				if err != nil {
+					return fmt.Errorf("Failed to create kubernetes client - %v", err)
+				}
+
+				preferredResources = kubeClient.ServerPreferredResources
 			}
 
-			computedPolicyRules, err := generateRules(generateKind, kubeClient.ServerPreferredResources, sets.NewString(denyResources...), sets.NewString(allowedGroups...), sets.NewString(allowedVerb...))
+			computedPolicyRules, err := generateRules(generateKind, preferredResources, sets.NewString(denyResources...), sets.NewString(allowedGroups...), sets.NewString(allowedVerb...))
 			if err != nil {
 				return err
 			}
@@ -78,6 +95,7 @@ rbac-tool gen --generated-type=Role --deny-resources=secrets.,ingresses.extensio
 
 	flags.StringVarP(&generateKind, "generated-type", "t", "ClusterRole", "Role or ClusterRole")
 	flags.StringVarP(&clusterContext, "cluster-context", "c", "", "Cluster.use 'kubectl config get-contexts' to list available contexts")
+	flags.StringVarP(&snapshotFile, "file", "f", "", "Read the API resources from a snapshot archive (see 'rbac-tool snapshot') instead of connecting to a cluster")
 	//flags.StringSliceVarP(&expandGroups, "expand-groups", "g", []string{""},  "Comma separated list of API groups we would like to list all resource kinds rather than using wild cards '*'")
 	flags.StringSliceVar(&allowedGroups, "allowed-groups", []string{"*"}, "Comma separated list of API groups we would like to allow '*'")
 	flags.StringSliceVar(&allowedVerb, "allowed-verbs", []string{"*"}, "Comma separated list of verbs to include. To include all use '*'")
@@ -0,0 +1,379 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/kube"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+	"github.com/alcideio/rbac-tool/pkg/snapshot"
+	"github.com/alcideio/rbac-tool/pkg/utils"
+)
+
+// inputSource is where the RBAC resources a command operates on are read from - a live cluster or resource files
+type inputSource struct {
+	//Cluster context to connect to
+	ClusterContext string
+
+	//Cluster contexts to query concurrently - see AddMultiClusterFlags
+	ClusterContexts []string
+	AllContexts     bool
+
+	//File, directory, snapshot archive or '-' for stdin to read resources from instead of connecting to a cluster
+	Infile string
+
+	//Discovery snapshot used to resolve resource kinds when reading resources from files
+	DiscoveryFile string
+
+	//Cache the cluster resources on disk and reuse them for this duration - 0 disables the cache
+	CacheTTL time.Duration
+
+	//Don't print the listing progress (e.g. when several clusters are listed concurrently)
+	noProgress bool
+	progress   *utils.Progress
+
+	snapshot *snapshot.Snapshot
+
+	//The snapshot is the cache of the cluster - it holds the discovery data and the RBAC resources only
+	cached bool
+
+	//The resources read from Infile
+	objs []runtime.Object
+}
+
+func (s *inputSource) AddFlags(flags *pflag.FlagSet) {
+	s.AddClusterFlags(flags)
+	s.addFileFlags(flags)
+}
+
+// AddClusterFlags adds the input flags of commands that only work with a live cluster
+func (s *inputSource) AddClusterFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&s.ClusterContext, "cluster-context", "", "Cluster Context .use 'kubectl config get-contexts' to list available contexts")
+}
+
+// AddMultiClusterFlags adds the input flags of commands that can query several clusters - see Clusters
+func (s *inputSource) AddMultiClusterFlags(flags *pflag.FlagSet) {
+	flags.StringSliceVar(&s.ClusterContexts, "cluster-context", nil, "Cluster Contexts (comma separated or repeated) .use 'kubectl config get-contexts' to list available contexts")
+	flags.BoolVar(&s.AllContexts, "all-contexts", false, "Query all the kubeconfig contexts")
+	s.addFileFlags(flags)
+}
+
+func (s *inputSource) addFileFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&s.Infile, "file", "f", "", "Read resources from a file, a directory, a snapshot archive (see 'rbac-tool snapshot') or '-' for stdin instead of connecting to a cluster")
+	flags.StringVar(&s.DiscoveryFile, "discovery-file", "", "Discovery snapshot (JSON/YAML list of APIResourceList) used to resolve resource kinds when reading from --file")
+	flags.DurationVar(&s.CacheTTL, "cache-ttl", 0, "Cache the cluster discovery data and RBAC resources on disk and reuse them for this duration (e.g. 10m) - 0 disables the cache")
+}
+
+func (s *inputSource) Validate() error {
+	if s.Infile != "" && (s.ClusterContext != "" || len(s.ClusterContexts) > 0 || s.AllContexts) {
+		return fmt.Errorf("Either use input file or specify cluster context")
+	}
+
+	if s.AllContexts && len(s.ClusterContexts) > 0 {
+		return fmt.Errorf("Either use --all-contexts or specify cluster contexts")
+	}
+
+	if s.Infile == "" && s.DiscoveryFile != "" {
+		return fmt.Errorf("--discovery-file can only be used with --file")
+	}
+
+	if s.Infile != "" && s.CacheTTL > 0 {
+		return fmt.Errorf("--cache-ttl can only be used with a cluster")
+	}
+
+	return nil
+}
+
+// IsMultiCluster reports whether several cluster contexts are queried
+func (s *inputSource) IsMultiCluster() bool {
+	return s.AllContexts || len(s.ClusterContexts) > 1
+}
+
+// Clusters returns an input source per cluster context - or the input source itself when a single cluster is queried
+func (s *inputSource) Clusters() ([]*inputSource, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	if !s.IsMultiCluster() {
+		if len(s.ClusterContexts) == 1 {
+			s.ClusterContext = s.ClusterContexts[0]
+		}
+
+		return []*inputSource{s}, nil
+	}
+
+	contexts := s.ClusterContexts
+	if s.AllContexts {
+		var err error
+		if contexts, err = kube.Contexts(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(contexts) == 0 {
+		return nil, fmt.Errorf("No cluster contexts found")
+	}
+
+	clusters := make([]*inputSource, 0, len(contexts))
+	for _, context := range contexts {
+		clusters = append(clusters, &inputSource{ClusterContext: context, CacheTTL: s.CacheTTL, noProgress: true})
+	}
+
+	return clusters, nil
+}
+
+func (s *inputSource) IsOffline() bool {
+	return s.Infile != ""
+}
+
+// isLive reports whether the resources are read from the cluster - rather than from files or the cache
+func (s *inputSource) isLive() bool {
+	return !s.IsOffline() && s.snapshot == nil
+}
+
+// loadCache loads the cached snapshot of the cluster - or captures and caches the discovery data and the RBAC resources when the cache expired
+func (s *inputSource) loadCache() error {
+	clusterContext := s.ClusterContext
+	if clusterContext == "" {
+		var err error
+		if clusterContext, err = kube.CurrentContext(); err != nil {
+			return err
+		}
+	}
+
+	// The same context may point at another cluster or authenticate as another user (kubeconfig, server, token and impersonation overrides)
+	identity, err := kube.Identity(s.ClusterContext)
+	if err != nil {
+		return fmt.Errorf("Failed to resolve the kubeconfig of '%v' - %v", clusterContext, err)
+	}
+
+	fname, err := snapshot.CachePath(clusterContext + "-" + identity)
+	if err != nil {
+		return fmt.Errorf("Failed to resolve the cache directory - %v", err)
+	}
+
+	snap, err := snapshot.LoadCache(fname, s.CacheTTL)
+	if err != nil {
+		klog.V(3).Infof("Failed to load the cached snapshot '%v' - %v", fname, err)
+	}
+
+	if snap == nil {
+		client, err := s.NewClient()
+		if err != nil {
+			return err
+		}
+
+		snap, err = snapshot.CaptureRBAC(client, clusterContext)
+		s.progressDone()
+		if err != nil {
+			return err
+		}
+
+		if err := snap.SaveCache(fname); err != nil {
+			klog.Warningf("Failed to cache the cluster resources - %v", err)
+		}
+	} else {
+		klog.V(3).Infof("Using the cluster resources cached at %v", snap.Metadata.CreatedAt)
+	}
+
+	s.snapshot = snap
+	s.cached = true
+	return nil
+}
+
+func (s *inputSource) progressDone() {
+	if s.progress != nil {
+		s.progress.Done()
+	}
+}
+
+// Snapshot returns the snapshot archive the input is read from - nil when the input is not a snapshot
+func (s *inputSource) Snapshot() (*snapshot.Snapshot, error) {
+	if s.snapshot != nil || !snapshot.IsSnapshot(s.Infile) {
+		return s.snapshot, nil
+	}
+
+	snap, err := snapshot.Load(s.Infile)
+	if err != nil {
+		return nil, err
+	}
+
+	s.snapshot = snap
+	return snap, nil
+}
+
+// NewClient connects to the cluster - when reading resources from files an offline client is returned
+func (s *inputSource) NewClient() (*kube.KubeClient, error) {
+	if s.isLive() {
+		return s.newLiveClient()
+	}
+
+	snap, err := s.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.DiscoveryFile == "" {
+		if snap != nil {
+			return snap.Client(), nil
+		}
+
+		return kube.NewOfflineClient(nil, nil), nil
+	}
+
+	resources, err := kube.LoadDiscovery(s.DiscoveryFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return kube.NewOfflineClient(resources, nil), nil
+}
+
+func (s *inputSource) newLiveClient() (*kube.KubeClient, error) {
+	client, err := kube.NewClient(s.ClusterContext)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create kubernetes client - %v", err)
+	}
+
+	if !s.noProgress {
+		s.progress = utils.NewProgress()
+		client.Progress = s.progress.Update
+	}
+
+	return client, nil
+}
+
+// NewPermissions reads the RBAC resources from the cluster or from the input files
+func (s *inputSource) NewPermissions(client *kube.KubeClient) (*rbac.Permissions, error) {
+	if s.isLive() {
+		defer s.progressDone()
+		return rbac.NewPermissionsFromCluster(client)
+	}
+
+	snap, err := s.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	if snap != nil {
+		return snap.Permissions()
+	}
+
+	objs, err := utils.ReadObjectsFromFile(s.Infile)
+	if err != nil {
+		return nil, err
+	}
+
+	klog.V(5).Infof("Loaded %v resources from '%v'", len(objs), s.Infile)
+	s.objs = objs
+
+	return rbac.NewPermissionsFromResourceList(objs)
+}
+
+// Load creates the client and reads the RBAC resources
+func (s *inputSource) Load() (*kube.KubeClient, *rbac.Permissions, error) {
+	if err := s.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if s.CacheTTL > 0 && s.snapshot == nil {
+		if err := s.loadCache(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	client, err := s.NewClient()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	perms, err := s.NewPermissions(client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return client, perms, nil
+}
+
+// Objects returns the resources read from the snapshot archive or the input files - available after Load
+func (s *inputSource) Objects() ([]runtime.Object, error) {
+	snap, err := s.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	if snap != nil {
+		return snap.Objects, nil
+	}
+
+	return s.objs, nil
+}
+
+// ListWorkloads lists the Pods and their owners (ReplicaSets and Jobs) of the cluster, the snapshot archive or the input files
+func (s *inputSource) ListWorkloads(client *kube.KubeClient) ([]runtime.Object, error) {
+	if s.IsOffline() {
+		return s.Objects()
+	}
+
+	//The cache holds no workloads - they are listed from the cluster
+	if s.cached {
+		var err error
+		if client, err = s.newLiveClient(); err != nil {
+			return nil, err
+		}
+	}
+
+	defer s.progressDone()
+
+	var pods []v1.Pod
+	var replicaSets []appsv1.ReplicaSet
+	var jobs []batchv1.Job
+
+	err := utils.RunConcurrently(
+		func() (err error) {
+			pods, err = client.ListPods(v1.NamespaceAll)
+			if err != nil {
+				return fmt.Errorf("Failed to list Pods - %v", err)
+			}
+			return nil
+		},
+		func() (err error) {
+			replicaSets, err = client.ListReplicaSets(v1.NamespaceAll)
+			if err != nil {
+				return fmt.Errorf("Failed to list ReplicaSets - %v", err)
+			}
+			return nil
+		},
+		func() (err error) {
+			jobs, err = client.ListJobs(v1.NamespaceAll)
+			if err != nil {
+				return fmt.Errorf("Failed to list Jobs - %v", err)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := make([]runtime.Object, 0, len(pods)+len(replicaSets)+len(jobs))
+	for i := range pods {
+		objs = append(objs, &pods[i])
+	}
+	for i := range replicaSets {
+		objs = append(objs, &replicaSets[i])
+	}
+	for i := range jobs {
+		objs = append(objs, &jobs[i])
+	}
+
+	return objs, nil
+}
@@ -4,26 +4,26 @@ import (
 	"fmt"
 	"os"
 	"regexp"
-	"sort"
-	"strings"
 
-	"github.com/alcideio/rbac-tool/pkg/kube"
-	"github.com/alcideio/rbac-tool/pkg/rbac"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
 )
 
 func NewCommandLookup() *cobra.Command {
 
-	clusterContext := ""
+	input := &inputSource{}
 	regex := ""
 	inverse := false
 
 	// Support overrides
 	cmd := &cobra.Command{
-		Use:     "lookup",
-		Aliases: []string{"look"},
-		Short:   "RBAC Lookup by subject (user/group/serviceaccount) name",
+		Use:           "lookup",
+		Aliases:       []string{"look"},
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Short:         "RBAC Lookup by subject (user/group/serviceaccount) name",
 		Long: `
 A Kubernetes RBAC lookup of Roles/ClusterRoles used by a given User/ServiceAccount/Group
 
@@ -44,6 +44,12 @@ rbac-tool lookup -e '^system:.*'
 # Lookup all accounts that DO NOT start with system: )
 rbac-tool lookup -ne '^system:.*'
 
+# Lookup from a directory of manifests
+rbac-tool lookup -f manifests/
+
+# Lookup in the clusters of the staging and production contexts
+rbac-tool lookup myname --cluster-context staging,production
+
 `,
 		Hidden: false,
 		RunE: func(c *cobra.Command, args []string) error {
@@ -67,85 +73,64 @@ rbac-tool lookup -ne '^system:.*'
 				return err
 			}
 
-			client, err := kube.NewClient(clusterContext)
+			clusters, err := input.Clusters()
 			if err != nil {
-				return fmt.Errorf("Failed to create kubernetes client - %v", err)
+				return err
 			}
 
-			perms, err := rbac.NewPermissionsFromCluster(client)
-			if err != nil {
-				return err
+			query := func(input *inputSource) ([]rbac.SubjectBinding, error) {
+				_, perms, err := input.Load()
+				if err != nil {
+					return nil, err
+				}
+
+				return rbac.Lookup(perms, re, inverse), nil
 			}
 
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetHeader([]string{"SUBJECT", "SUBJECT TYPE", "SCOPE", "NAMESPACE", "ROLE", "BINDING"})
-			table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
-			table.SetBorder(false)
-			table.SetAlignment(tablewriter.ALIGN_LEFT)
+			header := []string{"SUBJECT", "SUBJECT TYPE", "SCOPE", "NAMESPACE", "ROLE", "BINDING"}
+			lookupRow := func(b rbac.SubjectBinding) []string {
+				return []string{b.Subject.Name, b.Subject.Kind, b.Scope, b.Namespace, b.Role, b.Binding}
+			}
 
+			var failures error
 			rows := [][]string{}
-			for _, bindings := range perms.RoleBindings {
-				for _, binding := range bindings {
-					for _, subject := range binding.Subjects {
-						match := re.MatchString(subject.Name)
-
-						//  match    inverse
-						//  -----------------
-						//  true     true   --> skip
-						//  true     false  --> keep
-						//  false    true   --> keep
-						//  false    false  --> skip
-						if match {
-							if inverse {
-								continue
-							}
-						} else {
-							if !inverse {
-								continue
-							}
-						}
-
-						//Subject match
-						roleNamespace := binding.Namespace
-						if binding.RoleRef.Kind == "ClusterRole" {
-							roleNamespace = ""
-						}
-						_, exist := perms.Roles[roleNamespace]
-						if !exist {
-							continue
-						}
-
-						if binding.Namespace == "" {
-							row := []string{subject.Name, subject.Kind, "ClusterRole", "", binding.RoleRef.Name, binding.Name}
-							rows = append(rows, row)
-						} else if binding.Namespace != "" && roleNamespace == "" {
-							row := []string{subject.Name, subject.Kind, "ClusterRole", binding.Namespace, binding.RoleRef.Name, binding.Name}
-							rows = append(rows, row)
-						} else {
-							row := []string{subject.Name, subject.Kind, "Role", binding.Namespace, binding.RoleRef.Name, binding.Name}
-							rows = append(rows, row)
-						}
+			if input.IsMultiCluster() {
+				header = append([]string{"CLUSTER"}, header...)
+
+				results := queryClusters(clusters, query)
+				for _, r := range results {
+					for _, b := range r.Result {
+						rows = append(rows, append([]string{r.Cluster}, lookupRow(b)...))
 					}
 				}
-			}
 
-			sort.Slice(rows, func(i, j int) bool {
-				if strings.Compare(rows[i][0], rows[j][0]) == 0 {
-					return (strings.Compare(rows[i][3], rows[j][3]) < 0)
+				failures = reportClusterFailures(results)
+			} else {
+				bindings, err := query(clusters[0])
+				if err != nil {
+					return err
+				}
+
+				for _, b := range bindings {
+					rows = append(rows, lookupRow(b))
 				}
+			}
 
-				return (strings.Compare(rows[i][0], rows[j][0]) < 0)
-			})
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader(header)
+			table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+			table.SetBorder(false)
+			table.SetAlignment(tablewriter.ALIGN_LEFT)
 
 			table.AppendBulk(rows)
 			table.Render()
 
-			return nil
+			return failures
 		},
 	}
 
 	flags := cmd.Flags()
-	flags.StringVar(&clusterContext, "cluster-context", "", "Cluster Context .use 'kubectl config get-contexts' to list available contexts")
+	input.AddMultiClusterFlags(flags)
 
 	flags.StringVarP(&regex, "regex", "e", "", "Specify whether run the lookup using a regex match")
 	flags.BoolVarP(&inverse, "not", "n", false, "Inverse the regex matching. Use to search for users that do not match '^system:.*'")
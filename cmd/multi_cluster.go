@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/alcideio/rbac-tool/pkg/utils"
+)
+
+// maxConcurrentClusters bounds the number of clusters queried at once
+const maxConcurrentClusters = 8
+
+// clusterResult is the result of a query of a single cluster
+type clusterResult[T any] struct {
+	Cluster string `json:"cluster"`
+	Result  T      `json:"result,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// queryClusters runs the query on each cluster concurrently - the results are in the order of the clusters.
+// A cluster that fails is reported on its result and does not abort the other queries.
+func queryClusters[T any](clusters []*inputSource, query func(input *inputSource) (T, error)) []clusterResult[T] {
+	results := make([]clusterResult[T], len(clusters))
+	semaphore := make(chan struct{}, maxConcurrentClusters)
+
+	var wg sync.WaitGroup
+	for i, cluster := range clusters {
+		wg.Add(1)
+		go func(i int, cluster *inputSource) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			results[i].Cluster = cluster.ClusterContext
+
+			result, err := query(cluster)
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+			results[i].Result = result
+		}(i, cluster)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// reportClusterFailures prints the clusters that failed and returns an error when any did
+func reportClusterFailures[T any](results []clusterResult[T]) error {
+	failed := 0
+	for _, r := range results {
+		if r.Error == "" {
+			continue
+		}
+
+		failed++
+		utils.ConsolePrinter(fmt.Sprintf("Failed to query cluster '%v' - %v", r.Cluster, r.Error))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("Failed to query %v of %v clusters", failed, len(results))
+	}
+
+	return nil
+}
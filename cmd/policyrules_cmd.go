@@ -11,7 +11,6 @@ import (
 
 	"sigs.k8s.io/yaml"
 
-	"github.com/alcideio/rbac-tool/pkg/kube"
 	"github.com/alcideio/rbac-tool/pkg/rbac"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
@@ -21,10 +20,11 @@ import (
 
 func NewCommandPolicyRules() *cobra.Command {
 
-	clusterContext := ""
+	input := &inputSource{}
 	regex := ""
 	inverse := false
 	output := "table"
+	implicitGroups := true
 	// Support overrides
 	cmd := &cobra.Command{
 		Use:     "policy-rules",
@@ -47,6 +47,9 @@ rbac-tool policy-rules -e '^system:.*'
 # Lookup all accounts that DO NOT start with system: )
 rbac-tool policy-rules -ne '^system:.*'
 
+# List policy rules from the output of kubectl
+kubectl get roles,rolebindings,clusterroles,clusterrolebindings,serviceaccounts -A -o yaml | rbac-tool policy-rules -f -
+
 # Leveraging jmespath for further filtering and implementing who-can
 rbac-tool policy-rules -o json  | jp "[? @.allowedTo[? (verb=='get' || verb=='*') && (apiGroup=='core' || apiGroup=='*') && (resource=='secrets' || resource == '*')  ]].{name: name, namespace: namespace, kind: kind}"
 
@@ -70,40 +73,20 @@ rbac-tool policy-rules -o json  | jp "[? @.allowedTo[? (verb=='get' || verb=='*'
 				return err
 			}
 
-			client, err := kube.NewClient(clusterContext)
-			if err != nil {
-				return fmt.Errorf("Failed to create kubernetes client - %v", err)
-			}
-
-			perms, err := rbac.NewPermissionsFromCluster(client)
+			_, perms, err := input.Load()
 			if err != nil {
 				return err
 			}
 
-			policies := rbac.NewSubjectPermissions(perms)
-			filteredPolicies := []rbac.SubjectPermissions{}
-			for _, policy := range policies {
-				match := re.MatchString(policy.Subject.Name)
-
-				//  match    inverse
-				//  -----------------
-				//  true     true   --> skip
-				//  true     false  --> keep
-				//  false    true   --> keep
-				//  false    false  --> skip
-				if match {
-					if inverse {
-						continue
-					}
-				} else {
-					if !inverse {
-						continue
-					}
-				}
-
-				filteredPolicies = append(filteredPolicies, policy)
+			var policies []rbac.SubjectPermissions
+			if implicitGroups {
+				policies = rbac.NewEffectiveSubjectPermissions(perms)
+			} else {
+				policies = rbac.NewSubjectPermissions(perms)
 			}
 
+			filteredPolicies := rbac.FilterSubjectPermissions(policies, re, inverse)
+
 			switch output {
 			case "table":
 				rows := [][]string{}
@@ -120,6 +103,11 @@ rbac-tool policy-rules -o json  | jp "[? @.allowedTo[? (verb=='get' || verb=='*'
 					}
 
 					for _, allowedTo := range p.AllowedTo {
+						originatedFrom := renderOriginatedFromColumn(allowedTo.Namespace, allowedTo.OriginatedFrom)
+						if allowedTo.InheritedFrom != "" {
+							originatedFrom = fmt.Sprintf("%v (via Group>>%v)", originatedFrom, allowedTo.InheritedFrom)
+						}
+
 						row := []string{
 							p.Kind,
 							subject,
@@ -129,7 +117,7 @@ rbac-tool policy-rules -o json  | jp "[? @.allowedTo[? (verb=='get' || verb=='*'
 							allowedTo.Resource,
 							strings.Join(allowedTo.ResourceNames, ","),
 							strings.Join(allowedTo.NonResourceURLs, ","),
-							renderOriginatedFromColumn(allowedTo.Namespace, allowedTo.OriginatedFrom),
+							originatedFrom,
 						}
 						rows = append(rows, row)
 					}
@@ -185,8 +173,9 @@ rbac-tool policy-rules -o json  | jp "[? @.allowedTo[? (verb=='get' || verb=='*'
 	}
 
 	flags := cmd.Flags()
-	flags.StringVar(&clusterContext, "cluster-context", "", "Cluster Context .use 'kubectl config get-contexts' to list available contexts")
+	input.AddFlags(flags)
 	flags.StringVarP(&output, "output", "o", "table", "Output type: table | json | yaml")
+	flags.BoolVar(&implicitGroups, "implicit-groups", true, "Credit ServiceAccounts with the permissions of their implicit groups (system:serviceaccounts, system:serviceaccounts:<namespace>, system:authenticated)")
 
 	flags.StringVarP(&regex, "regex", "e", "", "Specify whether run the lookup using a regex match")
 	flags.BoolVarP(&inverse, "not", "n", false, "Inverse the regex matching. Use to search for users that do not match '^system:.*'")
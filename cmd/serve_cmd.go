@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/analysis"
+	"github.com/alcideio/rbac-tool/pkg/cache"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+	"github.com/alcideio/rbac-tool/pkg/server"
+	"github.com/alcideio/rbac-tool/pkg/utils"
+)
+
+func NewCommandServe() *cobra.Command {
+
+	input := &inputSource{}
+	listen := ":8080"
+	customConfig := ""
+	resync := time.Duration(0)
+
+	// Support overrides
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the RBAC queries (who-can, policy-rules, lookup, analysis) as JSON HTTP endpoints",
+		Long: `
+Serve the RBAC queries as JSON HTTP endpoints. The permissions are kept up to date with shared informers
+on the cluster RBAC resources - or, when reading from a snapshot archive or resource files, served as is.
+
+Endpoints:
+
+  GET /healthz
+  GET /api/v1/who-can?verb=<VERB>&resource=<KIND | KIND/NAME | KIND/SUBRESOURCE | NON-RESOURCE-URL>[&namespace=<ns>][&implicitGroups=false]
+  GET /api/v1/policy-rules[?regex=<regex>][&not=true][&implicitGroups=false]
+  GET /api/v1/lookup[?regex=<regex>][&not=true]
+  GET /api/v1/analysis
+
+Examples:
+
+# Serve the RBAC queries of the cluster pointed by current context
+rbac-tool serve
+
+# Serve the RBAC queries of a snapshot archive
+rbac-tool serve -f cluster-snapshot.tar.gz --listen 127.0.0.1:9090
+
+# Who can read secrets in the payments namespace
+curl 'localhost:8080/api/v1/who-can?verb=get&resource=secrets&namespace=payments'
+
+`,
+		Args:          cobra.ExactArgs(0),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Hidden:        false,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := input.Validate(); err != nil {
+				return err
+			}
+
+			var err error
+			analysisConfig := analysis.DefaultAnalysisConfig()
+
+			//Override Rules (if provided)
+			if customConfig != "" {
+				analysisConfig, err = analysis.LoadAnalysisConfig(customConfig)
+				if err != nil {
+					return err
+				}
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			var srv *server.Server
+			if input.IsOffline() {
+				client, perms, err := input.Load()
+				if err != nil {
+					return err
+				}
+
+				srv = server.NewServer(client, perms, analysisConfig)
+			} else {
+				client, err := input.NewClient()
+				if err != nil {
+					return err
+				}
+
+				permsCache := cache.NewCache(client.Client, resync)
+				if err := permsCache.Start(ctx); err != nil {
+					return err
+				}
+
+				srv = server.NewServer(client, permsCache.Permissions(), analysisConfig)
+				go permsCache.Run(ctx, func(old *rbac.Permissions, updated *rbac.Permissions) {
+					klog.V(5).Infof("Permissions updated")
+					srv.Update(updated)
+				})
+			}
+
+			httpServer := &http.Server{Addr: listen, Handler: srv.Handler()}
+			go func() {
+				<-ctx.Done()
+
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				httpServer.Shutdown(shutdownCtx)
+			}()
+
+			utils.ConsolePrinter(fmt.Sprintf("Serving on %v", listen))
+
+			if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("Failed to serve - %v", err)
+			}
+
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	input.AddFlags(flags)
+	flags.StringVar(&listen, "listen", listen, "The address to serve on")
+	flags.StringVar(&customConfig, "config", "", "Load analysis rules configuration from file")
+	flags.DurationVar(&resync, "resync", resync, "Informers resync period - 0 disables resync")
+
+	return cmd
+}
@@ -10,18 +10,19 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/alcideio/rbac-tool/pkg/kube"
+
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
-
-	"github.com/alcideio/rbac-tool/pkg/kube"
 )
 
 func NewCommandGenerateShowPermissions() *cobra.Command {
 
 	clusterContext := ""
+	snapshotFile := ""
 	generateKind := "ClusterRole"
 	forGroups := []string{"*"}
 	withVerb := []string{"*"}
@@ -48,6 +49,9 @@ rbac-tool show  --for-groups=,apps
 # Generate a ClusterRole with all the available permissions for core and apps api groups
 rbac-tool show --scope=namespaced --without-verbs=create,update,patch,delete,deletecollection
 
+# Generate a ClusterRole with all the available permissions of the cluster captured in a snapshot archive
+rbac-tool show --file cluster-snapshot.tar.gz
+
 
 `,
 		Hidden: false,
@@ -55,19 +59,9 @@ rbac-tool show --scope=namespaced --without-verbs=create,update,patch,delete,del
 			if scope != "all" && scope != "cluster" && scope != "namespaced" {
 				return fmt.Errorf("--scope must be one of: cluster, namespaced or all")
 			}
-			kubeClient, err := kube.NewClient(clusterContext)
+			preferredResources, allResources, err := readDiscovery(clusterContext, snapshotFile)
 			if err != nil {
-				return fmt.Errorf("Failed to create kubernetes client - %v", err)
-			}
-
-			_, allResources, err := kubeClient.Client.Discovery().ServerGroupsAndResources()
-			if err != nil {
-				return fmt.Errorf("failed to read ServerGroupsAndResources - %v", err)
-			}
-
-			preferredResources, err := kubeClient.Client.Discovery().ServerPreferredResources()
-			if err != nil {
-				return fmt.Errorf("failed to read ServerPreferredResources - %v", err)
+				return err
 			}
 
 			klog.V(7).Infof(">>>>> preferred Resources \n%v\n>>>>>", pretty.Sprint(preferredResources))
@@ -102,6 +96,7 @@ rbac-tool show --scope=namespaced --without-verbs=create,update,patch,delete,del
 	flags := cmd.Flags()
 
 	flags.StringVarP(&clusterContext, "cluster-context", "c", "", "Cluster.use 'kubectl config get-contexts' to list available contexts")
+	flags.StringVarP(&snapshotFile, "file", "f", "", "Read the API resources from a snapshot archive (see 'rbac-tool snapshot') instead of connecting to a cluster")
 	flags.StringVarP(&scope, "scope", "", "all", "Filter by resource scope. Valid values are: 'cluster' | 'namespaced' | 'all' ")
 	flags.StringSliceVar(&forGroups, "for-groups", []string{"*"}, "Comma separated list of API groups we would like to show the permissions")
 	flags.StringSliceVar(&withVerb, "with-verbs", []string{"*"}, "Comma separated list of verbs to include. To include all use '*'")
@@ -112,6 +107,35 @@ rbac-tool show --scope=namespaced --without-verbs=create,update,patch,delete,del
 	return cmd
 }
 
+// readDiscovery returns the preferred and all the API resources served by the cluster or captured in a snapshot archive
+func readDiscovery(clusterContext string, snapshotFile string) ([]*metav1.APIResourceList, []*metav1.APIResourceList, error) {
+	if snapshotFile != "" {
+		snap, err := loadSnapshot(clusterContext, snapshotFile)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return snap.ServerPreferredResources, snap.ServerResources, nil
+	}
+
+	kubeClient, err := kube.NewClient(clusterContext)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to create kubernetes client - %v", err)
+	}
+
+	_, allResources, err := kubeClient.Client.Discovery().ServerGroupsAndResources()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read ServerGroupsAndResources - %v", err)
+	}
+
+	preferredResources, err := kubeClient.Client.Discovery().ServerPreferredResources()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read ServerPreferredResources - %v", err)
+	}
+
+	return preferredResources, allResources, nil
+}
+
 func generateRulesWithSubResources(apiresourceList []*metav1.APIResourceList, scope string, preferredApiGroups sets.String, denyResources sets.String, includeGroups sets.String, allowedVerbs sets.String, deniedVerbs sets.String) ([]rbacv1.PolicyRule, error) {
 	errs := []error{}
 
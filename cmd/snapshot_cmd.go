@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/alcideio/rbac-tool/pkg/kube"
+	"github.com/alcideio/rbac-tool/pkg/snapshot"
+	"github.com/alcideio/rbac-tool/pkg/utils"
+)
+
+func NewCommandSnapshot() *cobra.Command {
+
+	clusterContext := ""
+	outfile := "rbac-snapshot.tar.gz"
+
+	// Support overrides
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Capture the cluster RBAC resources into a portable snapshot archive",
+		Long: `
+Capture everything rbac-tool reads from a cluster into a single versioned archive:
+ServiceAccounts, Roles, ClusterRoles, RoleBindings, ClusterRoleBindings, Pods, Namespaces,
+the API discovery data and the server version.
+
+The archive can be replayed later, without access to the cluster, by passing it with --file 
+to who-can, policy-rules, lookup, analysis, viz, generate and show.
+
+Examples:
+
+# Capture a snapshot of the cluster pointed by the kubeconfig context 'myctx'
+rbac-tool snapshot --cluster-context myctx -o myctx.tar.gz
+
+# Replay the snapshot
+rbac-tool who-can get secrets --file myctx.tar.gz
+rbac-tool analysis --file myctx.tar.gz
+rbac-tool viz --file myctx.tar.gz --include-pods-only
+
+`,
+		Hidden: false,
+		RunE: func(c *cobra.Command, args []string) error {
+			utils.ConsolePrinter(fmt.Sprintf("Connecting to cluster '%v'", color.HiBlueString(clusterContext)))
+
+			client, err := kube.NewClient(clusterContext)
+			if err != nil {
+				return fmt.Errorf("Failed to create kubernetes client - %v", err)
+			}
+
+			progress := utils.NewProgress()
+			client.Progress = progress.Update
+
+			snap, err := snapshot.Capture(client, clusterContext)
+			progress.Done()
+			if err != nil {
+				return err
+			}
+
+			snap.Metadata.ToolVersion = Version
+
+			if err := snap.Save(outfile); err != nil {
+				return fmt.Errorf("Failed to save snapshot - %v", err)
+			}
+
+			utils.ConsolePrinter(fmt.Sprintf("Captured %v resources into '%v'", len(snap.Objects), color.HiBlueString(outfile)))
+
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+
+	flags.StringVar(&clusterContext, "cluster-context", "", "Cluster Context .use 'kubectl config get-contexts' to list available contexts")
+	flags.StringVarP(&outfile, "outfile", "o", "rbac-snapshot.tar.gz", "Snapshot archive file")
+
+	return cmd
+}
+
+// loadSnapshot reads a snapshot archive passed instead of a cluster context
+func loadSnapshot(clusterContext string, snapshotFile string) (*snapshot.Snapshot, error) {
+	if clusterContext != "" {
+		return nil, fmt.Errorf("Either use snapshot file or specify cluster context")
+	}
+
+	return snapshot.Load(snapshotFile)
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apiserver/pkg/apis/audit"
+	"k8s.io/klog"
+	"sigs.k8s.io/yaml"
+
+	auditutil "github.com/alcideio/rbac-tool/pkg/audit"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+func NewCommandUnusedPermissions() *cobra.Command {
+
+	input := &inputSource{}
+	auditSources := []string{}
+	output := "table"
+	unusedOnly := false
+	includeSubjects := ".*"
+
+	// Support overrides
+	cmd := &cobra.Command{
+		Use:     "unused-permissions",
+		Aliases: []string{"unused"},
+		Short:   "Report the granted RBAC rules that were never exercised in the audit log",
+		Long: `
+Compare the RBAC rules granted to each subject with the requests recorded in Kubernetes audit events,
+and report per subject the rules that were never exercised during the audit window -
+along with the binding and role that granted each rule, and the usage counts of the rules that were used.
+
+Every rule that allows an audited request is credited - a request allowed by several bindings credits all of them.
+Rules granted to groups are credited with the requests of the group members.
+
+Examples:
+
+# Unused permissions of the cluster pointed by the current context during the audit window
+rbac-tool unused-permissions -f audit.log
+
+# Unused permissions only - of the RBAC resources in a snapshot archive
+rbac-tool unused-permissions -f audit.log --rbac-file cluster-snapshot.tar.gz --unused-only
+
+# Usage report of the ServiceAccounts of the payments namespace as JSON
+rbac-tool unused-permissions -f audit-logs/ --include-subjects '^payments/' -o json
+
+`,
+		Args:          cobra.ExactArgs(0),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(auditSources) == 0 {
+				return fmt.Errorf("--filename is required")
+			}
+
+			subjectsRegex, err := regexp.Compile(includeSubjects)
+			if err != nil {
+				return fmt.Errorf("Failed to compile --include-subjects regular expression - %v", err)
+			}
+
+			_, perms, err := input.Load()
+			if err != nil {
+				return err
+			}
+
+			tracker := auditutil.NewUsageTracker(perms)
+
+			errs := []error{}
+			results, err := auditutil.ReadAuditEvents(auditSources, auditutil.IsCompleted)
+			if err != nil {
+				errs = append(errs, err)
+			}
+
+			events := 0
+			for result := range results {
+				if result.Err != nil {
+					errs = append(errs, result.Err)
+					klog.V(7).Infof("skipping %v", result.Err)
+					continue
+				}
+
+				tracker.Record(auditutil.EventToAttributes(result.Obj.(*audit.Event)))
+				events++
+			}
+
+			klog.V(5).Infof("Processed %v audit events", events)
+
+			report := []auditutil.SubjectUsage{}
+			for _, s := range tracker.Report() {
+				if !subjectsRegex.MatchString(rbac.SubjectName(s.Subject)) {
+					continue
+				}
+
+				if unusedOnly {
+					if len(s.Unused) == 0 {
+						continue
+					}
+					s.Used = nil
+				}
+
+				report = append(report, s)
+			}
+
+			switch output {
+			case "table":
+				rows := [][]string{}
+				for _, s := range report {
+					usages := append(append([]auditutil.RuleUsage{}, s.Unused...), s.Used...)
+					for _, u := range usages {
+						scope := u.Namespace
+						if scope == "" {
+							scope = "cluster-wide"
+						}
+
+						uses := "UNUSED"
+						if u.Count > 0 {
+							uses = fmt.Sprintf("%v", u.Count)
+						}
+
+						rows = append(rows, []string{
+							s.Kind,
+							s.Name,
+							s.Namespace,
+							scope,
+							u.GrantedBy.String(),
+							fmt.Sprintf("%v>>%v", u.RoleRef.Kind, u.RoleRef.Name),
+							formatPolicyRule(u.Rule),
+							uses,
+						})
+					}
+				}
+
+				table := tablewriter.NewWriter(os.Stdout)
+				table.SetHeader([]string{"TYPE", "SUBJECT", "NAMESPACE", "SCOPE", "GRANTED BY", "ROLE", "RULE", "USES"})
+				table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+				table.SetBorder(false)
+				table.SetAlignment(tablewriter.ALIGN_LEFT)
+				table.SetAutoWrapText(false)
+
+				table.AppendBulk(rows)
+				table.Render()
+
+			case "yaml":
+				data, err := yaml.Marshal(&report)
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
+				fmt.Fprintln(os.Stdout, string(data))
+
+			case "json":
+				data, err := json.Marshal(&report)
+				if err != nil {
+					return fmt.Errorf("Processing error - %v", err)
+				}
+
+				fmt.Fprintln(os.Stdout, string(data))
+
+			default:
+				return fmt.Errorf("Unsupported output format")
+			}
+
+			return errors.NewAggregate(errs)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringArrayVarP(&auditSources, "filename", "f", auditSources, "File, Directory, URL, or - for STDIN to read audit events from")
+	flags.StringVar(&input.ClusterContext, "cluster-context", "", "Cluster Context .use 'kubectl config get-contexts' to list available contexts")
+	flags.StringVar(&input.Infile, "rbac-file", "", "Read the RBAC resources from a file, a directory or a snapshot archive (see 'rbac-tool snapshot') instead of connecting to a cluster")
+	flags.StringVarP(&output, "output", "o", "table", "Output type: table | json | yaml")
+	flags.BoolVar(&unusedOnly, "unused-only", false, "Only report the rules that were never exercised")
+	flags.StringVar(&includeSubjects, "include-subjects", ".*", "A regular expression to limit the subjects we report (matched against the name, or namespace/name for ServiceAccounts)")
+
+	return cmd
+}
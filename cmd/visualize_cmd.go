@@ -45,6 +45,9 @@ kubectl get roles,rolebindings,clusterroles,clusterrolebindings,serviceaccounts
 # Generate RBAC Graph for permissions used by cluster pods 
 rbac-tool viz --include-pods-only
 
+# Generate RBAC Graph for permissions used by pods captured in a snapshot archive
+rbac-tool viz --file cluster-snapshot.tar.gz --include-pods-only
+
 `,
 		Hidden: false,
 		RunE: func(c *cobra.Command, args []string) error {
@@ -70,7 +73,7 @@ rbac-tool viz --include-pods-only
 	flags := cmd.Flags()
 
 	flags.StringVar(&opts.ClusterContext, "cluster-context", "", "Cluster Context .use 'kubectl config get-contexts' to list available contexts")
-	flags.StringVarP(&opts.Infile, "file", "f", "", "Input File - use '-' to read from stdin")
+	flags.StringVarP(&opts.Infile, "file", "f", "", "Input File, directory or snapshot archive (see 'rbac-tool snapshot') - use '-' to read from stdin")
 
 	flags.StringVar(&opts.Outfile, "outfile", "rbac.html", "Output file")
 	flags.StringVar(&opts.Outformat, "outformat", "html", "Output format: dot or html")
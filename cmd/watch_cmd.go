@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/alcideio/rbac-tool/pkg/analysis"
+	"github.com/alcideio/rbac-tool/pkg/cache"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+	"github.com/alcideio/rbac-tool/pkg/utils"
+	"github.com/alcideio/rbac-tool/pkg/watch"
+)
+
+func NewCommandWatch() *cobra.Command {
+
+	input := &inputSource{}
+	output := "text"
+	withAnalysis := false
+	customConfig := ""
+	implicitGroups := true
+	debounce := time.Second
+	maxWait := 10 * time.Second
+	resync := time.Duration(0)
+
+	// Support overrides
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Watch the cluster RBAC resources and stream the effective permission changes",
+		Long: `
+Watch ServiceAccounts, Roles, ClusterRoles, RoleBindings and ClusterRoleBindings and emit an event
+whenever the effective permissions of a subject change - who gained or lost which verb/resource,
+and through which binding. A subject granted the same access through another binding is reported as well.
+Changes made less than the --debounce period apart are reported together - a steady stream of changes
+is reported at least every --max-wait.
+
+With --analysis the analysis rules (see 'rbac-tool analysis') are re-evaluated on every change
+and the findings that were not reported before the change are emitted as well.
+
+Examples:
+
+# Stream permission changes of the cluster pointed by current context
+rbac-tool watch
+
+# Stream permission changes and new analysis findings as JSON lines
+rbac-tool watch --analysis -o json
+
+`,
+		Args:          cobra.ExactArgs(0),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Hidden:        false,
+		RunE: func(c *cobra.Command, args []string) error {
+			var err error
+			var analysisConfig *analysis.AnalysisConfig
+
+			if output != "text" && output != "json" {
+				return fmt.Errorf("Unsupported output format")
+			}
+
+			if withAnalysis {
+				analysisConfig = analysis.DefaultAnalysisConfig()
+
+				//Override Rules (if provided)
+				if customConfig != "" {
+					analysisConfig, err = analysis.LoadAnalysisConfig(customConfig)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			client, err := input.NewClient()
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			permsCache := cache.NewCache(client.Client, resync)
+			permsCache.Debounce = debounce
+			permsCache.MaxWait = maxWait
+
+			if err := permsCache.Start(ctx); err != nil {
+				return err
+			}
+
+			watcher, err := watch.NewWatcher(permsCache.Permissions(), implicitGroups, analysisConfig)
+			if err != nil {
+				return err
+			}
+
+			utils.ConsolePrinter("Watching RBAC permission changes")
+
+			permsCache.Run(ctx, func(old *rbac.Permissions, updated *rbac.Permissions) {
+				for _, e := range watcher.Update(updated) {
+					if err := printWatchEvent(os.Stdout, output, &e); err != nil {
+						utils.ConsolePrinter(fmt.Sprintf("Failed to print event - %v", err))
+					}
+				}
+			})
+
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	input.AddClusterFlags(flags)
+	flags.StringVarP(&output, "output", "o", "text", "Output type: text | json (one event per line)")
+	flags.BoolVar(&withAnalysis, "analysis", false, "Re-run the analysis rules on every change and emit the new findings")
+	flags.StringVar(&customConfig, "config", "", "Load analysis rules configuration from file (used with --analysis)")
+	flags.BoolVar(&implicitGroups, "implicit-groups", true, "Credit ServiceAccounts with the permissions of their implicit groups (system:serviceaccounts, system:serviceaccounts:<namespace>, system:authenticated)")
+	flags.DurationVar(&debounce, "debounce", debounce, "Report the changes made less than this period apart together")
+	flags.DurationVar(&maxWait, "max-wait", maxWait, "The longest changes are held back waiting for a quiet --debounce period - 0 disables the limit")
+	flags.DurationVar(&resync, "resync", resync, "Informers resync period - 0 disables resync")
+
+	return cmd
+}
+
+func printWatchEvent(w io.Writer, output string, e *watch.Event) error {
+	if output == "json" {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	ts := e.Time.Format(time.RFC3339)
+
+	switch e.Type {
+	case watch.PermissionsChanged:
+		subject := fmt.Sprintf("%v %v", e.Change.Kind, rbac.SubjectName(e.Change.Subject))
+
+		changes := []struct {
+			change string
+			rules  []rbac.NamespacedPolicyRule
+		}{
+			{"+", e.Change.Added},
+			{"-", e.Change.Removed},
+		}
+
+		for _, change := range changes {
+			for _, rule := range change.rules {
+				var target string
+				if len(rule.NonResourceURLs) > 0 {
+					target = strings.Join(rule.NonResourceURLs, ",")
+				} else {
+					target = rule.Resource
+					if rule.APIGroup != "" {
+						target = fmt.Sprintf("%v.%v", rule.Resource, rule.APIGroup)
+					}
+					if len(rule.ResourceNames) > 0 {
+						target = fmt.Sprintf("%v [%v]", target, strings.Join(rule.ResourceNames, ","))
+					}
+				}
+
+				scope := "cluster-wide"
+				if rule.Namespace != "" {
+					scope = fmt.Sprintf("in %v", rule.Namespace)
+				}
+
+				grantedBy := rule.GrantedBy.String()
+				if rule.InheritedFrom != "" {
+					grantedBy = fmt.Sprintf("%v (via Group>>%v)", grantedBy, rule.InheritedFrom)
+				}
+
+				if _, err := fmt.Fprintf(w, "%v %v %v %v %v %v via %v\n", ts, change.change, subject, rule.Verb, target, scope, grantedBy); err != nil {
+					return err
+				}
+			}
+		}
+
+	case watch.NewFinding:
+		f := e.Finding
+		subject := fmt.Sprintf("%v %v", f.Subject.Kind, rbac.SubjectName(*f.Subject))
+
+		_, err := fmt.Fprintf(w, "%v ! %v [%v] %v - %v\n", ts, subject, strings.ToUpper(f.Finding.Severity), f.Finding.RuleName, f.Finding.Message)
+		return err
+	}
+
+	return nil
+}
@@ -1,14 +1,28 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/alcideio/rbac-tool/pkg/analysis"
 	"github.com/alcideio/rbac-tool/pkg/kube"
 	"github.com/alcideio/rbac-tool/pkg/rbac"
+	"github.com/alcideio/rbac-tool/pkg/utils"
 	"github.com/alcideio/rbac-tool/pkg/whoami"
 	"github.com/kylelemons/godebug/pretty"
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
+	authv1 "k8s.io/api/authentication/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/klog"
+	"sigs.k8s.io/yaml"
 )
 
 type whoAmI struct {
@@ -23,17 +37,58 @@ type whoAmI struct {
 
 func NewCommandWhoAmI() *cobra.Command {
 	clusterContext := ""
+	list := false
+	namespace := "default"
+	output := "table"
+	offline := false
+	expiryWarning := 7 * 24 * time.Hour
 
 	cmd := &cobra.Command{
 		Use:     "whoami",
 		Aliases: []string{"who-am-i"},
-		Example: "rbac-tool whoami",
-		Short:   "Shows the subject for the current context with which one authenticates with the cluster",
-		Long:    `Shows the subject for the current context with which one authenticates with the cluster`,
-		Hidden:  false,
+		Example: `rbac-tool whoami
+
+# What can the current identity do in namespace payments - and which of it is risky
+rbac-tool whoami --list -n payments
+
+# Decode the credentials of the kubeconfig context without contacting the cluster
+rbac-tool whoami --offline -c myctx`,
+		Short: "Shows the subject for the current context with which one authenticates with the cluster",
+		Long: `Shows the subject for the current context with which one authenticates with the cluster.
+
+With --list the effective rules of the identity in the namespace are listed as well - as reviewed by the API server
+(SelfSubjectRulesReview), completed from the RBAC resources when the identity can read them.
+The risky capabilities are flagged with the default analysis rules.
+
+With --offline nothing is sent to the cluster - the credentials of the kubeconfig context are decoded instead:
+the client certificate, the JWT/OIDC and ServiceAccount token claims and the exec plugin configuration.
+Credentials that expired or are about to expire are flagged.`,
+		Hidden: false,
 		RunE: func(c *cobra.Command, args []string) error {
 			var err error
 
+			if offline {
+				if list {
+					return fmt.Errorf("--list requires access to the cluster and cannot be used with --offline")
+				}
+
+				creds, err := whoami.InspectCredentials(kube.ClientConfig(clusterContext), clusterContext, time.Now(), expiryWarning)
+				if err != nil {
+					return err
+				}
+
+				for _, w := range creds.Warnings {
+					utils.ConsolePrinter(w)
+				}
+
+				switch output {
+				case "table":
+					return printCredentials(os.Stdout, creds)
+				default:
+					return printWhoAmI(output, creds)
+				}
+			}
+
 			kubeClient, err := kube.NewClient(clusterContext)
 			if err != nil {
 				return fmt.Errorf("Failed to create kubernetes client - %v", err)
@@ -44,14 +99,229 @@ func NewCommandWhoAmI() *cobra.Command {
 				return err
 			}
 
-			fmt.Fprintln(os.Stdout, pretty.Sprint(userInfo))
+			if !list {
+				switch output {
+				case "table":
+					fmt.Fprintln(os.Stdout, pretty.Sprint(userInfo))
+					return nil
+				default:
+					return printWhoAmI(output, userInfo)
+				}
+			}
+
+			access, err := userAccess(kubeClient, userInfo, namespace)
+			if err != nil {
+				return err
+			}
+
+			if access.Incomplete {
+				utils.ConsolePrinter(fmt.Sprintf("The rules are incomplete - %v", access.EvaluationError))
+			}
 
-			return err
+			switch output {
+			case "table":
+				return printUserAccess(os.Stdout, access)
+			default:
+				return printWhoAmI(output, access)
+			}
 		},
 	}
 
 	flags := cmd.Flags()
 	flags.StringVarP(&clusterContext, "cluster-context", "c", "", "Cluster Context .use 'kubectl config get-contexts' to list available contexts")
+	flags.BoolVar(&list, "list", false, "List the effective rules and the risky capabilities of the current identity in the namespace")
+	flags.StringVarP(&namespace, "namespace", "n", "default", "The namespace to list the rules in")
+	flags.StringVarP(&output, "output", "o", "table", "Output type: table | json | yaml")
+	flags.BoolVar(&offline, "offline", false, "Decode the credentials of the kubeconfig context without contacting the cluster")
+	flags.DurationVar(&expiryWarning, "expiry-warning", expiryWarning, "Flag the credentials that expire within the duration (with --offline)")
 
 	return cmd
 }
+
+// userAccess combines the rules the API server reviews for the identity with the rules of the RBAC resources
+func userAccess(kubeClient *kube.KubeClient, userInfo *authv1.UserInfo, namespace string) (*whoami.Access, error) {
+	access := &whoami.Access{User: userInfo, Namespace: namespace}
+
+	status, rules, err := whoami.SelfSubjectRules(kubeClient.Client, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	access.Incomplete = status.Incomplete
+	access.EvaluationError = status.EvaluationError
+
+	// Identities that can read the RBAC resources get the rules and the bindings that grant them
+	perms, err := rbac.NewPermissionsFromCluster(kubeClient)
+	if err != nil {
+		klog.V(3).Infof("Skipping the RBAC resources - %v", err)
+	} else {
+		bindings := sets.NewString()
+		u := &user.DefaultInfo{Name: userInfo.Username, Groups: userInfo.Groups}
+
+		rbac.NewAuthorizer(perms).VisitRulesFor(u, namespace, func(source fmt.Stringer, rule *rbacv1.PolicyRule, err error) bool {
+			if rule != nil {
+				rules = append(rules, *rule)
+				bindings.Insert(source.String())
+			}
+			return true
+		})
+
+		access.Bindings = bindings.List()
+	}
+
+	access.Rules, err = compactPolicyRules(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	access.Risks, err = whoami.Risks(userInfo, namespace, access.Rules, kubeClient.IsNamespaced, analysis.DefaultAnalysisConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	return access, nil
+}
+
+func printUserAccess(out io.Writer, access *whoami.Access) error {
+	fmt.Fprintf(out, "User:      %v\n", access.User.Username)
+	fmt.Fprintf(out, "Groups:    %v\n", strings.Join(access.User.Groups, ", "))
+	fmt.Fprintf(out, "Namespace: %v\n\n", access.Namespace)
+
+	if err := printAccess(out, access.Rules); err != nil {
+		return err
+	}
+
+	if len(access.Bindings) > 0 {
+		fmt.Fprintln(out, "\nGranted By:")
+		for _, b := range access.Bindings {
+			fmt.Fprintf(out, "  %v\n", b)
+		}
+	}
+
+	if len(access.Risks) == 0 {
+		fmt.Fprintln(out, "\nNo risky capabilities")
+		return nil
+	}
+
+	fmt.Fprintln(out, "\nRisky Capabilities:")
+
+	rows := [][]string{}
+	for _, f := range access.Risks {
+		rows = append(rows, []string{strings.ToUpper(f.Finding.Severity), f.Finding.RuleName, f.Finding.Message})
+	}
+
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"SEVERITY", "RULE", "MESSAGE"})
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetBorder(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	table.AppendBulk(rows)
+	table.Render()
+
+	return nil
+}
+
+func printCredentials(out io.Writer, creds *whoami.Credentials) error {
+	w := tabwriter.NewWriter(out, 6, 4, 3, ' ', 0)
+
+	fmt.Fprintf(w, "Context:\t%v\n", creds.Context)
+	fmt.Fprintf(w, "Cluster:\t%v (%v)\n", creds.Cluster, creds.Server)
+	fmt.Fprintf(w, "AuthInfo:\t%v\n", creds.AuthInfo)
+
+	if creds.User != nil {
+		fmt.Fprintf(w, "User:\t%v\n", creds.User.Username)
+		fmt.Fprintf(w, "Groups:\t%v\n", strings.Join(creds.User.Groups, ", "))
+	}
+
+	if creds.Impersonate != "" {
+		fmt.Fprintf(w, "Impersonate:\t%v %v\n", creds.Impersonate, strings.Join(creds.ImpersonateGroups, ", "))
+	}
+
+	if creds.Username != "" {
+		fmt.Fprintf(w, "Basic Auth Username:\t%v\n", creds.Username)
+	}
+
+	if cert := creds.Certificate; cert != nil {
+		fmt.Fprintf(w, "\nClient Certificate\n")
+		fmt.Fprintf(w, "  Subject:\t%v\n", cert.Subject)
+		fmt.Fprintf(w, "  Issuer:\t%v\n", cert.Issuer)
+		fmt.Fprintf(w, "  Valid:\t%v - %v\n", cert.NotBefore.Format(time.RFC3339), cert.NotAfter.Format(time.RFC3339))
+		fmt.Fprintf(w, "  Status:\t%v\n", cert.Status)
+	}
+
+	if creds.Token != nil {
+		fmt.Fprintf(w, "\nBearer Token\n")
+		printTokenInfo(w, creds.Token)
+	}
+
+	if p := creds.AuthProvider; p != nil {
+		fmt.Fprintf(w, "\nAuth Provider\n")
+		fmt.Fprintf(w, "  Name:\t%v\n", p.Name)
+		if p.IDToken != nil {
+			printTokenInfo(w, p.IDToken)
+		}
+	}
+
+	if e := creds.Exec; e != nil {
+		fmt.Fprintf(w, "\nExec Plugin\n")
+		fmt.Fprintf(w, "  API Version:\t%v\n", e.APIVersion)
+		fmt.Fprintf(w, "  Command:\t%v %v\n", e.Command, strings.Join(e.Args, " "))
+		if len(e.Env) > 0 {
+			fmt.Fprintf(w, "  Env:\t%v\n", strings.Join(e.Env, ", "))
+		}
+		if e.InteractiveMode != "" {
+			fmt.Fprintf(w, "  Interactive Mode:\t%v\n", e.InteractiveMode)
+		}
+	}
+
+	return w.Flush()
+}
+
+func printTokenInfo(w io.Writer, token *whoami.TokenInfo) {
+	if token.Opaque {
+		fmt.Fprintf(w, "  Type:\tOpaque (not a JWT)\n")
+		return
+	}
+
+	if token.ServiceAccount != "" {
+		fmt.Fprintf(w, "  ServiceAccount:\t%v\n", token.ServiceAccount)
+	}
+	fmt.Fprintf(w, "  Subject:\t%v\n", token.Subject)
+	fmt.Fprintf(w, "  Issuer:\t%v\n", token.Issuer)
+	fmt.Fprintf(w, "  Audience:\t%v\n", strings.Join(token.Audience, ", "))
+	if len(token.Groups) > 0 {
+		fmt.Fprintf(w, "  Groups:\t%v\n", strings.Join(token.Groups, ", "))
+	}
+	if token.Email != "" {
+		fmt.Fprintf(w, "  Email:\t%v\n", token.Email)
+	}
+	if token.ExpiresAt != nil {
+		fmt.Fprintf(w, "  Expires:\t%v\n", token.ExpiresAt.Format(time.RFC3339))
+	}
+	fmt.Fprintf(w, "  Status:\t%v\n", token.Status)
+}
+
+func printWhoAmI(output string, obj interface{}) error {
+	switch output {
+	case "yaml":
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("Processing error - %v", err)
+		}
+		fmt.Fprintln(os.Stdout, string(data))
+		return nil
+
+	case "json":
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("Processing error - %v", err)
+		}
+
+		fmt.Fprintln(os.Stdout, string(data))
+		return nil
+
+	default:
+		return fmt.Errorf("Unsupported output format")
+	}
+}
@@ -5,31 +5,21 @@ import (
 	"fmt"
 	"os"
 	"sort"
-	"strings"
 
-	"github.com/alcideio/rbac-tool/pkg/kube"
 	"github.com/alcideio/rbac-tool/pkg/rbac"
 
-	"github.com/antonmedv/expr"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog"
 	"sigs.k8s.io/yaml"
 )
 
-type whoCanQuery struct {
-	Verb           string
-	APIGroup       string
-	Kind           string
-	Name           string
-	NonResourceUrl string
-
-	Rules []rbac.SubjectPolicyList
-}
-
 func NewCommandWhoCan() *cobra.Command {
 
-	clusterContext := ""
+	input := &inputSource{}
+	implicitGroups := true
+	namespace := ""
 
 	output := "table"
 	// Support overrides
@@ -42,14 +32,20 @@ func NewCommandWhoCan() *cobra.Command {
 		Example:       "rbac-tool who-can delete deployments.apps",
 		Short:         "Shows which subjects have RBAC permissions to perform an action",
 		Long: `
-Shows which subjects have RBAC permissions to perform an action denoted by VERB on an object denoted as ( KIND | KIND/NAME | NON-RESOURCE-URL)
+Shows which subjects have RBAC permissions to perform an action denoted by VERB on an object denoted as ( KIND | KIND/NAME | KIND/SUBRESOURCE | KIND/SUBRESOURCE/NAME | NON-RESOURCE-URL)
 
 * VERB is a logical Kubernetes API verb like 'get', 'list', 'watch', 'delete', etc.
 * KIND is a Kubernetes resource kind. Shortcuts and API groups will be resolved, e.g. 'po' or 'deploy'.
+* SUBRESOURCE is a subresource of the kind, e.g. 'pods/exec' or 'pods/log'.
 * NAME is the name of a particular Kubernetes resource.
 * NON-RESOURCE-URL is a URL that starts with "/".
 
-Shows which subjects have RBAC permissions to <VERB>  ( KIND> | KIND/NAME | NON-RESOURCE-URL)
+Shows which subjects have RBAC permissions to <VERB>  ( KIND> | KIND/NAME | KIND/SUBRESOURCE | NON-RESOURCE-URL)
+
+Requests are evaluated with the same semantics as the Kubernetes RBAC authorizer (resource names, subresources,
+non-resource URL prefixes, namespace scoping).
+For each subject the binding and role that granted the access is shown - SCOPE is either cluster-wide 
+(ClusterRoleBinding) or the namespace of the RoleBinding.
 
 Examples:
 
@@ -65,136 +61,94 @@ rbac-tool who-can get /apis
 # Who can read a secret resource by the name some-secret
 rbac-tool who-can get secret/some-secret
 
-`,
-		Hidden: false,
-		RunE: func(c *cobra.Command, args []string) error {
-			var err error
+# Who can exec into pods in the payments namespace
+rbac-tool who-can create pods/exec -n payments
 
-			kind := ""
+# Who can read pod logs
+rbac-tool who-can get pods/log
 
-			queryEnv := whoCanQuery{
-				Verb:           args[0],
-				APIGroup:       "core",
-				Kind:           "*",
-				Name:           "*",
-				NonResourceUrl: "",
-				Rules:          nil,
-			}
+# Who can create Deployments - based on the RBAC resources in a directory of manifests
+rbac-tool who-can create deploy -f manifests/
 
-			if len(args) == 2 {
-				kind = args[1]
-			}
-
-			query := `
-				filter(
-					Rules, 
-					{any(
-						.AllowedTo, 
-						 { 	.Verb     in [Verb, "*"] and 
-							.Resource in [Kind, "*"] and 
-							.APIGroup in [APIGroup, "*"] and 
-							(Name == "*" or len(.ResourceNames) == 0 or Name in .ResourceNames)
-						 }
-					)}
-				)`
-
-			if strings.HasPrefix(kind, "/") {
-				queryEnv.NonResourceUrl = kind
-				query = `
-					filter(
-						Rules, 
-						{any(
-							.AllowedTo, 
-							 { 	.Verb           in [Verb, "*"] and 
-								(NonResourceUrl in .NonResourceURLs or (len(.NonResourceURLs) == 1 and .NonResourceURLs[0] == "*"))
-							 }
-						)}
-					)`
-			} else if strings.Contains(kind, "/") {
-				parts := strings.Split(kind, "/")
-
-				queryEnv.Kind = parts[0]
-				queryEnv.Name = parts[1]
-			} else {
-				queryEnv.Kind = kind
-			}
+# Who can read secrets - in every cluster of the kubeconfig
+rbac-tool who-can get secrets --all-contexts
 
-			client, err := kube.NewClient(clusterContext)
+`,
+		Hidden: false,
+		RunE: func(c *cobra.Command, args []string) error {
+			clusters, err := input.Clusters()
 			if err != nil {
-				return fmt.Errorf("Failed to create kubernetes client - %v", err)
+				return err
 			}
 
-			if queryEnv.NonResourceUrl == "" {
-				gr, err := client.Resolve(queryEnv.Verb, queryEnv.Kind, "")
+			query := func(input *inputSource) ([]rbac.SubjectGrants, error) {
+				client, perms, err := input.Load()
 				if err != nil {
-					return err
+					return nil, err
 				}
 
-				queryEnv.Kind = gr.Resource
-				if gr.Group != "" {
-					queryEnv.APIGroup = gr.Group
+				attrs, err := rbac.NewRequestAttributes(client, args[0], args[1], namespace)
+				if err != nil {
+					return nil, err
 				}
-			}
 
-			klog.V(8).Infof("query\n%v\n%#v\n", query, queryEnv)
+				authz := rbac.NewAuthorizer(perms)
+				namespaces := rbac.RequestNamespaces(client, authz, attrs)
 
-			program, err := expr.Compile(query)
-			if err != nil {
-				return err
-			}
+				klog.V(8).Infof("who-can %#v in %v", attrs, namespaces)
 
-			perms, err := rbac.NewPermissionsFromCluster(client)
-			if err != nil {
-				return err
+				return authz.WhoCan(perms, attrs, namespaces, implicitGroups), nil
 			}
 
-			permsPerSubject := rbac.NewSubjectPermissions(perms)
-			policies := rbac.NewSubjectPermissionsList(permsPerSubject)
+			header := []string{"TYPE", "SUBJECT", "NAMESPACE", "SCOPE", "GRANTED BY", "ROLE"}
 
-			queryEnv.Rules = policies
+			if input.IsMultiCluster() {
+				results := queryClusters(clusters, query)
 
-			out, err := expr.Run(program, queryEnv)
-			if err != nil {
-				return fmt.Errorf("Failed to run program - %v", err)
-			}
-
-			filteredPolicies := out.([]interface{})
+				switch output {
+				case "table":
+					rows := [][]string{}
+					for _, r := range results {
+						for _, row := range whoCanRows(r.Result) {
+							rows = append(rows, append([]string{r.Cluster}, row...))
+						}
+					}
 
-			switch output {
-			case "table":
-				rows := [][]string{}
-
-				for _, e := range filteredPolicies {
-					p := e.(rbac.SubjectPolicyList)
-					row := []string{
-						p.Kind,
-						p.Name,
-						p.Namespace,
+					renderWhoCanTable(append([]string{"CLUSTER"}, header...), rows)
+				case "yaml":
+					data, err := yaml.Marshal(&results)
+					if err != nil {
+						return fmt.Errorf("Processing error - %v", err)
 					}
-					rows = append(rows, row)
-				}
+					fmt.Fprintln(os.Stdout, string(data))
 
-				sort.Slice(rows, func(i, j int) bool {
-					if strings.Compare(rows[i][0], rows[j][0]) == 0 {
-						return (strings.Compare(rows[i][1], rows[j][1]) < 0)
+				case "json":
+					data, err := json.Marshal(&results)
+					if err != nil {
+						return fmt.Errorf("Processing error - %v", err)
 					}
 
-					return (strings.Compare(rows[i][0], rows[j][0]) < 0)
-				})
+					fmt.Fprintln(os.Stdout, string(data))
 
-				table := tablewriter.NewWriter(os.Stdout)
-				table.SetHeader([]string{"TYPE", "SUBJECT", "NAMESPACE"})
-				table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
-				table.SetBorder(false)
-				table.SetAlignment(tablewriter.ALIGN_LEFT)
-				//table.SetAutoMergeCells(true)
+				default:
+					return fmt.Errorf("Unsupported output format")
+				}
+
+				return reportClusterFailures(results)
+			}
+
+			subjects, err := query(clusters[0])
+			if err != nil {
+				return err
+			}
 
-				table.AppendBulk(rows)
-				table.Render()
+			switch output {
+			case "table":
+				renderWhoCanTable(header, whoCanRows(subjects))
 
 				return nil
 			case "yaml":
-				data, err := yaml.Marshal(&filteredPolicies)
+				data, err := yaml.Marshal(&subjects)
 				if err != nil {
 					return fmt.Errorf("Processing error - %v", err)
 				}
@@ -202,7 +156,7 @@ rbac-tool who-can get secret/some-secret
 				return nil
 
 			case "json":
-				data, err := json.Marshal(&filteredPolicies)
+				data, err := json.Marshal(&subjects)
 				if err != nil {
 					return fmt.Errorf("Processing error - %v", err)
 				}
@@ -217,8 +171,72 @@ rbac-tool who-can get secret/some-secret
 	}
 
 	flags := cmd.Flags()
-	flags.StringVar(&clusterContext, "cluster-context", "", "Cluster Context .use 'kubectl config get-contexts' to list available contexts")
+	input.AddMultiClusterFlags(flags)
+	flags.StringVarP(&namespace, "namespace", "n", "", "Only show subjects allowed to perform the action in the namespace (cluster-wide grants included)")
 	flags.StringVarP(&output, "output", "o", "table", "Output type: table | json | yaml")
+	flags.BoolVar(&implicitGroups, "implicit-groups", true, "Credit ServiceAccounts with the permissions of their implicit groups (system:serviceaccounts, system:serviceaccounts:<namespace>, system:authenticated)")
 
 	return cmd
 }
+
+func whoCanRows(subjects []rbac.SubjectGrants) [][]string {
+	rows := [][]string{}
+
+	for _, p := range subjects {
+		//Multiple rules of the same role may allow the action
+		grants := sets.NewString()
+
+		for _, grant := range p.Grants {
+			scope := grant.Namespace
+			if scope == "" {
+				scope = "cluster-wide"
+			}
+
+			binding := grant.Binding.String()
+			if grant.BindingSubject.Kind != p.Kind || grant.BindingSubject.Name != p.Name {
+				binding = fmt.Sprintf("%v (via %v>>%v)", binding, grant.BindingSubject.Kind, grant.BindingSubject.Name)
+			}
+
+			role := fmt.Sprintf("%v>>%v", grant.RoleRef.Kind, grant.RoleRef.Name)
+
+			if grants.Has(binding + role) {
+				continue
+			}
+			grants.Insert(binding + role)
+
+			row := []string{
+				p.Kind,
+				p.Name,
+				p.Namespace,
+				scope,
+				binding,
+				role,
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		for c := range rows[i] {
+			if rows[i][c] != rows[j][c] {
+				return rows[i][c] < rows[j][c]
+			}
+		}
+
+		return false
+	})
+
+	return rows
+}
+
+func renderWhoCanTable(header []string, rows [][]string) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(header)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetBorder(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	//table.SetAutoMergeCells(true)
+
+	table.AppendBulk(rows)
+	table.Render()
+}
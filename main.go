@@ -8,6 +8,7 @@ import (
 	"os"
 
 	"github.com/alcideio/rbac-tool/cmd"
+	"github.com/alcideio/rbac-tool/pkg/kube"
 	"github.com/spf13/cobra"
 )
 
@@ -41,9 +42,20 @@ func RbacGenCmd() *cobra.Command {
 		cmd.NewCommandAnalysis(),
 		cmd.NewCommandGenerateShowPermissions(),
 		cmd.NewCommandWhoAmI(),
+		cmd.NewCommandSnapshot(),
+		cmd.NewCommandDiff(),
+		cmd.NewCommandExplain(),
+		cmd.NewCommandCanI(),
+		cmd.NewCommandEscalation(),
+		cmd.NewCommandUnusedPermissions(),
+		cmd.NewCommandAuditReplay(),
+		cmd.NewCommandWatch(),
+		cmd.NewCommandServe(),
+		cmd.NewCommandAdmissionWebhook(),
 	}
 
 	flags := rootCmd.PersistentFlags()
+	kube.AddClientFlags(flags)
 
 	klog.InitFlags(nil)
 	flags.AddGoFlagSet(goflag.CommandLine)
@@ -63,7 +75,7 @@ func RbacGenCmd() *cobra.Command {
 func main() {
 	rootCmd := RbacGenCmd()
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(-1)
 	}
 }
@@ -0,0 +1,309 @@
+package admission
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/analysis"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+// Reviewer evaluates Role, ClusterRole, RoleBinding and ClusterRoleBinding create/update requests against the analysis rules.
+// A request is rejected (or admitted with warnings) when the resulting subject permissions produce findings
+// that the current cluster state does not.
+type Reviewer struct {
+	//The current cluster state - ServiceAccounts, Roles, ClusterRoles, RoleBindings and ClusterRoleBindings
+	objects func() ([]runtime.Object, error)
+
+	analysisConfig *analysis.AnalysisConfig
+	implicitGroups bool
+
+	//Admit the requests with warnings instead of rejecting them
+	Warn bool
+
+	//Changes whenever the current cluster state changes - nil when it never does
+	Generation func() uint64
+
+	//The current cluster state and its report - reused until the generation changes
+	mu               sync.Mutex
+	cached           bool
+	cachedGeneration uint64
+	current          []runtime.Object
+	before           *analysis.AnalysisReport
+}
+
+// The API server limits objects to 3MiB - a review carries the object and the old object
+const maxRequestBytes = 7 * 1024 * 1024
+
+func NewReviewer(objects func() ([]runtime.Object, error), analysisConfig *analysis.AnalysisConfig, implicitGroups bool) *Reviewer {
+	return &Reviewer{
+		objects:        objects,
+		analysisConfig: analysisConfig,
+		implicitGroups: implicitGroups,
+	}
+}
+
+// Review evaluates the admission request
+func (r *Reviewer) Review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	resp := &admissionv1.AdmissionResponse{UID: req.UID, Allowed: true}
+
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return resp
+	}
+
+	obj, err := decodeObject(req)
+	if err != nil {
+		return deny(resp, http.StatusBadRequest, metav1.StatusReasonBadRequest, err)
+	}
+	if obj == nil {
+		klog.V(5).Infof("Skipping %v %v", req.Operation, req.Kind.String())
+		return resp
+	}
+
+	findings, err := r.NewFindings(obj)
+	if err != nil {
+		klog.Errorf("Failed to review %v %v/%v - %v", req.Kind.Kind, req.Namespace, req.Name, err)
+		if r.Warn {
+			resp.Warnings = []string{fmt.Sprintf("Failed to evaluate the RBAC analysis rules - %v", err)}
+			return resp
+		}
+		return deny(resp, http.StatusInternalServerError, metav1.StatusReasonInternalError, err)
+	}
+
+	if len(findings) == 0 {
+		return resp
+	}
+
+	messages := make([]string, 0, len(findings))
+	for _, f := range findings {
+		messages = append(messages, fmt.Sprintf("[%v] %v - %v %v: %v",
+			strings.ToUpper(f.Finding.Severity), f.Finding.RuleName, f.Subject.Kind, rbac.SubjectName(*f.Subject), f.Finding.Message))
+	}
+
+	if r.Warn {
+		resp.Warnings = messages
+		return resp
+	}
+
+	return deny(resp, http.StatusForbidden, metav1.StatusReasonForbidden, fmt.Errorf("The %v would produce new RBAC analysis findings: %v", req.Kind.Kind, strings.Join(messages, "; ")))
+}
+
+// NewFindings returns the findings of the cluster state with the object created or updated, that the current cluster state does not have
+func (r *Reviewer) NewFindings(obj runtime.Object) ([]analysis.AnalysisReportFinding, error) {
+	current, before, err := r.currentState()
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := r.analyze(replaceObject(current, obj))
+	if err != nil {
+		return nil, err
+	}
+
+	existing := map[string]bool{}
+	for i := range before.Findings {
+		existing[findingKey(&before.Findings[i])] = true
+	}
+
+	findings := []analysis.AnalysisReportFinding{}
+	for i := range after.Findings {
+		if !existing[findingKey(&after.Findings[i])] {
+			findings = append(findings, after.Findings[i])
+		}
+	}
+
+	return findings, nil
+}
+
+// currentState returns the current cluster state and its report - analyzed again only when the generation changes
+func (r *Reviewer) currentState() ([]runtime.Object, *analysis.AnalysisReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var generation uint64
+	if r.Generation != nil {
+		generation = r.Generation()
+	}
+
+	if r.cached && r.cachedGeneration == generation {
+		return r.current, r.before, nil
+	}
+
+	//The generation is read before listing - a change while listing is picked up by the next request
+	current, err := r.objects()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	before, err := r.analyze(current)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r.cached = true
+	r.cachedGeneration = generation
+	r.current = current
+	r.before = before
+
+	return current, before, nil
+}
+
+// findingKey identifies a finding by the rule, the subject and the bindings that grant the access -
+// so a new binding that grants an already flagged subject the same access is reported too
+func findingKey(f *analysis.AnalysisReportFinding) string {
+	bindings := make([]string, 0, len(f.GrantedBy))
+	for _, b := range f.GrantedBy {
+		bindings = append(bindings, b.String())
+	}
+	sort.Strings(bindings)
+
+	return f.Key() + "|" + strings.Join(bindings, ",")
+}
+
+func (r *Reviewer) analyze(objs []runtime.Object) (*analysis.AnalysisReport, error) {
+	perms, err := rbac.NewPermissionsFromResourceList(objs)
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []rbac.SubjectPermissions
+	if r.implicitGroups {
+		policies = rbac.NewEffectiveSubjectPermissions(perms)
+	} else {
+		policies = rbac.NewSubjectPermissions(perms)
+	}
+
+	analyzer := analysis.CreateAnalyzer(r.analysisConfig, rbac.NewSubjectPermissionsList(policies))
+	if analyzer == nil {
+		return nil, fmt.Errorf("Failed to create analyzer")
+	}
+
+	return analyzer.Analyze()
+}
+
+// ServeHTTP handles AdmissionReview requests
+func (r *Reviewer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxRequestBytes))
+	if err != nil {
+		code := http.StatusBadRequest
+		if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, fmt.Sprintf("Failed to read request - %v", err), code)
+		return
+	}
+
+	review := &admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(data, review); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to decode AdmissionReview - %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if review.Request == nil {
+		http.Error(w, "AdmissionReview has no request", http.StatusBadRequest)
+		return
+	}
+
+	data, err = json.Marshal(r.ReviewAdmission(review))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Processing error - %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// ReviewAdmission returns the AdmissionReview response of the AdmissionReview request
+func (r *Reviewer) ReviewAdmission(review *admissionv1.AdmissionReview) *admissionv1.AdmissionReview {
+	return &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: admissionv1.SchemeGroupVersion.String(), Kind: "AdmissionReview"},
+		Response: r.Review(review.Request),
+	}
+}
+
+// decodeObject decodes the RBAC object of the request - nil for other kinds
+func decodeObject(req *admissionv1.AdmissionRequest) (runtime.Object, error) {
+	if req.Kind.Group != rbacv1.GroupName {
+		return nil, nil
+	}
+
+	var obj runtime.Object
+	switch req.Kind.Kind {
+	case "Role":
+		obj = &rbacv1.Role{}
+	case "ClusterRole":
+		obj = &rbacv1.ClusterRole{}
+	case "RoleBinding":
+		obj = &rbacv1.RoleBinding{}
+	case "ClusterRoleBinding":
+		obj = &rbacv1.ClusterRoleBinding{}
+	default:
+		return nil, nil
+	}
+
+	if err := json.Unmarshal(req.Object.Raw, obj); err != nil {
+		return nil, fmt.Errorf("Failed to decode %v - %v", req.Kind.Kind, err)
+	}
+
+	//The namespace may be omitted from the object of a namespaced request
+	if accessor, ok := obj.(metav1.Object); ok && accessor.GetNamespace() == "" && (req.Kind.Kind == "Role" || req.Kind.Kind == "RoleBinding") {
+		accessor.SetNamespace(req.Namespace)
+	}
+
+	return obj, nil
+}
+
+// replaceObject returns the objects with obj added - or replacing the object of the same kind, namespace and name
+func replaceObject(objs []runtime.Object, obj runtime.Object) []runtime.Object {
+	key := objectKey(obj)
+
+	result := make([]runtime.Object, 0, len(objs)+1)
+	for _, o := range objs {
+		if objectKey(o) == key {
+			continue
+		}
+		result = append(result, o)
+	}
+
+	return append(result, obj)
+}
+
+func objectKey(obj runtime.Object) string {
+	switch o := obj.(type) {
+	case *rbacv1.Role:
+		return fmt.Sprintf("Role/%v/%v", o.Namespace, o.Name)
+	case *rbacv1.ClusterRole:
+		return fmt.Sprintf("ClusterRole/%v", o.Name)
+	case *rbacv1.RoleBinding:
+		return fmt.Sprintf("RoleBinding/%v/%v", o.Namespace, o.Name)
+	case *rbacv1.ClusterRoleBinding:
+		return fmt.Sprintf("ClusterRoleBinding/%v", o.Name)
+	}
+
+	return ""
+}
+
+func deny(resp *admissionv1.AdmissionResponse, code int32, reason metav1.StatusReason, err error) *admissionv1.AdmissionResponse {
+	resp.Allowed = false
+	resp.Result = &metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    code,
+		Reason:  reason,
+		Message: err.Error(),
+	}
+
+	return resp
+}
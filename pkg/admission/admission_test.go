@@ -0,0 +1,189 @@
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/analysis"
+	"github.com/alcideio/rbac-tool/pkg/utils"
+)
+
+func Test__Reviewer(t *testing.T) {
+	defer klog.Flush()
+
+	objs, err := utils.ReadObjectsFromFile("../../testdata/admission/cluster.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read resources - %v", err)
+	}
+
+	reviewer := NewReviewer(func() ([]runtime.Object, error) { return objs, nil }, analysis.DefaultAnalysisConfig(), true)
+
+	tests := []struct {
+		fixture string
+		allowed bool
+		message string
+	}{
+		{fixture: "rolebinding-secret-reader.json", allowed: false, message: "ServiceAccount payments/api"},
+		{fixture: "role-update-secrets.json", allowed: false, message: "Secret Readers"},
+		{fixture: "role-update-configmaps.json", allowed: true},
+		//kube-system is excluded by the default analysis config
+		{fixture: "clusterrolebinding-excluded.json", allowed: true},
+	}
+
+	for _, test := range tests {
+		data, err := os.ReadFile("../../testdata/admission/" + test.fixture)
+		if err != nil {
+			t.Fatalf("Failed to read fixture - %v", err)
+		}
+
+		srv := httptest.NewServer(reviewer)
+		resp, err := http.Post(srv.URL, "application/json", bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Failed to post '%v' - %v", test.fixture, err)
+		}
+
+		review := admissionv1.AdmissionReview{}
+		err = json.NewDecoder(resp.Body).Decode(&review)
+		resp.Body.Close()
+		srv.Close()
+		if err != nil {
+			t.Fatalf("Failed to decode the response of '%v' - %v", test.fixture, err)
+		}
+
+		if review.Response == nil || review.Response.Allowed != test.allowed {
+			t.Fatalf("Expecting allowed=%v for '%v' got %+v", test.allowed, test.fixture, review.Response)
+		}
+
+		if !test.allowed && !strings.Contains(review.Response.Result.Message, test.message) {
+			t.Fatalf("Expecting '%v' in the response of '%v' got '%v'", test.message, test.fixture, review.Response.Result.Message)
+		}
+	}
+
+	//Warn instead of reject
+	reviewer.Warn = true
+	data, err := os.ReadFile("../../testdata/admission/rolebinding-secret-reader.json")
+	if err != nil {
+		t.Fatalf("Failed to read fixture - %v", err)
+	}
+
+	review := admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(data, &review); err != nil {
+		t.Fatalf("Failed to decode fixture - %v", err)
+	}
+
+	response := reviewer.ReviewAdmission(&review).Response
+	if !response.Allowed || len(response.Warnings) == 0 || response.UID != review.Request.UID {
+		t.Fatalf("Expecting the request to be allowed with warnings got %+v", response)
+	}
+}
+
+func Test__ReviewerCurrentState(t *testing.T) {
+	defer klog.Flush()
+
+	objs, err := utils.ReadObjectsFromFile("../../testdata/admission/cluster.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read resources - %v", err)
+	}
+
+	listed := 0
+	generation := uint64(0)
+	reviewer := NewReviewer(func() ([]runtime.Object, error) { listed++; return objs, nil }, analysis.DefaultAnalysisConfig(), true)
+	reviewer.Generation = func() uint64 { return generation }
+
+	binding := func(name string) *rbacv1.RoleBinding {
+		return &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "payments"},
+			Subjects:   []rbacv1.Subject{{Kind: "ServiceAccount", Name: "api", Namespace: "payments"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "secret-reader"},
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		findings, err := reviewer.NewFindings(binding("api-secrets"))
+		if err != nil {
+			t.Fatalf("Failed to review - %v", err)
+		}
+		if len(findings) == 0 {
+			t.Fatalf("Expecting findings for the secret reader binding")
+		}
+	}
+
+	if listed != 1 {
+		t.Fatalf("Expecting the current state to be listed once got %v", listed)
+	}
+
+	//The binding is created - the current state is listed again
+	objs = append(objs, binding("api-secrets"))
+	generation++
+
+	findings, err := reviewer.NewFindings(binding("api-secrets"))
+	if err != nil {
+		t.Fatalf("Failed to review - %v", err)
+	}
+	if len(findings) != 0 {
+		t.Fatalf("Expecting no new findings when updating the existing binding got %+v", findings)
+	}
+	if listed != 2 {
+		t.Fatalf("Expecting the current state to be listed again after a change got %v", listed)
+	}
+
+	//Another binding that grants the already flagged subject the same access
+	findings, err = reviewer.NewFindings(binding("api-secrets-copy"))
+	if err != nil {
+		t.Fatalf("Failed to review - %v", err)
+	}
+	if len(findings) == 0 {
+		t.Fatalf("Expecting findings for the new binding of an already flagged subject")
+	}
+	for _, f := range findings {
+		if !strings.Contains(findingKey(&f), "api-secrets-copy") {
+			t.Fatalf("Expecting the finding to be granted by the new binding got %+v", f.GrantedBy)
+		}
+	}
+}
+
+func Test__ReviewerBadRequests(t *testing.T) {
+	reviewer := NewReviewer(func() ([]runtime.Object, error) { return nil, nil }, analysis.DefaultAnalysisConfig(), true)
+
+	srv := httptest.NewServer(reviewer)
+	defer srv.Close()
+
+	tests := []struct {
+		body    string
+		code    int
+		message string
+	}{
+		{body: `{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview"}`, code: http.StatusBadRequest, message: "AdmissionReview has no request"},
+		{body: `{"request": `, code: http.StatusBadRequest, message: "Failed to decode AdmissionReview"},
+		{body: `{"request": {"uid": "` + strings.Repeat("x", maxRequestBytes) + `"}}`, code: http.StatusRequestEntityTooLarge, message: "Failed to read request"},
+	}
+
+	for _, test := range tests {
+		resp, err := http.Post(srv.URL, "application/json", strings.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("Failed to post - %v", err)
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Failed to read the response - %v", err)
+		}
+
+		if resp.StatusCode != test.code || !strings.Contains(string(data), test.message) {
+			t.Fatalf("Expecting %v '%v' got %v '%v'", test.code, test.message, resp.StatusCode, string(data))
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	v1 "k8s.io/api/rbac/v1"
 	"reflect"
+	"sort"
 	"time"
 
 	"github.com/alcideio/rbac-tool/pkg/rbac"
@@ -19,9 +20,17 @@ type Analyzer interface {
 }
 
 func CreateAnalyzer(config *AnalysisConfig, policies []rbac.SubjectPolicyList) Analyzer {
+	return CreateWorkloadAnalyzer(config, policies, nil)
+}
+
+// CreateWorkloadAnalyzer creates an analyzer that is aware of the workloads that run as each ServiceAccount (see NewWorkloads).
+// The workloads are exposed to the rules as 'subject.workloads', they are attached to the findings
+// and the findings of ServiceAccounts that are not used by any workload are lowered in severity.
+func CreateWorkloadAnalyzer(config *AnalysisConfig, policies []rbac.SubjectPolicyList, workloads map[string][]Workload) Analyzer {
 	analyzer := analyzer{
 		config:           *config,
 		policies:         policies,
+		workloads:        workloads,
 		rules:            []*analysisRule{},
 		globalExclusions: []*exclusion{},
 	}
@@ -222,6 +231,9 @@ type analyzer struct {
 	globalExclusions []*exclusion
 
 	policiesObj interface{}
+
+	//map[namespace/serviceaccount][]Workload - nil when workloads were not loaded
+	workloads map[string][]Workload
 }
 
 func (a *analyzer) initialize() error {
@@ -246,6 +258,12 @@ func (a *analyzer) initialize() error {
 	}
 	a.policiesObj = m["subjects"]
 
+	if a.workloads != nil {
+		if err := a.addWorkloads(); err != nil {
+			return err
+		}
+	}
+
 	for i, _ := range a.config.Rules {
 		aRule, err := newAnalysisRule(&a.config.Rules[i])
 		if err != nil {
@@ -258,6 +276,45 @@ func (a *analyzer) initialize() error {
 	return nil
 }
 
+// addWorkloads exposes the workloads of each ServiceAccount to the rules as 'subject.workloads' (empty for other subjects)
+func (a *analyzer) addWorkloads() error {
+	subjects, ok := a.policiesObj.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	for _, subject := range subjects {
+		sub, ok := subject.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		workloads := []Workload{}
+		if kind, _ := sub["kind"].(string); kind == v1.ServiceAccountKind {
+			namespace, _ := sub["namespace"].(string)
+			name, _ := sub["name"].(string)
+
+			if w, exist := a.workloads[WorkloadsKey(namespace, name)]; exist {
+				workloads = w
+			}
+		}
+
+		b, err := json.Marshal(workloads)
+		if err != nil {
+			return err
+		}
+
+		var obj []interface{}
+		if err := json.Unmarshal(b, &obj); err != nil {
+			return err
+		}
+
+		sub["workloads"] = obj
+	}
+
+	return nil
+}
+
 func (a *analyzer) shouldExclude(subject map[string]interface{}, exclusions []*exclusion) (bool, int, error) {
 	for i, exclusion := range exclusions {
 		if exclusion.exclusion.Disabled {
@@ -291,6 +348,97 @@ func (a *analyzer) shouldExclude(subject map[string]interface{}, exclusions []*e
 	return false, 0, nil
 }
 
+// grantedBy finds the bindings that grant the subject the permissions the rule matched - the rule is evaluated with the
+// rules of each binding on its own. When no binding matches on its own (the combination matched) the bindings that the
+// match does not depend on are left out one at a time - so unrelated bindings of the subject are not returned.
+func (a *analyzer) grantedBy(rule *analysisRule, subject map[string]interface{}) []rbac.BindingRef {
+	allowedTo, _ := subject["allowedTo"].([]interface{})
+
+	refs := map[string]rbac.BindingRef{}
+	rulesByBinding := map[string][]interface{}{}
+	for _, r := range allowedTo {
+		policyRule, _ := r.(map[string]interface{})
+		grantedBy, _ := policyRule["grantedBy"].(map[string]interface{})
+
+		ref := rbac.BindingRef{}
+		ref.Kind, _ = grantedBy["kind"].(string)
+		ref.Namespace, _ = grantedBy["namespace"].(string)
+		ref.Name, _ = grantedBy["name"].(string)
+		if ref.Name == "" {
+			continue
+		}
+
+		refs[ref.String()] = ref
+		rulesByBinding[ref.String()] = append(rulesByBinding[ref.String()], r)
+	}
+
+	keys := make([]string, 0, len(refs))
+	for k := range refs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	//matches evaluates the rule with the rules of the bindings
+	matches := func(bindings []string) bool {
+		partial := make(map[string]interface{}, len(subject))
+		for field, v := range subject {
+			partial[field] = v
+		}
+
+		rules := []interface{}{}
+		for _, k := range bindings {
+			rules = append(rules, rulesByBinding[k]...)
+		}
+		partial["allowedTo"] = rules
+
+		out, _, err := rule.compiledAnalysisExpr.Eval(map[string]interface{}{
+			"subjects": []interface{}{partial},
+		})
+		if err != nil {
+			klog.V(5).Infof("Failed to evaluate rule '%v' with the rules of %v - %v", rule.rule.Name, bindings, err)
+			return false
+		}
+
+		l, err := out.ConvertToNative(reflect.TypeOf([]interface{}{}))
+		return err == nil && len(l.([]interface{})) > 0
+	}
+
+	matched := []rbac.BindingRef{}
+	for _, k := range keys {
+		if matches([]string{k}) {
+			matched = append(matched, refs[k])
+		}
+	}
+
+	if len(matched) > 0 {
+		return matched
+	}
+
+	//The combination matched - keep only the bindings the match depends on
+	contributing := keys
+	if matches(contributing) {
+		for _, k := range keys {
+			without := make([]string, 0, len(contributing))
+			for _, c := range contributing {
+				if c != k {
+					without = append(without, c)
+				}
+			}
+
+			if len(without) > 0 && matches(without) {
+				contributing = without
+			}
+		}
+	}
+
+	res := make([]rbac.BindingRef, 0, len(contributing))
+	for _, k := range contributing {
+		res = append(res, refs[k])
+	}
+
+	return res
+}
+
 func (a *analyzer) Analyze() (*AnalysisReport, error) {
 	analysisStats := AnalysisStats{
 		RuleCount: len(a.config.Rules),
@@ -365,8 +513,12 @@ func (a *analyzer) Analyze() (*AnalysisReport, error) {
 				analysisStats.ExclusionCount++
 				klog.V(5).Infof("Skipping subject '%v' from rule exclusion - %v (exclusion #%v)", sub, rule.rule.Name, index+1)
 				ei := ExclusionInfo{
-					Subject: &s,
-					Message: fmt.Sprintf("For rule: \"%v\", subject excluded by the rule-level (#%v) - \"%v\" ", rule.rule.Name, index+1, rule.rule.Exclusions[index].Comment),
+					Subject:   &s,
+					Message:   fmt.Sprintf("For rule: \"%v\", subject excluded by the rule-level (#%v) - \"%v\" ", rule.rule.Name, index+1, rule.rule.Exclusions[index].Comment),
+					RuleName:  rule.rule.Name,
+					RuleUuid:  rule.rule.Uuid,
+					Comment:   rule.rule.Exclusions[index].Comment,
+					GrantedBy: a.grantedBy(rule, sub),
 				}
 				report.ExclusionsInfo = append(report.ExclusionsInfo, ei)
 				continue
@@ -383,8 +535,12 @@ func (a *analyzer) Analyze() (*AnalysisReport, error) {
 				analysisStats.ExclusionCount++
 				klog.V(5).Infof("Skipping subject '%v' from global exclusion - %v", s, index+1)
 				ei := ExclusionInfo{
-					Subject: &s,
-					Message: fmt.Sprintf("For rule: \"%v\", subject excluded by a global exclusion (#%v) - \"%v\" ", rule.rule.Name, index+1, a.globalExclusions[index].exclusion.Comment),
+					Subject:   &s,
+					Message:   fmt.Sprintf("For rule: \"%v\", subject excluded by a global exclusion (#%v) - \"%v\" ", rule.rule.Name, index+1, a.globalExclusions[index].exclusion.Comment),
+					RuleName:  rule.rule.Name,
+					RuleUuid:  rule.rule.Uuid,
+					Comment:   a.globalExclusions[index].exclusion.Comment,
+					GrantedBy: a.grantedBy(rule, sub),
 				}
 				report.ExclusionsInfo = append(report.ExclusionsInfo, ei)
 				continue
@@ -415,9 +571,22 @@ func (a *analyzer) Analyze() (*AnalysisReport, error) {
 			}
 
 			finding := AnalysisReportFinding{
-				Subject: &s,
-				Finding: info,
+				Subject:   &s,
+				Finding:   info,
+				GrantedBy: a.grantedBy(rule, sub),
+			}
+
+			if a.workloads != nil && s.Kind == v1.ServiceAccountKind {
+				finding.Workloads = a.workloads[WorkloadsKey(s.Namespace, s.Name)]
+
+				//Permissions of a ServiceAccount that nothing runs as are not exposed
+				if len(finding.Workloads) == 0 {
+					finding.Finding.RuleSeverity = finding.Finding.Severity
+					finding.Finding.Severity = lowerSeverity(finding.Finding.Severity)
+				}
 			}
+
+			finding.Fingerprint = Fingerprint(&finding)
 			report.Findings = append(report.Findings, finding)
 		}
 
@@ -0,0 +1,122 @@
+package analysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// BaselineInfo captures the comparison of a report with a baseline report
+type BaselineInfo struct {
+	//The baseline report file
+	File string
+
+	//The number of findings that are already in the baseline and were suppressed
+	Suppressed int
+
+	//The findings of the baseline that are no longer found
+	Resolved []AnalysisReportFinding
+}
+
+// Fingerprint identifies a finding by the rule, the subject and the bindings that grant the access.
+// Unlike Key the fingerprint changes when the access is granted by another binding.
+func Fingerprint(f *AnalysisReportFinding) string {
+	bindings := make([]string, 0, len(f.GrantedBy))
+	for _, b := range f.GrantedBy {
+		bindings = append(bindings, b.String())
+	}
+	sort.Strings(bindings)
+
+	data := fmt.Sprintf("%v|%v/%v/%v|%v", f.Finding.RuleUuid, f.Subject.Kind, f.Subject.Namespace, f.Subject.Name, strings.Join(bindings, ","))
+	sum := sha256.Sum256([]byte(data))
+
+	return hex.EncodeToString(sum[:16])
+}
+
+// LoadBaseline loads a previous analysis report (JSON or YAML)
+func LoadBaseline(fname string) (*AnalysisReport, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read baseline '%v' - %v", fname, err)
+	}
+
+	baseline := &AnalysisReport{}
+	if err := yaml.Unmarshal(data, baseline); err != nil {
+		return nil, fmt.Errorf("Failed to parse baseline '%v' - %v", fname, err)
+	}
+
+	for i, f := range baseline.Findings {
+		if f.Subject == nil {
+			return nil, fmt.Errorf("Invalid baseline '%v' - finding %v has no subject", fname, i)
+		}
+	}
+
+	return baseline, nil
+}
+
+// ApplyBaseline suppresses the findings of the report that are in the baseline - the findings of the baseline
+// that are no longer found are reported as resolved (see AnalysisReport.Baseline).
+// The findings of baselines that predate fingerprints are matched by the rule and the subject (see Key).
+func ApplyBaseline(report *AnalysisReport, baseline *AnalysisReport, fname string) {
+	existing := map[string]bool{}
+	for i := range baseline.Findings {
+		existing[baselineKey(&baseline.Findings[i])] = true
+	}
+
+	info := &BaselineInfo{File: fname, Resolved: []AnalysisReportFinding{}}
+	current := map[string]bool{}
+	findings := []AnalysisReportFinding{}
+
+	for i := range report.Findings {
+		f := &report.Findings[i]
+		fingerprint := fingerprintKey(f.Fingerprint)
+		current[fingerprint] = true
+		current[f.Key()] = true
+
+		if existing[fingerprint] || existing[f.Key()] {
+			info.Suppressed++
+			continue
+		}
+
+		findings = append(findings, *f)
+	}
+
+	for i := range baseline.Findings {
+		if !current[baselineKey(&baseline.Findings[i])] {
+			info.Resolved = append(info.Resolved, baseline.Findings[i])
+		}
+	}
+
+	report.Findings = findings
+	report.Baseline = info
+}
+
+// BaselineSummary summarizes the comparison with the baseline - the new, suppressed and resolved findings
+func BaselineSummary(report *AnalysisReport) string {
+	if report.Baseline == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("Baseline '%v' - %v new findings, %v suppressed, %v resolved",
+		report.Baseline.File, len(report.Findings), report.Baseline.Suppressed, len(report.Baseline.Resolved))
+}
+
+// baselineKey identifies a baseline finding by its fingerprint - or by Key for the reports that predate fingerprints
+// (their findings have no GrantedBy to compute the fingerprint from)
+func baselineKey(f *AnalysisReportFinding) string {
+	if f.Fingerprint != "" {
+		return fingerprintKey(f.Fingerprint)
+	}
+
+	return f.Key()
+}
+
+// fingerprintKey keeps the fingerprints apart from the Key of the findings
+func fingerprintKey(fingerprint string) string {
+	return "fingerprint:" + fingerprint
+}
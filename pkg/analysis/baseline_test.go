@@ -0,0 +1,147 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "k8s.io/api/rbac/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/yaml"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+func Test__ApplyBaseline(t *testing.T) {
+	defer klog.Flush()
+
+	secretReader := func(name string, binding string) rbac.SubjectPermissions {
+		return rbac.SubjectPermissions{
+			Subject: v1.Subject{Kind: v1.ServiceAccountKind, Namespace: "payments", Name: name},
+			Rules: map[string][]rbac.PolicyRule{
+				"payments": {
+					{
+						PolicyRule: v1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"secrets"}},
+						GrantedBy:  rbac.BindingRef{Kind: "RoleBinding", Namespace: "payments", Name: binding},
+					},
+				},
+			},
+		}
+	}
+
+	analyze := func(policies ...rbac.SubjectPermissions) *AnalysisReport {
+		report, err := CreateAnalyzer(DefaultAnalysisConfig(), rbac.NewSubjectPermissionsList(policies)).Analyze()
+		if err != nil {
+			t.Fatalf("Failed to analyze - %v", err)
+		}
+		return report
+	}
+
+	baseline := analyze(secretReader("api", "read-secrets"), secretReader("worker", "read-secrets"), secretReader("legacy", "read-secrets"), secretReader("cron", "read-secrets"))
+	if len(baseline.Findings) != 4 || baseline.Findings[0].Fingerprint == "" {
+		t.Fatalf("Expecting 4 fingerprinted findings got %+v", baseline.Findings)
+	}
+
+	//Reports that predate fingerprints have no granting bindings either
+	for i := range baseline.Findings {
+		if baseline.Findings[i].Subject.Name == "cron" {
+			baseline.Findings[i].Fingerprint = ""
+			baseline.Findings[i].GrantedBy = nil
+		}
+	}
+
+	fname := filepath.Join(t.TempDir(), "baseline.yaml")
+	data, err := yaml.Marshal(baseline)
+	if err != nil {
+		t.Fatalf("Failed to marshal baseline - %v", err)
+	}
+
+	if err := os.WriteFile(fname, data, 0644); err != nil {
+		t.Fatalf("Failed to write baseline - %v", err)
+	}
+
+	loaded, err := LoadBaseline(fname)
+	if err != nil {
+		t.Fatalf("Failed to load baseline - %v", err)
+	}
+
+	//api is unchanged, worker is granted by another binding, legacy was removed and batch is new -
+	//cron is matched by the rule and the subject
+	report := analyze(secretReader("api", "read-secrets"), secretReader("worker", "read-all-secrets"), secretReader("batch", "read-secrets"), secretReader("cron", "read-all-secrets"))
+	ApplyBaseline(report, loaded, fname)
+
+	if report.Baseline == nil || report.Baseline.Suppressed != 2 || len(report.Findings) != 2 || len(report.Baseline.Resolved) != 2 {
+		t.Fatalf("Expecting 2 new, 2 suppressed and 2 resolved findings got %+v %+v", report.Findings, report.Baseline)
+	}
+
+	for _, f := range report.Findings {
+		if f.Subject.Name == "api" || f.Subject.Name == "cron" {
+			t.Fatalf("Expecting the baseline finding to be suppressed got %+v", f)
+		}
+	}
+
+	if summary := BaselineSummary(report); summary != "Baseline '"+fname+"' - 2 new findings, 2 suppressed, 2 resolved" {
+		t.Fatalf("Unexpected summary '%v'", summary)
+	}
+}
+
+func Test__FingerprintCombinedBindings(t *testing.T) {
+	defer klog.Flush()
+
+	config := &AnalysisConfig{
+		Rules: []Rule{
+			{
+				Name:           "Secret Readers And Pod Creators",
+				Severity:       SEVERITY_HIGH,
+				Uuid:           "7e1f3c2a-5b4d-4e6f-8a9b-0c1d2e3f4a5b",
+				Recommendation: `"Review " + subject.name`,
+				AnalysisExpr: `subjects.filter(subject, has(subject.allowedTo) &&
+					subject.allowedTo.exists(rule, rule.resource == 'secrets') &&
+					subject.allowedTo.exists(rule, rule.resource == 'pods' && rule.verb == 'create'))`,
+			},
+		},
+	}
+
+	analyze := func(bindings map[string]string) *AnalysisReportFinding {
+		rules := []rbac.PolicyRule{}
+		for binding, resource := range bindings {
+			verb := "get"
+			if resource == "pods" {
+				verb = "create"
+			}
+
+			rules = append(rules, rbac.PolicyRule{
+				PolicyRule: v1.PolicyRule{Verbs: []string{verb}, APIGroups: []string{""}, Resources: []string{resource}},
+				GrantedBy:  rbac.BindingRef{Kind: "RoleBinding", Namespace: "payments", Name: binding},
+			})
+		}
+
+		policies := []rbac.SubjectPermissions{{
+			Subject: v1.Subject{Kind: v1.ServiceAccountKind, Namespace: "payments", Name: "api"},
+			Rules:   map[string][]rbac.PolicyRule{"payments": rules},
+		}}
+
+		report, err := CreateAnalyzer(config, rbac.NewSubjectPermissionsList(policies)).Analyze()
+		if err != nil {
+			t.Fatalf("Failed to analyze - %v", err)
+		}
+
+		if len(report.Findings) != 1 {
+			t.Fatalf("Expecting 1 finding got %+v", report.Findings)
+		}
+
+		return &report.Findings[0]
+	}
+
+	//No binding matches on its own - the finding is granted by the combination of read-secrets and create-pods
+	f := analyze(map[string]string{"read-secrets": "secrets", "create-pods": "pods", "read-configmaps": "configmaps"})
+	if len(f.GrantedBy) != 2 || f.GrantedBy[0].Name != "create-pods" || f.GrantedBy[1].Name != "read-secrets" {
+		t.Fatalf("Expecting the contributing bindings got %+v", f.GrantedBy)
+	}
+
+	//An unrelated binding does not change the fingerprint
+	other := analyze(map[string]string{"read-secrets": "secrets", "create-pods": "pods", "read-configmaps": "configmaps", "read-services": "services"})
+	if other.Fingerprint != f.Fingerprint {
+		t.Fatalf("Expecting the same fingerprint got %+v and %+v", f, other)
+	}
+}
@@ -0,0 +1,105 @@
+package analysis
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// JUnit XML as rendered by CI test reports - a test case per rule and a failure per finding
+type JUnitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+type JUnitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	TestCases []JUnitTestCase `xml:"testcase"`
+}
+
+type JUnitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Failures  []JUnitFailure `xml:"failure"`
+}
+
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",cdata"`
+}
+
+// NewJUnitTestSuites combines the test suites
+func NewJUnitTestSuites(suites ...JUnitTestSuite) *JUnitTestSuites {
+	result := &JUnitTestSuites{Name: "rbac-tool analysis", Suites: suites}
+	for _, s := range suites {
+		result.Tests += s.Tests
+		result.Failures += s.Failures
+	}
+
+	return result
+}
+
+// NewJUnitTestSuite maps each rule of the config to a test case - the findings of the rule are its failures
+func NewJUnitTestSuite(report *AnalysisReport, config *AnalysisConfig, name string) JUnitTestSuite {
+	suite := JUnitTestSuite{
+		Name:      name,
+		Timestamp: report.CreatedOn,
+		TestCases: []JUnitTestCase{},
+	}
+
+	index := map[string]int{}
+	for _, r := range config.Rules {
+		if _, exist := index[r.Uuid]; exist {
+			continue
+		}
+
+		index[r.Uuid] = len(suite.TestCases)
+		suite.TestCases = append(suite.TestCases, JUnitTestCase{Name: r.Name, ClassName: "rbac-tool.analysis." + r.Uuid})
+	}
+
+	for _, f := range report.Findings {
+		i, exist := index[f.Finding.RuleUuid]
+		if !exist {
+			index[f.Finding.RuleUuid] = len(suite.TestCases)
+			i = len(suite.TestCases)
+			suite.TestCases = append(suite.TestCases, JUnitTestCase{Name: f.Finding.RuleName, ClassName: "rbac-tool.analysis." + f.Finding.RuleUuid})
+		}
+
+		subject := f.Subject.Name
+		if f.Subject.Namespace != "" {
+			subject = f.Subject.Namespace + "/" + f.Subject.Name
+		}
+
+		text := []string{f.Finding.Message, f.Finding.Recommendation}
+		for _, b := range f.GrantedBy {
+			text = append(text, "Granted by "+b.String())
+		}
+
+		suite.TestCases[i].Failures = append(suite.TestCases[i].Failures, JUnitFailure{
+			Message: fmt.Sprintf("%v %v", f.Subject.Kind, subject),
+			Type:    strings.ToUpper(f.Finding.Severity),
+			Text:    strings.Join(text, "\n"),
+		})
+	}
+
+	suite.Tests = len(suite.TestCases)
+	for _, tc := range suite.TestCases {
+		if len(tc.Failures) > 0 {
+			suite.Failures++
+		}
+
+		sort.SliceStable(tc.Failures, func(i, j int) bool {
+			return tc.Failures[i].Message < tc.Failures[j].Message
+		})
+	}
+
+	return suite
+}
@@ -0,0 +1,42 @@
+package analysis
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/rbac/v1"
+)
+
+func Test__NewJUnitTestSuite(t *testing.T) {
+	config := DefaultAnalysisConfig()
+	secretReaders := config.Rules[0]
+
+	finding := func(subject string, severity string) AnalysisReportFinding {
+		return AnalysisReportFinding{
+			Subject: &v1.Subject{Kind: v1.UserKind, Name: subject},
+			Finding: AnalysisFinding{RuleName: secretReaders.Name, RuleUuid: secretReaders.Uuid, Severity: severity},
+		}
+	}
+
+	report := &AnalysisReport{Findings: []AnalysisReportFinding{finding("bob", SEVERITY_HIGH), finding("alice", SEVERITY_MED)}}
+
+	suites := NewJUnitTestSuites(NewJUnitTestSuite(report, config, "production"))
+	if suites.Tests != len(config.Rules) || suites.Failures != 1 {
+		t.Fatalf("Expecting a test case per rule and a failed test case got tests=%v failures=%v", suites.Tests, suites.Failures)
+	}
+
+	failures := suites.Suites[0].TestCases[0].Failures
+	if len(failures) != 2 || failures[0].Message != "User alice" || failures[1].Type != SEVERITY_HIGH {
+		t.Fatalf("Expecting a failure per finding got %+v", failures)
+	}
+
+	data, err := xml.Marshal(suites)
+	if err != nil || !strings.Contains(string(data), `<testsuite name="production"`) {
+		t.Fatalf("Unexpected JUnit XML %v (%v)", string(data), err)
+	}
+
+	if CountAtSeverity(report.Findings, SEVERITY_HIGH) != 1 || CountAtSeverity(report.Findings, "medium") != 2 || CountAtSeverity(report.Findings, SEVERITY_CRIT) != 0 {
+		t.Fatalf("Unexpected counts of findings at severity")
+	}
+}
@@ -1,6 +1,12 @@
 package analysis
 
-import v1 "k8s.io/api/rbac/v1"
+import (
+	"fmt"
+
+	v1 "k8s.io/api/rbac/v1"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
 
 type AnalysisReport struct {
 	//The Analysis Config Info
@@ -14,6 +20,9 @@ type AnalysisReport struct {
 	Findings []AnalysisReportFinding
 
 	ExclusionsInfo []ExclusionInfo
+
+	//The comparison with the baseline report - set when the findings of a baseline were suppressed
+	Baseline *BaselineInfo `json:",omitempty"`
 }
 
 type AnalysisStats struct {
@@ -27,6 +36,20 @@ type AnalysisReportFinding struct {
 	Subject *v1.Subject
 
 	Finding AnalysisFinding
+
+	//The workloads that run as the ServiceAccount - set when the analysis is workload-aware
+	Workloads []Workload `json:",omitempty"`
+
+	//The RoleBindings/ClusterRoleBindings that grant the permissions the rule matched
+	GrantedBy []rbac.BindingRef `json:",omitempty"`
+
+	//Identifies the finding across runs - see Fingerprint
+	Fingerprint string `json:",omitempty"`
+}
+
+// Key identifies the finding by the rule and the subject
+func (f *AnalysisReportFinding) Key() string {
+	return fmt.Sprintf("%v|%v/%v/%v", f.Finding.RuleUuid, f.Subject.Kind, f.Subject.Namespace, f.Subject.Name)
 }
 
 type AnalysisFinding struct {
@@ -46,6 +69,9 @@ type AnalysisFinding struct {
 
 	//Documetation & additional reading references
 	References []string
+
+	//The rule severity - set when the finding severity was lowered (a ServiceAccount that is not used by any workload)
+	RuleSeverity string `json:",omitempty"`
 }
 
 type ExclusionInfo struct {
@@ -53,4 +79,14 @@ type ExclusionInfo struct {
 
 	//Exclusion Message
 	Message string
+
+	//The rule the subject was excluded from
+	RuleName string `json:",omitempty"`
+	RuleUuid string `json:",omitempty"`
+
+	//The comment of the exclusion
+	Comment string `json:",omitempty"`
+
+	//The RoleBindings/ClusterRoleBindings that grant the permissions the rule matched
+	GrantedBy []rbac.BindingRef `json:",omitempty"`
 }
@@ -0,0 +1,327 @@
+package analysis
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+// SARIF 2.1.0 - https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+const (
+	SarifVersion = "2.1.0"
+	SarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	//The base of the artifact URIs relative to the working directory (the repository checkout)
+	SarifSrcRoot = "%SRCROOT%"
+)
+
+type SarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SarifRun `json:"runs"`
+}
+
+type SarifRun struct {
+	Tool              SarifTool               `json:"tool"`
+	AutomationDetails *SarifAutomationDetails `json:"automationDetails,omitempty"`
+	Results           []SarifResult           `json:"results"`
+}
+
+type SarifTool struct {
+	Driver SarifDriver `json:"driver"`
+}
+
+type SarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationUri string      `json:"informationUri"`
+	Rules          []SarifRule `json:"rules"`
+}
+
+type SarifAutomationDetails struct {
+	ID string `json:"id"`
+}
+
+type SarifMessage struct {
+	Text string `json:"text"`
+}
+
+type SarifRule struct {
+	ID                   string                 `json:"id"`
+	Name                 string                 `json:"name"`
+	ShortDescription     SarifMessage           `json:"shortDescription"`
+	FullDescription      SarifMessage           `json:"fullDescription"`
+	HelpUri              string                 `json:"helpUri,omitempty"`
+	DefaultConfiguration SarifRuleConfiguration `json:"defaultConfiguration"`
+	Properties           SarifRuleProperties    `json:"properties"`
+}
+
+type SarifRuleConfiguration struct {
+	Level string `json:"level"`
+}
+
+type SarifRuleProperties struct {
+	Severity         string   `json:"severity"`
+	SecuritySeverity string   `json:"security-severity"`
+	References       []string `json:"references,omitempty"`
+	Tags             []string `json:"tags"`
+}
+
+type SarifResult struct {
+	RuleID              string             `json:"ruleId"`
+	RuleIndex           int                `json:"ruleIndex"`
+	Level               string             `json:"level"`
+	Message             SarifMessage       `json:"message"`
+	Locations           []SarifLocation    `json:"locations"`
+	PartialFingerprints map[string]string  `json:"partialFingerprints,omitempty"`
+	Suppressions        []SarifSuppression `json:"suppressions,omitempty"`
+	Properties          map[string]string  `json:"properties,omitempty"`
+}
+
+type SarifLocation struct {
+	PhysicalLocation *SarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []SarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type SarifPhysicalLocation struct {
+	ArtifactLocation SarifArtifactLocation `json:"artifactLocation"`
+	Region           *SarifRegion          `json:"region,omitempty"`
+}
+
+type SarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type SarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type SarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+type SarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+// BindingLocator returns the file and line where the binding is defined - false when unknown (e.g. the bindings were read from a cluster)
+type BindingLocator func(binding rbac.BindingRef) (string, int, bool)
+
+// NewSarifLog creates a SARIF log of the runs
+func NewSarifLog(runs ...SarifRun) *SarifLog {
+	return &SarifLog{
+		Schema:  SarifSchema,
+		Version: SarifVersion,
+		Runs:    runs,
+	}
+}
+
+// NewSarifRun maps the rules of the config to SARIF rules and the findings of the report to results.
+// The exclusions are reported as suppressed results. Results are located at the bindings that granted the access -
+// in the manifest files when the locator finds them. The id distinguishes the runs of several clusters (optional).
+func NewSarifRun(report *AnalysisReport, config *AnalysisConfig, locate BindingLocator, version string, id string) SarifRun {
+	run := SarifRun{
+		Tool: SarifTool{
+			Driver: SarifDriver{
+				Name:           "rbac-tool",
+				Version:        version,
+				InformationUri: "https://github.com/alcideio/rbac-tool",
+				Rules:          []SarifRule{},
+			},
+		},
+		Results: []SarifResult{},
+	}
+
+	if id != "" {
+		run.AutomationDetails = &SarifAutomationDetails{ID: id}
+	}
+
+	ruleIndex := map[string]int{}
+	for _, r := range config.Rules {
+		if _, exist := ruleIndex[r.Uuid]; exist {
+			continue
+		}
+
+		ruleIndex[r.Uuid] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, newSarifRule(r))
+	}
+
+	for _, f := range report.Findings {
+		result := newSarifResult(f.Subject.Kind, f.Subject.Namespace, f.Subject.Name, f.Finding.RuleUuid, f.Finding.Severity, f.GrantedBy, locate)
+		result.RuleIndex = ruleIndex[f.Finding.RuleUuid]
+		result.Message.Text = fmt.Sprintf("%v\n%v", f.Finding.Message, f.Finding.Recommendation)
+		if f.Fingerprint != "" {
+			result.PartialFingerprints = map[string]string{"rbacToolFinding/v1": f.Fingerprint}
+		}
+
+		run.Results = append(run.Results, result)
+	}
+
+	for _, e := range report.ExclusionsInfo {
+		index, exist := ruleIndex[e.RuleUuid]
+		if !exist {
+			continue
+		}
+
+		rule := run.Tool.Driver.Rules[index]
+		result := newSarifResult(e.Subject.Kind, e.Subject.Namespace, e.Subject.Name, e.RuleUuid, rule.Properties.Severity, e.GrantedBy, locate)
+		result.RuleIndex = index
+		result.Message.Text = e.Message
+		result.Suppressions = []SarifSuppression{{Kind: "external", Justification: e.Comment}}
+
+		run.Results = append(run.Results, result)
+	}
+
+	// Findings first, by rule and subject
+	sort.SliceStable(run.Results, func(i, j int) bool {
+		ri, rj := run.Results[i], run.Results[j]
+		if (len(ri.Suppressions) == 0) != (len(rj.Suppressions) == 0) {
+			return len(ri.Suppressions) == 0
+		}
+
+		if ri.RuleIndex != rj.RuleIndex {
+			return ri.RuleIndex < rj.RuleIndex
+		}
+
+		return ri.Properties["subjectKind"]+"/"+ri.Properties["subject"] < rj.Properties["subjectKind"]+"/"+rj.Properties["subject"]
+	})
+
+	return run
+}
+
+func newSarifRule(r Rule) SarifRule {
+	rule := SarifRule{
+		ID:               r.Uuid,
+		Name:             sarifRuleName(r.Name),
+		ShortDescription: SarifMessage{Text: r.Name},
+		FullDescription:  SarifMessage{Text: r.Description},
+		DefaultConfiguration: SarifRuleConfiguration{
+			Level: sarifLevel(r.Severity),
+		},
+		Properties: SarifRuleProperties{
+			Severity:         strings.ToUpper(r.Severity),
+			SecuritySeverity: sarifSecuritySeverity(r.Severity),
+			References:       r.References,
+			Tags:             []string{"security", "rbac"},
+		},
+	}
+
+	if len(r.References) > 0 {
+		rule.HelpUri = r.References[0]
+	}
+
+	return rule
+}
+
+func newSarifResult(kind, namespace, name, ruleUuid, severity string, grantedBy []rbac.BindingRef, locate BindingLocator) SarifResult {
+	subject := name
+	if namespace != "" {
+		subject = namespace + "/" + name
+	}
+
+	result := SarifResult{
+		RuleID:     ruleUuid,
+		Level:      sarifLevel(severity),
+		Locations:  []SarifLocation{},
+		Properties: map[string]string{"subjectKind": kind, "subject": subject},
+	}
+
+	for _, binding := range grantedBy {
+		location := SarifLocation{
+			LogicalLocations: []SarifLogicalLocation{
+				{Name: binding.Name, FullyQualifiedName: binding.String(), Kind: "resource"},
+			},
+		}
+
+		if locate != nil {
+			if file, line, found := locate(binding); found {
+				location.PhysicalLocation = &SarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation(file),
+					Region:           &SarifRegion{StartLine: line},
+				}
+			}
+		}
+
+		result.Locations = append(result.Locations, location)
+	}
+
+	// The subject is the location when the binding is unknown
+	if len(result.Locations) == 0 {
+		result.Locations = append(result.Locations, SarifLocation{
+			LogicalLocations: []SarifLogicalLocation{
+				{Name: name, FullyQualifiedName: kind + "/" + subject, Kind: "resource"},
+			},
+		})
+	}
+
+	return result
+}
+
+// sarifArtifactLocation is the location of the file - relative to the working directory when the file is under it, a file:// URI otherwise
+func sarifArtifactLocation(file string) SarifArtifactLocation {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return SarifArtifactLocation{URI: (&url.URL{Path: filepath.ToSlash(file)}).String()}
+	}
+
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return SarifArtifactLocation{URI: (&url.URL{Path: filepath.ToSlash(rel)}).String(), URIBaseID: SarifSrcRoot}
+		}
+	}
+
+	path := filepath.ToSlash(abs)
+	if !strings.HasPrefix(path, "/") {
+		//Windows drive letters - file:///C:/...
+		path = "/" + path
+	}
+
+	return SarifArtifactLocation{URI: (&url.URL{Scheme: "file", Path: path}).String()}
+}
+
+// sarifRuleName turns the rule name into an identifier, e.g. "Secret Readers" -> "SecretReaders"
+func sarifRuleName(name string) string {
+	words := strings.Fields(name)
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+
+	return strings.Join(words, "")
+}
+
+// sarifLevel maps the severity to the SARIF level
+func sarifLevel(severity string) string {
+	switch strings.ToUpper(severity) {
+	case SEVERITY_CRIT, SEVERITY_HIGH:
+		return "error"
+	case SEVERITY_MED:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// sarifSecuritySeverity maps the severity to the score code scanning dashboards rank by
+func sarifSecuritySeverity(severity string) string {
+	switch strings.ToUpper(severity) {
+	case SEVERITY_CRIT:
+		return "9.5"
+	case SEVERITY_HIGH:
+		return "8.0"
+	case SEVERITY_MED:
+		return "5.5"
+	case SEVERITY_LOW:
+		return "3.0"
+	default:
+		return "2.0"
+	}
+}
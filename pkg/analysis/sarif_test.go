@@ -0,0 +1,107 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/rbac/v1"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+func Test__NewSarifRun(t *testing.T) {
+	defer klog.Flush()
+
+	secretReader := func(namespace, name string, binding rbac.BindingRef) rbac.SubjectPermissions {
+		return rbac.SubjectPermissions{
+			Subject: v1.Subject{Kind: v1.ServiceAccountKind, Namespace: namespace, Name: name},
+			Rules: map[string][]rbac.PolicyRule{
+				namespace: {
+					{
+						PolicyRule: v1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"secrets"}},
+						GrantedBy:  binding,
+					},
+					{
+						PolicyRule: v1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"configmaps"}},
+						GrantedBy:  rbac.BindingRef{Kind: "RoleBinding", Namespace: namespace, Name: "read-configmaps"},
+					},
+				},
+			},
+		}
+	}
+
+	granting := rbac.BindingRef{Kind: "RoleBinding", Namespace: "payments", Name: "read-secrets"}
+	policies := []rbac.SubjectPermissions{
+		secretReader("payments", "api", granting),
+		//kube-system is excluded by the default analysis config
+		secretReader("kube-system", "operator", rbac.BindingRef{Kind: "RoleBinding", Namespace: "kube-system", Name: "read-secrets"}),
+	}
+
+	config := DefaultAnalysisConfig()
+	report, err := CreateAnalyzer(config, rbac.NewSubjectPermissionsList(policies)).Analyze()
+	if err != nil {
+		t.Fatalf("Failed to analyze - %v", err)
+	}
+
+	locate := func(binding rbac.BindingRef) (string, int, bool) {
+		if binding == granting {
+			return "manifests/payments.yaml", 12, true
+		}
+		return "", 0, false
+	}
+
+	run := NewSarifRun(report, config, locate, "v1.0.0", "")
+	if len(run.Tool.Driver.Rules) != len(config.Rules) {
+		t.Fatalf("Expecting %v rules got %v", len(config.Rules), len(run.Tool.Driver.Rules))
+	}
+
+	if len(run.Results) != 2 {
+		t.Fatalf("Expecting a result and a suppressed result got %+v", run.Results)
+	}
+
+	result := run.Results[0]
+	if result.Level != "error" || len(result.Suppressions) != 0 || run.Tool.Driver.Rules[result.RuleIndex].ID != result.RuleID {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+
+	//Only the binding that granted the secrets access
+	if len(result.Locations) != 1 || result.Locations[0].PhysicalLocation == nil ||
+		result.Locations[0].PhysicalLocation.ArtifactLocation.URI != "manifests/payments.yaml" || result.Locations[0].PhysicalLocation.Region.StartLine != 12 {
+		t.Fatalf("Expecting the result at the binding got %+v", result.Locations)
+	}
+
+	suppressed := run.Results[1]
+	if len(suppressed.Suppressions) != 1 || suppressed.Suppressions[0].Justification != "Exclude kube-system from analysis" ||
+		suppressed.Properties["subject"] != "kube-system/operator" || suppressed.Locations[0].PhysicalLocation != nil {
+		t.Fatalf("Unexpected suppressed result %+v", suppressed)
+	}
+}
+
+func Test__SarifArtifactLocation(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get the working directory - %v", err)
+	}
+
+	outside, err := filepath.Abs(filepath.Join(wd, "..", "rbac bindings", "payments.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to resolve the path - %v", err)
+	}
+
+	for _, tc := range []struct {
+		file     string
+		expected SarifArtifactLocation
+	}{
+		{"manifests/payments.yaml", SarifArtifactLocation{URI: "manifests/payments.yaml", URIBaseID: SarifSrcRoot}},
+		{"./manifests/../rbac.yaml", SarifArtifactLocation{URI: "rbac.yaml", URIBaseID: SarifSrcRoot}},
+		{filepath.Join(wd, "manifests", "payments.yaml"), SarifArtifactLocation{URI: "manifests/payments.yaml", URIBaseID: SarifSrcRoot}},
+		{outside, SarifArtifactLocation{URI: "file://" + strings.ReplaceAll(filepath.ToSlash(outside), " ", "%20")}},
+	} {
+		if location := sarifArtifactLocation(tc.file); location != tc.expected {
+			t.Fatalf("Expecting %+v for '%v' got %+v", tc.expected, tc.file, location)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RuleSummary counts the findings of a rule (at a given severity) across the analysis reports of several clusters
+type RuleSummary struct {
+	RuleName string
+	RuleUuid string
+	Severity string
+
+	Findings int
+
+	//The clusters with findings of the rule
+	Clusters []string
+}
+
+// Summarize combines the findings of the reports by rule and severity - ordered by severity (most severe first) and number of findings.
+// Reports are keyed by cluster name; nil reports (clusters that failed) are skipped.
+func Summarize(reports map[string]*AnalysisReport) []RuleSummary {
+	summaries := map[string]*RuleSummary{}
+
+	clusters := make([]string, 0, len(reports))
+	for cluster := range reports {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+
+	for _, cluster := range clusters {
+		report := reports[cluster]
+		if report == nil {
+			continue
+		}
+
+		for _, f := range report.Findings {
+			severity := strings.ToUpper(f.Finding.Severity)
+			key := f.Finding.RuleUuid + "|" + severity
+
+			s, exist := summaries[key]
+			if !exist {
+				s = &RuleSummary{RuleName: f.Finding.RuleName, RuleUuid: f.Finding.RuleUuid, Severity: severity, Clusters: []string{}}
+				summaries[key] = s
+			}
+
+			s.Findings++
+			if len(s.Clusters) == 0 || s.Clusters[len(s.Clusters)-1] != cluster {
+				s.Clusters = append(s.Clusters, cluster)
+			}
+		}
+	}
+
+	result := make([]RuleSummary, 0, len(summaries))
+	for _, s := range summaries {
+		result = append(result, *s)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if SeverityRank(result[i].Severity) != SeverityRank(result[j].Severity) {
+			return SeverityRank(result[i].Severity) > SeverityRank(result[j].Severity)
+		}
+
+		if result[i].Findings != result[j].Findings {
+			return result[i].Findings > result[j].Findings
+		}
+
+		return result[i].RuleName < result[j].RuleName
+	})
+
+	return result
+}
+
+// IsSeverity returns whether the severity is one of the analysis severities
+func IsSeverity(severity string) bool {
+	switch strings.ToUpper(severity) {
+	case SEVERITY_CRIT, SEVERITY_HIGH, SEVERITY_MED, SEVERITY_LOW, SEVERITY_INFO:
+		return true
+	default:
+		return false
+	}
+}
+
+// CountAtSeverity counts the findings at or above the severity
+func CountAtSeverity(findings []AnalysisReportFinding, severity string) int {
+	count := 0
+	for _, f := range findings {
+		if SeverityRank(f.Finding.Severity) >= SeverityRank(severity) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// StatsSummary summarizes the report - the rules, findings by severity and exclusions
+func StatsSummary(report *AnalysisReport) string {
+	counts := map[string]int{}
+	for _, f := range report.Findings {
+		counts[strings.ToUpper(f.Finding.Severity)]++
+	}
+
+	bySeverity := []string{}
+	for _, severity := range []string{SEVERITY_CRIT, SEVERITY_HIGH, SEVERITY_MED, SEVERITY_LOW, SEVERITY_INFO} {
+		bySeverity = append(bySeverity, fmt.Sprintf("%v: %v", severity, counts[severity]))
+	}
+
+	return fmt.Sprintf("Evaluated %v rules - %v findings (%v) - %v exclusions",
+		report.Stats.RuleCount, len(report.Findings), strings.Join(bySeverity, ", "), report.Stats.ExclusionCount)
+}
+
+// SeverityRank orders the severities - CRITICAL is the highest
+func SeverityRank(severity string) int {
+	switch strings.ToUpper(severity) {
+	case SEVERITY_CRIT:
+		return 4
+	case SEVERITY_HIGH:
+		return 3
+	case SEVERITY_MED:
+		return 2
+	case SEVERITY_LOW:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,42 @@
+package analysis
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/rbac/v1"
+)
+
+func Test__Summarize(t *testing.T) {
+	finding := func(rule string, severity string, subject string) AnalysisReportFinding {
+		return AnalysisReportFinding{
+			Subject: &v1.Subject{Kind: v1.UserKind, Name: subject},
+			Finding: AnalysisFinding{RuleName: rule, RuleUuid: rule, Severity: severity},
+		}
+	}
+
+	reports := map[string]*AnalysisReport{
+		"staging": {Findings: []AnalysisReportFinding{
+			finding("Secret Readers", SEVERITY_HIGH, "alice"),
+			finding("Secret Readers", SEVERITY_HIGH, "bob"),
+			finding("Impersonators", SEVERITY_CRIT, "alice"),
+		}},
+		"production": {Findings: []AnalysisReportFinding{
+			finding("Secret Readers", SEVERITY_HIGH, "carol"),
+		}},
+		//Failed cluster
+		"dev": nil,
+	}
+
+	summary := Summarize(reports)
+	if len(summary) != 2 {
+		t.Fatalf("Expecting 2 rules got %+v", summary)
+	}
+
+	if summary[0].RuleName != "Impersonators" || summary[0].Findings != 1 || len(summary[0].Clusters) != 1 {
+		t.Fatalf("Expecting the critical rule first got %+v", summary[0])
+	}
+
+	if summary[1].Findings != 3 || len(summary[1].Clusters) != 2 || summary[1].Clusters[0] != "production" || summary[1].Clusters[1] != "staging" {
+		t.Fatalf("Unexpected summary %+v", summary[1])
+	}
+}
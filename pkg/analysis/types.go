@@ -12,6 +12,7 @@ const (
 	SEVERITY_CRIT = "CRITICAL"
 	SEVERITY_HIGH = "HIGH"
 	SEVERITY_MED  = "MEDIUM"
+	SEVERITY_LOW  = "LOW"
 	SEVERITY_INFO = "INFO"
 )
 
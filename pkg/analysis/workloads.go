@@ -0,0 +1,171 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// Workload is a controller (or a standalone Pod) that runs Pods as a ServiceAccount
+type Workload struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	//Number of Pods that are not completed
+	Pods int `json:"pods"`
+
+	//Number of Pods that mount the ServiceAccount token (automountServiceAccountToken enabled)
+	TokenMountedPods int `json:"tokenMountedPods"`
+}
+
+func (w Workload) String() string {
+	return fmt.Sprintf("%v/%v (%v/%v pods mount the token)", w.Kind, w.Name, w.TokenMountedPods, w.Pods)
+}
+
+// WorkloadsKey is the key of the ServiceAccount workloads
+func WorkloadsKey(namespace, serviceAccount string) string {
+	return namespace + "/" + serviceAccount
+}
+
+// NewWorkloads groups the Pods by their owning workload and the ServiceAccount they run as - map[namespace/serviceaccount][]Workload.
+// The owners are resolved through the controllers found in objs (e.g. Pod -> ReplicaSet -> Deployment, Pod -> Job -> CronJob).
+// serviceAccounts (map[namespace]map[name]ServiceAccount) are used to resolve the automountServiceAccountToken default of Pods that don't set it.
+func NewWorkloads(objs []runtime.Object, serviceAccounts map[string]map[string]v1.ServiceAccount) map[string][]Workload {
+	workloads := map[string]map[string]*Workload{}
+
+	pods := []*v1.Pod{}
+	controllers := map[string]*metav1.OwnerReference{}
+	for _, obj := range objs {
+		if pod, ok := obj.(*v1.Pod); ok {
+			pods = append(pods, pod)
+			continue
+		}
+
+		o, ok := obj.(metav1.Object)
+		if !ok {
+			continue
+		}
+
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		if kind == "" {
+			kind = kindOf(obj)
+		}
+
+		controllers[ownerKey(kind, o.GetNamespace(), o.GetName())] = metav1.GetControllerOf(o)
+	}
+
+	for _, pod := range pods {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+
+		sa := pod.Spec.ServiceAccountName
+		if sa == "" {
+			sa = "default"
+		}
+
+		kind, name := podOwner(pod, controllers)
+
+		key := WorkloadsKey(pod.Namespace, sa)
+		if _, exist := workloads[key]; !exist {
+			workloads[key] = map[string]*Workload{}
+		}
+
+		w, exist := workloads[key][kind+"/"+name]
+		if !exist {
+			w = &Workload{Kind: kind, Namespace: pod.Namespace, Name: name}
+			workloads[key][kind+"/"+name] = w
+		}
+
+		w.Pods++
+		if automountServiceAccountToken(pod, serviceAccounts[pod.Namespace][sa]) {
+			w.TokenMountedPods++
+		}
+	}
+
+	res := map[string][]Workload{}
+	for key, owners := range workloads {
+		list := make([]Workload, 0, len(owners))
+		for _, w := range owners {
+			list = append(list, *w)
+		}
+
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Kind != list[j].Kind {
+				return list[i].Kind < list[j].Kind
+			}
+			return list[i].Name < list[j].Name
+		})
+
+		res[key] = list
+	}
+
+	return res
+}
+
+// podOwner returns the workload that controls the Pod - the top most controller found in controllers (map[kind/namespace/name]controller).
+// When the owner is not found (e.g. the ReplicaSets or Jobs were not listed) the Pod controller reference is used as is.
+func podOwner(pod *v1.Pod, controllers map[string]*metav1.OwnerReference) (string, string) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "Pod", pod.Name
+	}
+
+	visited := map[string]bool{}
+	for {
+		key := ownerKey(owner.Kind, pod.Namespace, owner.Name)
+		controller, exist := controllers[key]
+		if !exist || controller == nil || visited[key] {
+			return owner.Kind, owner.Name
+		}
+
+		visited[key] = true
+		owner = controller
+	}
+}
+
+func ownerKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// kindOf returns the kind of typed objects that don't carry it (e.g. objects returned by List)
+func kindOf(obj runtime.Object) string {
+	gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil || len(gvks) == 0 {
+		return ""
+	}
+
+	return gvks[0].Kind
+}
+
+func automountServiceAccountToken(pod *v1.Pod, sa v1.ServiceAccount) bool {
+	if pod.Spec.AutomountServiceAccountToken != nil {
+		return *pod.Spec.AutomountServiceAccountToken
+	}
+
+	if sa.AutomountServiceAccountToken != nil {
+		return *sa.AutomountServiceAccountToken
+	}
+
+	return true
+}
+
+// lowerSeverity returns the next lower severity
+func lowerSeverity(severity string) string {
+	switch strings.ToUpper(severity) {
+	case SEVERITY_CRIT:
+		return SEVERITY_HIGH
+	case SEVERITY_HIGH:
+		return SEVERITY_MED
+	case SEVERITY_MED:
+		return SEVERITY_LOW
+	default:
+		return SEVERITY_INFO
+	}
+}
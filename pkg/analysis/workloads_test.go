@@ -0,0 +1,175 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+)
+
+func Test__WorkloadAnalyzer(t *testing.T) {
+	defer klog.Flush()
+
+	controller := true
+	noToken := false
+
+	objs := []runtime.Object{
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "test",
+				Name:            "web-5d8f9c7b6-abcde",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d8f9c7b6", Controller: &controller}},
+			},
+			Spec: v1.PodSpec{ServiceAccountName: "web"},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "test",
+				Name:            "web-5d8f9c7b6-fghij",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d8f9c7b6", Controller: &controller}},
+			},
+			Spec: v1.PodSpec{ServiceAccountName: "web", AutomountServiceAccountToken: &noToken},
+		},
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "test",
+				Name:            "web-5d8f9c7b6",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web", Controller: &controller}},
+			},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "test",
+				Name:            "backup-28101234-xk2lp",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Job", Name: "backup-28101234", Controller: &controller}},
+			},
+			Spec: v1.PodSpec{ServiceAccountName: "backup"},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "test",
+				Name:            "backup-28101234",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "CronJob", Name: "backup", Controller: &controller}},
+			},
+		},
+		// The ReplicaSet is missing - the Pod is attributed to its controller reference
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "test",
+				Name:            "worker-7c6b5d4f8-mnopq",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "worker-7c6b5d4f8", Controller: &controller}},
+			},
+			Spec: v1.PodSpec{ServiceAccountName: "worker"},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "done"},
+			Spec:       v1.PodSpec{ServiceAccountName: "idle"},
+			Status:     v1.PodStatus{Phase: v1.PodSucceeded},
+		},
+	}
+
+	workloads := NewWorkloads(objs, nil)
+
+	web := workloads[WorkloadsKey("test", "web")]
+	if len(web) != 1 || web[0].Kind != "Deployment" || web[0].Name != "web" || web[0].Pods != 2 || web[0].TokenMountedPods != 1 {
+		t.Fatalf("Unexpected workloads %+v", workloads)
+	}
+
+	backup := workloads[WorkloadsKey("test", "backup")]
+	if len(backup) != 1 || backup[0].Kind != "CronJob" || backup[0].Name != "backup" {
+		t.Fatalf("Expecting the CronJob workload got %+v", backup)
+	}
+
+	worker := workloads[WorkloadsKey("test", "worker")]
+	if len(worker) != 1 || worker[0].Kind != "ReplicaSet" || worker[0].Name != "worker-7c6b5d4f8" {
+		t.Fatalf("Expecting the ReplicaSet workload got %+v", worker)
+	}
+
+	if _, exist := workloads[WorkloadsKey("test", "idle")]; exist {
+		t.Fatalf("Completed pods should not be counted %+v", workloads)
+	}
+
+	config := &AnalysisConfig{
+		Rules: []Rule{
+			{
+				Name:           "Secret Readers",
+				Severity:       SEVERITY_HIGH,
+				Uuid:           "d1a7a7f4-8c3e-4f0c-9a3a-1c2b3d4e5f60",
+				Recommendation: `"Review " + subject.name`,
+				AnalysisExpr:   `subjects.filter(subject, has(subject.allowedTo) && subject.allowedTo.exists(rule, rule.resource == 'secrets'))`,
+			},
+			{
+				Name:           "Mounted Secret Readers",
+				Severity:       SEVERITY_CRIT,
+				Uuid:           "0b9e2c1d-3f4a-4b5c-8d6e-7f8091a2b3c4",
+				Recommendation: `"Review " + subject.name`,
+				AnalysisExpr:   `subjects.filter(subject, subject.workloads.exists(w, w.tokenMountedPods > 0))`,
+			},
+		},
+	}
+
+	secretReader := []rbac.NamespacedPolicyRule{{Namespace: "test", Verb: "get", APIGroup: "core", Resource: "secrets"}}
+
+	analyzer := CreateWorkloadAnalyzer(config, []rbac.SubjectPolicyList{
+		{Subject: rbacv1.Subject{Kind: "ServiceAccount", Name: "web", Namespace: "test"}, AllowedTo: secretReader},
+		{Subject: rbacv1.Subject{Kind: "ServiceAccount", Name: "idle", Namespace: "test"}, AllowedTo: secretReader},
+	}, workloads)
+
+	if analyzer == nil {
+		t.Fatalf("Failed to create analyzer")
+	}
+
+	report, err := analyzer.Analyze()
+	if err != nil {
+		t.Fatalf("Analysis failed - %v", err)
+	}
+
+	if len(report.Findings) != 3 {
+		t.Fatalf("Expecting 3 findings got %+v", report.Findings)
+	}
+
+	for _, f := range report.Findings {
+		switch {
+		case f.Subject.Name == "web" && f.Finding.RuleName == "Secret Readers":
+			if f.Finding.Severity != SEVERITY_HIGH || f.Finding.RuleSeverity != "" || len(f.Workloads) != 1 {
+				t.Fatalf("Unexpected finding %+v", f)
+			}
+		case f.Subject.Name == "web" && f.Finding.RuleName == "Mounted Secret Readers":
+			if f.Finding.Severity != SEVERITY_CRIT {
+				t.Fatalf("Unexpected finding %+v", f)
+			}
+		case f.Subject.Name == "idle":
+			if f.Finding.Severity != SEVERITY_MED || f.Finding.RuleSeverity != SEVERITY_HIGH || len(f.Workloads) != 0 {
+				t.Fatalf("Unexpected finding %+v", f)
+			}
+		default:
+			t.Fatalf("Unexpected finding %+v", f)
+		}
+	}
+}
+
+func Test__LowerSeverity(t *testing.T) {
+	tests := []struct {
+		severity string
+		expected string
+	}{
+		{SEVERITY_CRIT, SEVERITY_HIGH},
+		{SEVERITY_HIGH, SEVERITY_MED},
+		{SEVERITY_MED, SEVERITY_LOW},
+		{SEVERITY_LOW, SEVERITY_INFO},
+		{SEVERITY_INFO, SEVERITY_INFO},
+		{"medium", SEVERITY_LOW},
+	}
+
+	for _, tc := range tests {
+		if lowered := lowerSeverity(tc.severity); lowered != tc.expected {
+			t.Fatalf("Expecting %v lowered to %v got %v", tc.severity, tc.expected, lowered)
+		}
+	}
+}
@@ -94,6 +94,8 @@ func openStreams(sources []string) ([]io.ReadCloser, []error) {
 			} else {
 				streams = append(streams, f)
 			}
+
+			continue
 		}
 
 		err = filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
@@ -0,0 +1,181 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/apis/audit"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+const (
+	decisionAnnotation = "authorization.k8s.io/decision"
+	decisionAllow      = "allow"
+
+	//Users of the kubelets - authorized by the Node authorizer
+	nodeUserPrefix = "system:node:"
+)
+
+// DeniedRequest is a kind of request (verb and resource or URL) that would be denied, with the namespaces it was made in
+type DeniedRequest struct {
+	Verb string `json:"verb"`
+
+	APIGroup    string `json:"apiGroup,omitempty"`
+	Resource    string `json:"resource,omitempty"`
+	Subresource string `json:"subresource,omitempty"`
+
+	//The non-resource URL
+	Path string `json:"path,omitempty"`
+
+	//The namespaces the requests were made in - empty for cluster scoped requests
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	//Number of audited requests
+	Count int `json:"count"`
+}
+
+// SubjectDenials captures the requests of a subject that would be denied
+type SubjectDenials struct {
+	rbacv1.Subject
+
+	Denied []DeniedRequest `json:"denied"`
+}
+
+// Replayer evaluates audited requests against a (proposed) set of RBAC resources
+type Replayer struct {
+	authz *rbac.Authorizer
+
+	//Requests of these users and group members are not replayed - they are authorized by other authorizers than RBAC
+	//(e.g. system:masters, the Node authorizer or a webhook)
+	SkipUsers  sets.String
+	SkipGroups sets.String
+
+	//Allowed requests replayed
+	Replayed int
+
+	//map[subject]map[verb|group|resource|subresource|path]
+	denied     map[string]map[string]*DeniedRequest
+	namespaces map[string]map[string]sets.String
+	subjects   map[string]rbacv1.Subject
+}
+
+func NewReplayer(perms *rbac.Permissions) *Replayer {
+	return &Replayer{
+		authz:      rbac.NewAuthorizer(perms),
+		SkipUsers:  sets.NewString(),
+		SkipGroups: sets.NewString(user.SystemPrivilegedGroup, user.NodesGroup),
+		denied:     map[string]map[string]*DeniedRequest{},
+		namespaces: map[string]map[string]sets.String{},
+		subjects:   map[string]rbacv1.Subject{},
+	}
+}
+
+// Replay evaluates the request of an event that was allowed.
+// Events that were denied, and requests of users that are not authorized by RBAC (system:masters members, nodes and SkipUsers/SkipGroups), are skipped.
+func (r *Replayer) Replay(event *audit.Event) {
+	if !WasAllowed(event) {
+		return
+	}
+
+	attrs := EventToAttributes(event)
+	if r.skip(attrs.User) {
+		return
+	}
+
+	r.Replayed++
+
+	if decision, _, _ := r.authz.Authorize(context.Background(), attrs); decision == authorizer.DecisionAllow {
+		return
+	}
+
+	subject := userToSubject(attrs.User)
+	subjectKey := fmt.Sprintf("%v/%v/%v", subject.Kind, subject.Namespace, subject.Name)
+	r.subjects[subjectKey] = subject
+
+	if _, exist := r.denied[subjectKey]; !exist {
+		r.denied[subjectKey] = map[string]*DeniedRequest{}
+		r.namespaces[subjectKey] = map[string]sets.String{}
+	}
+
+	d := DeniedRequest{Verb: attrs.Verb}
+	if attrs.ResourceRequest {
+		d.APIGroup = attrs.APIGroup
+		d.Resource = attrs.Resource
+		d.Subresource = attrs.Subresource
+	} else {
+		d.Path = attrs.Path
+	}
+
+	key := fmt.Sprintf("%v|%v|%v|%v|%v", d.Verb, d.APIGroup, d.Resource, d.Subresource, d.Path)
+	existing, exist := r.denied[subjectKey][key]
+	if !exist {
+		existing = &d
+		r.denied[subjectKey][key] = existing
+		r.namespaces[subjectKey][key] = sets.NewString()
+	}
+
+	existing.Count++
+	if attrs.Namespace != "" {
+		r.namespaces[subjectKey][key].Insert(attrs.Namespace)
+	}
+}
+
+// skip reports whether the requests of the user are authorized by other authorizers than RBAC
+func (r *Replayer) skip(u user.Info) bool {
+	if strings.HasPrefix(u.GetName(), nodeUserPrefix) || r.SkipUsers.Has(u.GetName()) {
+		return true
+	}
+
+	return r.SkipGroups.HasAny(u.GetGroups()...)
+}
+
+// Report returns the requests that would be denied grouped by subject and verb/resource
+func (r *Replayer) Report() []SubjectDenials {
+	keys := make([]string, 0, len(r.subjects))
+	for key := range r.subjects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	report := make([]SubjectDenials, 0, len(keys))
+	for _, subjectKey := range keys {
+		requestKeys := make([]string, 0, len(r.denied[subjectKey]))
+		for key := range r.denied[subjectKey] {
+			requestKeys = append(requestKeys, key)
+		}
+		sort.Strings(requestKeys)
+
+		s := SubjectDenials{Subject: r.subjects[subjectKey], Denied: []DeniedRequest{}}
+		for _, key := range requestKeys {
+			d := *r.denied[subjectKey][key]
+			d.Namespaces = r.namespaces[subjectKey][key].List()
+			s.Denied = append(s.Denied, d)
+		}
+
+		report = append(report, s)
+	}
+
+	return report
+}
+
+// WasAllowed reports whether the request of the event was authorized - based on the authorization decision annotation,
+// or when the event has no such annotation, on the response status
+func WasAllowed(event *audit.Event) bool {
+	if decision, exist := event.Annotations[decisionAnnotation]; exist {
+		return decision == decisionAllow
+	}
+
+	if event.ResponseStatus != nil {
+		return event.ResponseStatus.Code != http.StatusForbidden && event.ResponseStatus.Code != http.StatusUnauthorized
+	}
+
+	return true
+}
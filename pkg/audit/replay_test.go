@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"testing"
+
+	authnv1 "k8s.io/api/authentication/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/apis/audit"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+func Test__Replayer(t *testing.T) {
+	defer klog.Flush()
+
+	objs := []runtime.Object{
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "payments"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "payments"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "api"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "app"},
+		},
+	}
+
+	perms, err := rbac.NewPermissionsFromResourceList(objs)
+	if err != nil {
+		t.Fatalf("Failed to create permissions - %v", err)
+	}
+
+	event := func(username string, groups []string, verb string, namespace string, resource string, decision string) *audit.Event {
+		return &audit.Event{
+			Stage:       audit.StageResponseComplete,
+			Verb:        verb,
+			User:        authnv1.UserInfo{Username: username, Groups: groups},
+			ObjectRef:   &audit.ObjectReference{Namespace: namespace, Resource: resource, APIVersion: "v1"},
+			Annotations: map[string]string{decisionAnnotation: decision},
+		}
+	}
+
+	api := "system:serviceaccount:payments:api"
+
+	replayer := NewReplayer(perms)
+	replayer.SkipUsers.Insert("deployer")
+	replayer.SkipGroups.Insert("ci-bots")
+	for _, e := range []*audit.Event{
+		event(api, nil, "get", "payments", "configmaps", "allow"),
+		event(api, nil, "list", "payments", "configmaps", "allow"),
+		event(api, nil, "list", "payments", "configmaps", "allow"),
+		event(api, nil, "list", "default", "configmaps", "allow"),
+		//Denied in the log - not replayed
+		event(api, nil, "delete", "payments", "configmaps", "forbid"),
+		//Not subject to RBAC
+		event("admin", []string{user.SystemPrivilegedGroup}, "delete", "payments", "secrets", "allow"),
+		//Authorized by the Node authorizer
+		event("system:node:worker-1", []string{user.NodesGroup}, "get", "payments", "secrets", "allow"),
+		event("kubelet", []string{user.NodesGroup}, "get", "payments", "configmaps", "allow"),
+		//Authorized by a webhook
+		event("ci", []string{"ci-bots"}, "get", "payments", "secrets", "allow"),
+		event("deployer", nil, "get", "payments", "secrets", "allow"),
+	} {
+		replayer.Replay(e)
+	}
+
+	if replayer.Replayed != 4 {
+		t.Fatalf("Expecting 4 replayed requests got %v", replayer.Replayed)
+	}
+
+	report := replayer.Report()
+	if len(report) != 1 || report[0].Name != "api" || report[0].Namespace != "payments" || len(report[0].Denied) != 1 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+
+	denied := report[0].Denied[0]
+	if denied.Verb != "list" || denied.Resource != "configmaps" || denied.Count != 3 ||
+		len(denied.Namespaces) != 2 || denied.Namespaces[0] != "default" || denied.Namespaces[1] != "payments" {
+		t.Fatalf("Unexpected denied request %+v", denied)
+	}
+}
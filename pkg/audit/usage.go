@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"fmt"
+	"sort"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiserver/pkg/apis/audit"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	rbacauthorizer "k8s.io/kubernetes/plugin/pkg/auth/authorizer/rbac"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+// RuleUsage is a rule granted to a subject and the number of audited requests it allowed
+type RuleUsage struct {
+	//The namespace of the RoleBinding - empty for cluster-wide grants (ClusterRoleBindings)
+	Namespace string `json:"namespace,omitempty"`
+
+	GrantedBy rbac.BindingRef `json:"grantedBy"`
+	RoleRef   rbacv1.RoleRef  `json:"roleRef"`
+
+	Rule rbacv1.PolicyRule `json:"rule"`
+
+	Count int `json:"count"`
+}
+
+// SubjectUsage captures the granted rules of a subject split by whether they were exercised
+type SubjectUsage struct {
+	rbacv1.Subject
+
+	Used   []RuleUsage `json:"used,omitempty"`
+	Unused []RuleUsage `json:"unused,omitempty"`
+}
+
+// UsageTracker credits the granted rules with the audited requests they allowed
+type UsageTracker struct {
+	authz *rbac.Authorizer
+
+	//map[subject]map[grant]*RuleUsage
+	usage map[string]map[string]*RuleUsage
+
+	subjects map[string]rbacv1.Subject
+}
+
+func NewUsageTracker(perms *rbac.Permissions) *UsageTracker {
+	t := &UsageTracker{
+		authz:    rbac.NewAuthorizer(perms),
+		usage:    map[string]map[string]*RuleUsage{},
+		subjects: map[string]rbacv1.Subject{},
+	}
+
+	for _, p := range rbac.NewSubjectPermissions(perms) {
+		subjectKey := usageSubjectKey(p.Subject)
+		t.subjects[subjectKey] = p.Subject
+		t.usage[subjectKey] = map[string]*RuleUsage{}
+
+		for namespace, rules := range p.Rules {
+			for _, rule := range rules {
+				if len(rule.OriginatedFrom) == 0 {
+					continue
+				}
+
+				u := &RuleUsage{
+					Namespace: namespace,
+					GrantedBy: rule.GrantedBy,
+					RoleRef:   rule.OriginatedFrom[0],
+					Rule:      rule.PolicyRule,
+				}
+
+				t.usage[subjectKey][grantKey(u.GrantedBy, u.RoleRef, &u.Rule)] = u
+			}
+		}
+	}
+
+	return t
+}
+
+// Record credits every rule that allows the request - a request allowed by several bindings credits all of them
+func (t *UsageTracker) Record(attrs authorizer.AttributesRecord) {
+	t.authz.VisitRulesFor(attrs.User, attrs.Namespace, func(source fmt.Stringer, rule *rbacv1.PolicyRule, err error) bool {
+		if rule == nil || !rbacauthorizer.RuleAllows(attrs, rule) {
+			return true
+		}
+
+		d := source.(rbac.BindingDescriber)
+
+		subject := d.Subject()
+		if subject.Kind == rbacv1.ServiceAccountKind && subject.Namespace == "" {
+			subject.Namespace = d.Binding().Namespace
+		}
+
+		if u, exist := t.usage[usageSubjectKey(subject)][grantKey(d.Binding(), d.RoleRef(), rule)]; exist {
+			u.Count++
+		}
+
+		return true
+	})
+}
+
+// Report returns the used and unused rules of every subject, sorted by subject
+func (t *UsageTracker) Report() []SubjectUsage {
+	keys := make([]string, 0, len(t.subjects))
+	for key := range t.subjects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	report := make([]SubjectUsage, 0, len(keys))
+	for _, key := range keys {
+		s := SubjectUsage{Subject: t.subjects[key]}
+
+		for _, u := range t.usage[key] {
+			if u.Count > 0 {
+				s.Used = append(s.Used, *u)
+			} else {
+				s.Unused = append(s.Unused, *u)
+			}
+		}
+
+		sortRuleUsage(s.Used)
+		sortRuleUsage(s.Unused)
+
+		report = append(report, s)
+	}
+
+	return report
+}
+
+// IsCompleted reports whether the event is the final stage of a request - requests are logged once per stage
+func IsCompleted(event *audit.Event) bool {
+	return event.Stage != audit.StageRequestReceived && event.Stage != audit.StageResponseStarted
+}
+
+func usageSubjectKey(subject rbacv1.Subject) string {
+	return fmt.Sprintf("%v/%v/%v", subject.Kind, subject.Namespace, subject.Name)
+}
+
+func grantKey(binding rbac.BindingRef, roleRef rbacv1.RoleRef, rule *rbacv1.PolicyRule) string {
+	return fmt.Sprintf("%v|%v/%v|%v", binding.String(), roleRef.Kind, roleRef.Name, rule.String())
+}
+
+func sortRuleUsage(usage []RuleUsage) {
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Count != usage[j].Count {
+			return usage[i].Count > usage[j].Count
+		}
+
+		ki := grantKey(usage[i].GrantedBy, usage[i].RoleRef, &usage[i].Rule)
+		kj := grantKey(usage[j].GrantedBy, usage[j].RoleRef, &usage[j].Rule)
+		return ki < kj
+	})
+}
@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/apis/audit"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+func Test__UsageTracker(t *testing.T) {
+	defer klog.Flush()
+
+	objs := []runtime.Object{
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "payments"},
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get", "list"}},
+				{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}},
+			},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "payments"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "api"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "app"},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-reader"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"get"}}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "ops-node-reader"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "ops"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "node-reader"},
+		},
+	}
+
+	perms, err := rbac.NewPermissionsFromResourceList(objs)
+	if err != nil {
+		t.Fatalf("Failed to create permissions - %v", err)
+	}
+
+	tracker := NewUsageTracker(perms)
+
+	api := &user.DefaultInfo{Name: "system:serviceaccount:payments:api"}
+	alice := &user.DefaultInfo{Name: "alice", Groups: []string{"ops"}}
+
+	requests := []authorizer.AttributesRecord{
+		{User: api, Verb: "list", Namespace: "payments", Resource: "configmaps", ResourceRequest: true},
+		{User: api, Verb: "get", Namespace: "payments", Resource: "configmaps", Name: "settings", ResourceRequest: true},
+		//Denied - not credited
+		{User: api, Verb: "delete", Namespace: "payments", Resource: "configmaps", ResourceRequest: true},
+		{User: alice, Verb: "get", Resource: "nodes", Name: "node-1", ResourceRequest: true},
+	}
+
+	for _, attrs := range requests {
+		tracker.Record(attrs)
+	}
+
+	report := tracker.Report()
+	if len(report) != 2 {
+		t.Fatalf("Expecting 2 subjects got %+v", report)
+	}
+
+	ops, sa := report[0], report[1]
+
+	if ops.Kind != rbacv1.GroupKind || len(ops.Used) != 1 || ops.Used[0].Count != 1 || len(ops.Unused) != 0 {
+		t.Fatalf("Unexpected group usage %+v", ops)
+	}
+
+	if sa.Kind != rbacv1.ServiceAccountKind || sa.Namespace != "payments" ||
+		len(sa.Used) != 1 || sa.Used[0].Count != 2 || sa.Used[0].Rule.Resources[0] != "configmaps" ||
+		len(sa.Unused) != 1 || sa.Unused[0].Rule.Resources[0] != "secrets" ||
+		sa.Unused[0].GrantedBy.Name != "app" || sa.Unused[0].RoleRef.Name != "app" {
+		t.Fatalf("Unexpected ServiceAccount usage %+v", sa)
+	}
+}
+
+func Test__UsageTrackerAuditFile(t *testing.T) {
+	defer klog.Flush()
+
+	objs := []runtime.Object{
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "payments"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get", "list"}}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "payments"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "api"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "app"},
+		},
+	}
+
+	perms, err := rbac.NewPermissionsFromResourceList(objs)
+	if err != nil {
+		t.Fatalf("Failed to create permissions - %v", err)
+	}
+
+	event := `{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"%v","stage":"ResponseComplete",` +
+		`"requestURI":"/api/v1/namespaces/payments/configmaps","verb":"list","user":{"username":"system:serviceaccount:payments:api"},` +
+		`"objectRef":{"resource":"configmaps","namespace":"payments","apiVersion":"v1"},"responseStatus":{"code":200}}`
+
+	fname := filepath.Join(t.TempDir(), "audit.log")
+	if err := os.WriteFile(fname, []byte(fmt.Sprintf(event, 1)+"\n"+fmt.Sprintf(event, 2)+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write audit events - %v", err)
+	}
+
+	results, err := ReadAuditEvents([]string{fname}, IsCompleted)
+	if err != nil {
+		t.Fatalf("Failed to read audit events - %v", err)
+	}
+
+	tracker := NewUsageTracker(perms)
+	for result := range results {
+		if result.Err != nil {
+			t.Fatalf("Failed to read audit event - %v", result.Err)
+		}
+		tracker.Record(EventToAttributes(result.Obj.(*audit.Event)))
+	}
+
+	//Each event of the file is counted once
+	report := tracker.Report()
+	if len(report) != 1 || len(report[0].Used) != 1 || report[0].Used[0].Count != 2 {
+		t.Fatalf("Expecting 2 uses of the configmaps rule got %+v", report)
+	}
+}
@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	rbaclisters "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+// Cache keeps the RBAC Permissions model up to date with shared informers on
+// ServiceAccounts, Roles, ClusterRoles, RoleBindings and ClusterRoleBindings
+type Cache struct {
+	factory informers.SharedInformerFactory
+
+	serviceAccounts     corelisters.ServiceAccountLister
+	roles               rbaclisters.RoleLister
+	clusterRoles        rbaclisters.ClusterRoleLister
+	roleBindings        rbaclisters.RoleBindingLister
+	clusterRoleBindings rbaclisters.ClusterRoleBindingLister
+
+	//Signaled on every informer event
+	changes chan struct{}
+
+	//Incremented on every informer event
+	generation atomic.Uint64
+
+	//Changes less than the debounce period apart are coalesced into a single update
+	Debounce time.Duration
+
+	//The longest an update waits for the changes to quiet down - measured from the first change (0 waits for a quiet period)
+	MaxWait time.Duration
+
+	mu    sync.RWMutex
+	perms *rbac.Permissions
+}
+
+func NewCache(client kubernetes.Interface, resync time.Duration) *Cache {
+	factory := informers.NewSharedInformerFactory(client, resync)
+
+	c := &Cache{
+		factory:  factory,
+		changes:  make(chan struct{}, 1),
+		Debounce: time.Second,
+		MaxWait:  10 * time.Second,
+	}
+
+	core := factory.Core().V1()
+	rbacv1 := factory.Rbac().V1()
+
+	c.serviceAccounts = core.ServiceAccounts().Lister()
+	c.roles = rbacv1.Roles().Lister()
+	c.clusterRoles = rbacv1.ClusterRoles().Lister()
+	c.roleBindings = rbacv1.RoleBindings().Lister()
+	c.clusterRoleBindings = rbacv1.ClusterRoleBindings().Lister()
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { c.notify() },
+		DeleteFunc: func(obj interface{}) { c.notify() },
+	}
+
+	for _, informer := range []cache.SharedIndexInformer{
+		core.ServiceAccounts().Informer(),
+		rbacv1.Roles().Informer(),
+		rbacv1.ClusterRoles().Informer(),
+		rbacv1.RoleBindings().Informer(),
+		rbacv1.ClusterRoleBindings().Informer(),
+	} {
+		informer.AddEventHandler(handler)
+	}
+
+	return c
+}
+
+func (c *Cache) notify() {
+	c.generation.Add(1)
+
+	select {
+	case c.changes <- struct{}{}:
+	default:
+		//A change is already pending
+	}
+}
+
+// Start the informers and build the Permissions model once they are synced
+func (c *Cache) Start(ctx context.Context) error {
+	c.factory.Start(ctx.Done())
+
+	for informerType, synced := range c.factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return fmt.Errorf("Failed to sync %v informer", informerType)
+		}
+	}
+
+	//The initial listing is captured by the first build
+	select {
+	case <-c.changes:
+	default:
+	}
+
+	perms, err := c.build()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.perms = perms
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Generation changes whenever the cached objects change
+func (c *Cache) Generation() uint64 {
+	return c.generation.Load()
+}
+
+// Permissions returns the current Permissions model - it must not be modified
+func (c *Cache) Permissions() *rbac.Permissions {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.perms
+}
+
+// Run rebuilds the Permissions model on changes until the context is done.
+// onChange is called with the previous and the updated models.
+func (c *Cache) Run(ctx context.Context, onChange func(old *rbac.Permissions, updated *rbac.Permissions)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.changes:
+		}
+
+		if !c.waitForQuiet(ctx) {
+			return
+		}
+
+		perms, err := c.build()
+		if err != nil {
+			klog.Errorf("Failed to update permissions - %v", err)
+			continue
+		}
+
+		c.mu.Lock()
+		old := c.perms
+		c.perms = perms
+		c.mu.Unlock()
+
+		if onChange != nil {
+			onChange(old, perms)
+		}
+	}
+}
+
+// waitForQuiet coalesces bursts of changes (e.g. a 'kubectl apply' of several resources) - it waits for a quiet debounce period,
+// but no longer than MaxWait from the first change (e.g. a controller that keeps reconciling bindings).
+// Returns false when the context is done.
+func (c *Cache) waitForQuiet(ctx context.Context) bool {
+	debounce := time.NewTimer(c.Debounce)
+	defer debounce.Stop()
+
+	var maxWait <-chan time.Time
+	if c.MaxWait > 0 {
+		maxWaitTimer := time.NewTimer(c.MaxWait)
+		defer maxWaitTimer.Stop()
+		maxWait = maxWaitTimer.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-c.changes:
+			if !debounce.Stop() {
+				select {
+				case <-debounce.C:
+				default:
+				}
+			}
+			debounce.Reset(c.Debounce)
+		case <-debounce.C:
+			return true
+		case <-maxWait:
+			return true
+		}
+	}
+}
+
+// Objects returns the cached ServiceAccounts, Roles, ClusterRoles, RoleBindings and ClusterRoleBindings - they must not be modified
+func (c *Cache) Objects() ([]runtime.Object, error) {
+	objs := []runtime.Object{}
+
+	sas, err := c.serviceAccounts.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range sas {
+		objs = append(objs, o)
+	}
+
+	roles, err := c.roles.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range roles {
+		objs = append(objs, o)
+	}
+
+	clusterRoles, err := c.clusterRoles.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range clusterRoles {
+		objs = append(objs, o)
+	}
+
+	bindings, err := c.roleBindings.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range bindings {
+		objs = append(objs, o)
+	}
+
+	clusterBindings, err := c.clusterRoleBindings.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range clusterBindings {
+		objs = append(objs, o)
+	}
+
+	return objs, nil
+}
+
+func (c *Cache) build() (*rbac.Permissions, error) {
+	objs, err := c.Objects()
+	if err != nil {
+		return nil, err
+	}
+
+	klog.V(6).Infof("Building permissions from %v cached resources", len(objs))
+
+	return rbac.NewPermissionsFromResourceList(objs)
+}
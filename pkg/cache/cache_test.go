@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+type update struct {
+	old     *rbac.Permissions
+	updated *rbac.Permissions
+	at      time.Time
+}
+
+func startCache(t *testing.T, ctx context.Context, debounce time.Duration, maxWait time.Duration) (*fake.Clientset, chan update) {
+	client := fake.NewSimpleClientset(&rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "secrets-reader"},
+		Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}}},
+	})
+
+	c := NewCache(client, 0)
+	c.Debounce = debounce
+	c.MaxWait = maxWait
+
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("Failed to start cache - %v", err)
+	}
+
+	updates := make(chan update, 100)
+	go c.Run(ctx, func(old *rbac.Permissions, updated *rbac.Permissions) {
+		updates <- update{old: old, updated: updated, at: time.Now()}
+	})
+
+	return client, updates
+}
+
+func createBinding(t *testing.T, ctx context.Context, client *fake.Clientset, name string) {
+	_, err := client.RbacV1().ClusterRoleBindings().Create(ctx, &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: name}},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "secrets-reader"},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Errorf("Failed to create binding - %v", err)
+	}
+}
+
+func Test__CacheDebounce(t *testing.T) {
+	defer klog.Flush()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, updates := startCache(t, ctx, 300*time.Millisecond, 0)
+
+	// A burst of changes is a single update - with the model before and after the burst
+	for i := 0; i < 3; i++ {
+		createBinding(t, ctx, client, fmt.Sprintf("burst-%v", i))
+	}
+
+	select {
+	case u := <-updates:
+		if len(u.old.RoleBindings[""]) != 0 || len(u.updated.RoleBindings[""]) != 3 {
+			t.Fatalf("Expecting 0 bindings before and 3 after the burst got %v and %v", len(u.old.RoleBindings[""]), len(u.updated.RoleBindings[""]))
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Timed out waiting for an update")
+	}
+
+	select {
+	case u := <-updates:
+		t.Fatalf("Expecting a single update got another with %v bindings", len(u.updated.RoleBindings[""]))
+	case <-time.After(time.Second):
+	}
+
+	// The next update starts from the previous model
+	createBinding(t, ctx, client, "single")
+
+	select {
+	case u := <-updates:
+		if len(u.old.RoleBindings[""]) != 3 || len(u.updated.RoleBindings[""]) != 4 {
+			t.Fatalf("Expecting 3 bindings before and 4 after the change got %v and %v", len(u.old.RoleBindings[""]), len(u.updated.RoleBindings[""]))
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Timed out waiting for an update")
+	}
+}
+
+func Test__CacheMaxWait(t *testing.T) {
+	defer klog.Flush()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, updates := startCache(t, ctx, 500*time.Millisecond, time.Second)
+
+	// A change every 100ms never quiets down for the debounce period
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 40; i++ {
+			createBinding(t, ctx, client, fmt.Sprintf("stream-%v", i))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}()
+
+	select {
+	case u := <-updates:
+		if elapsed := u.at.Sub(start); elapsed > 3*time.Second {
+			t.Fatalf("Expecting an update within the max wait got one after %v", elapsed)
+		}
+
+		if n := len(u.updated.RoleBindings[""]); n == 0 || n == 40 {
+			t.Fatalf("Expecting an update in the middle of the stream got %v bindings", n)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Timed out waiting for an update")
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,164 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/rbac/v1"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+const (
+	//The subject exists only on the right side
+	SubjectAdded = "added"
+	//The subject exists only on the left side
+	SubjectRemoved = "removed"
+	//The subject exists on both sides with different permissions
+	SubjectChanged = "changed"
+)
+
+// SubjectDiff captures the permissions a subject gained or lost
+type SubjectDiff struct {
+	v1.Subject
+
+	Status string `json:"status"`
+
+	//Rules granted only on the right side - with the bindings and roles responsible
+	Added []rbac.NamespacedPolicyRule `json:"added,omitempty"`
+
+	//Rules granted only on the left side - with the bindings and roles responsible
+	Removed []rbac.NamespacedPolicyRule `json:"removed,omitempty"`
+}
+
+// Diff compares two permission sets at the flattened policy rule level.
+// A rule is reported only when the access it represents is not granted at all on the other side -
+// re-granting the same access through a different binding or role is not an effective change.
+func Diff(left []rbac.SubjectPolicyList, right []rbac.SubjectPolicyList) []SubjectDiff {
+	return diffBy(left, right, ruleKey)
+}
+
+// DiffGrants compares two permission sets like Diff, but a rule granted through a different binding is reported as a change -
+// e.g. a subject that moved from one binding to another.
+func DiffGrants(left []rbac.SubjectPolicyList, right []rbac.SubjectPolicyList) []SubjectDiff {
+	return diffBy(left, right, grantKey)
+}
+
+func diffBy(left []rbac.SubjectPolicyList, right []rbac.SubjectPolicyList, key func(r rbac.NamespacedPolicyRule) string) []SubjectDiff {
+	leftSubjects := bySubject(left)
+	rightSubjects := bySubject(right)
+
+	keys := map[string]v1.Subject{}
+	for k, p := range leftSubjects {
+		keys[k] = p.Subject
+	}
+	for k, p := range rightSubjects {
+		keys[k] = p.Subject
+	}
+
+	res := []SubjectDiff{}
+	for k, subject := range keys {
+		l, inLeft := leftSubjects[k]
+		r, inRight := rightSubjects[k]
+
+		d := SubjectDiff{
+			Subject: subject,
+			Status:  SubjectChanged,
+			Added:   missingFrom(r.AllowedTo, l.AllowedTo, key),
+			Removed: missingFrom(l.AllowedTo, r.AllowedTo, key),
+		}
+
+		if len(d.Added) == 0 && len(d.Removed) == 0 {
+			continue
+		}
+
+		switch {
+		case !inLeft:
+			d.Status = SubjectAdded
+		case !inRight:
+			d.Status = SubjectRemoved
+		}
+
+		res = append(res, d)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return subjectKey(res[i].Subject) < subjectKey(res[j].Subject)
+	})
+
+	return res
+}
+
+// Summary returns the number of permissions added and removed
+func Summary(diffs []SubjectDiff) (added int, removed int) {
+	for _, d := range diffs {
+		added += len(d.Added)
+		removed += len(d.Removed)
+	}
+
+	return added, removed
+}
+
+func bySubject(policies []rbac.SubjectPolicyList) map[string]rbac.SubjectPolicyList {
+	subjects := map[string]rbac.SubjectPolicyList{}
+
+	//The same subject may be referenced with and without an API group - merge them
+	for _, p := range policies {
+		k := subjectKey(p.Subject)
+		merged, exist := subjects[k]
+		if !exist {
+			merged = rbac.SubjectPolicyList{Subject: p.Subject}
+		}
+
+		merged.AllowedTo = append(merged.AllowedTo, p.AllowedTo...)
+		subjects[k] = merged
+	}
+
+	return subjects
+}
+
+// missingFrom returns the rules whose access is not granted by any of the other rules - rules are compared by key
+func missingFrom(rules []rbac.NamespacedPolicyRule, other []rbac.NamespacedPolicyRule, key func(r rbac.NamespacedPolicyRule) string) []rbac.NamespacedPolicyRule {
+	granted := map[string]bool{}
+	for _, rule := range other {
+		granted[key(rule)] = true
+	}
+
+	res := []rbac.NamespacedPolicyRule{}
+	for _, rule := range rules {
+		if granted[key(rule)] {
+			continue
+		}
+
+		res = append(res, rule)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if ruleKey(res[i]) != ruleKey(res[j]) {
+			return ruleKey(res[i]) < ruleKey(res[j])
+		}
+		return res[i].GrantedBy.String() < res[j].GrantedBy.String()
+	})
+
+	return res
+}
+
+func subjectKey(s v1.Subject) string {
+	return fmt.Sprintf("%v/%v/%v", s.Kind, s.Namespace, s.Name)
+}
+
+func ruleKey(r rbac.NamespacedPolicyRule) string {
+	return strings.Join([]string{
+		r.Namespace,
+		r.Verb,
+		r.APIGroup,
+		r.Resource,
+		strings.Join(r.ResourceNames, ","),
+		strings.Join(r.NonResourceURLs, ","),
+	}, "|")
+}
+
+func grantKey(r rbac.NamespacedPolicyRule) string {
+	return ruleKey(r) + "|" + r.GrantedBy.String()
+}
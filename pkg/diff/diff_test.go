@@ -0,0 +1,59 @@
+package diff
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+	"github.com/alcideio/rbac-tool/pkg/utils"
+)
+
+func loadPolicies(t *testing.T, files ...string) []rbac.SubjectPolicyList {
+	objs := []runtime.Object{}
+	for _, f := range files {
+		l, err := utils.ReadObjectsFromFile("../../testdata/whocan/" + f)
+		if err != nil {
+			t.Fatalf("Failed to read resources - %v", err)
+		}
+		objs = append(objs, l...)
+	}
+
+	perms, err := rbac.NewPermissionsFromResourceList(objs)
+	if err != nil {
+		t.Fatalf("Failed to create permissions - %v", err)
+	}
+
+	return rbac.NewSubjectPermissionsList(rbac.NewSubjectPermissions(perms))
+}
+
+func Test__Diff(t *testing.T) {
+	defer klog.Flush()
+
+	left := loadPolicies(t, "secret-reader.yaml", "pod-creator.yaml")
+	right := loadPolicies(t, "secret-reader.yaml", "impersonator.yaml")
+
+	if diffs := Diff(left, left); len(diffs) != 0 {
+		t.Fatalf("Expecting no changes, got %+v", diffs)
+	}
+
+	diffs := Diff(left, right)
+	added, removed := Summary(diffs)
+	if added != 3 || removed != 6 {
+		t.Fatalf("Expecting 3 added and 6 removed permissions, got %v added %v removed", added, removed)
+	}
+
+	for _, d := range diffs {
+		switch d.Name {
+		case "test-impersonator-user":
+			if d.Status != SubjectAdded || d.Added[0].GrantedBy.Name != "impersonator" {
+				t.Fatalf("Unexpected diff %+v", d)
+			}
+		case "test-pod-creator-user":
+			if d.Status != SubjectRemoved || d.Removed[0].GrantedBy.Name != "pod-creator" {
+				t.Fatalf("Unexpected diff %+v", d)
+			}
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package escalation
+
+import (
+	"fmt"
+	"sort"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+// clusterAdmin is the graph node every escalation path ends at
+const clusterAdmin = ""
+
+// workloadResources are the resources that run Pods - creating any of them in a namespace runs a Pod as a ServiceAccount of the namespace
+var workloadResources = []schema.GroupResource{
+	{Group: "", Resource: "pods"},
+	{Group: "apps", Resource: "deployments"},
+	{Group: "apps", Resource: "daemonsets"},
+	{Group: "apps", Resource: "statefulsets"},
+	{Group: "apps", Resource: "replicasets"},
+	{Group: "batch", Resource: "jobs"},
+	{Group: "batch", Resource: "cronjobs"},
+	{Group: "", Resource: "replicationcontrollers"},
+}
+
+type edge struct {
+	from string
+	to   string
+	step Step
+}
+
+// Graph connects the subjects that can obtain the privileges of other subjects.
+// A subject that holds cluster-admin, or can grant itself cluster-admin, is connected to the cluster-admin node.
+type Graph struct {
+	authz          *rbac.Authorizer
+	implicitGroups bool
+
+	subjects map[string]rbacv1.Subject
+	keys     []string
+
+	//map[to][]edge
+	reverse map[string][]edge
+}
+
+func NewGraph(perms *rbac.Permissions, implicitGroups bool) *Graph {
+	g := &Graph{
+		authz:          rbac.NewAuthorizer(perms),
+		implicitGroups: implicitGroups,
+		subjects:       map[string]rbacv1.Subject{},
+		keys:           []string{},
+		reverse:        map[string][]edge{},
+	}
+
+	for _, subject := range rbac.Subjects(perms) {
+		key := SubjectKey(subject)
+		g.subjects[key] = subject
+		g.keys = append(g.keys, key)
+	}
+
+	g.build()
+
+	return g
+}
+
+func (g *Graph) build() {
+	serviceAccounts := map[string][]rbacv1.Subject{}
+	principals := []rbacv1.Subject{}
+
+	for _, key := range g.keys {
+		subject := g.subjects[key]
+		if subject.Kind == rbacv1.ServiceAccountKind {
+			serviceAccounts[subject.Namespace] = append(serviceAccounts[subject.Namespace], subject)
+		} else {
+			principals = append(principals, subject)
+		}
+	}
+
+	namespaces := make([]string, 0, len(serviceAccounts))
+	for namespace := range serviceAccounts {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	for _, key := range g.keys {
+		from := g.subjects[key]
+		info := rbac.SubjectUser(from, g.implicitGroups)
+
+		allowed := func(attrs authorizer.AttributesRecord) (rbac.BindingRef, bool) {
+			attrs.User = info
+			attrs.ResourceRequest = true
+
+			grants := g.authz.Grants(attrs)
+			if len(grants) == 0 {
+				return rbac.BindingRef{}, false
+			}
+
+			return grants[0].Binding, true
+		}
+
+		add := func(to *rbacv1.Subject, technique Technique, namespace string, description string, grantedBy rbac.BindingRef) {
+			toKey := clusterAdmin
+			if to != nil {
+				toKey = SubjectKey(*to)
+				if toKey == key {
+					return
+				}
+			}
+
+			g.reverse[toKey] = append(g.reverse[toKey], edge{
+				from: key,
+				to:   toKey,
+				step: Step{
+					From:        from,
+					To:          to,
+					Technique:   technique.Name,
+					Description: description,
+					Namespace:   namespace,
+					GrantedBy:   grantedBy,
+					Cost:        technique.Cost,
+				},
+			})
+		}
+
+		if by, ok := allowed(authorizer.AttributesRecord{Verb: "*", APIGroup: "*", Resource: "*"}); ok {
+			add(nil, HoldsClusterAdmin, "", HoldsClusterAdmin.Description, by)
+			//Nothing left to gain
+			continue
+		}
+
+		if by, ok := allowed(authorizer.AttributesRecord{Verb: "impersonate", Resource: "groups", Name: user.SystemPrivilegedGroup}); ok {
+			add(nil, Impersonate, "", fmt.Sprintf("impersonate the %v group", user.SystemPrivilegedGroup), by)
+		}
+
+		if _, ok := allowed(authorizer.AttributesRecord{Verb: "create", APIGroup: rbacv1.GroupName, Resource: "clusterrolebindings"}); ok {
+			if by, ok := allowed(authorizer.AttributesRecord{Verb: "bind", APIGroup: rbacv1.GroupName, Resource: "clusterroles", Name: "cluster-admin"}); ok {
+				add(nil, BindClusterAdmin, "", "create a ClusterRoleBinding to cluster-admin", by)
+			}
+		}
+
+		if by, ok := allowed(authorizer.AttributesRecord{Verb: "escalate", APIGroup: rbacv1.GroupName, Resource: "clusterroles"}); ok {
+			_, canUpdate := allowed(authorizer.AttributesRecord{Verb: "update", APIGroup: rbacv1.GroupName, Resource: "clusterroles"})
+			_, canPatch := allowed(authorizer.AttributesRecord{Verb: "patch", APIGroup: rbacv1.GroupName, Resource: "clusterroles"})
+			if canUpdate || canPatch {
+				add(nil, EscalateClusterRoles, "", "update a bound ClusterRole with any permission", by)
+			}
+		}
+
+		for i := range principals {
+			to := principals[i]
+
+			resource := "users"
+			if to.Kind == rbacv1.GroupKind {
+				resource = "groups"
+			}
+
+			if by, ok := allowed(authorizer.AttributesRecord{Verb: "impersonate", Resource: resource, Name: to.Name}); ok {
+				add(&to, Impersonate, "", fmt.Sprintf("impersonate %v '%v'", to.Kind, to.Name), by)
+			}
+		}
+
+		for _, namespace := range namespaces {
+			sas := serviceAccounts[namespace]
+
+			for _, gr := range workloadResources {
+				by, ok := allowed(authorizer.AttributesRecord{Verb: "create", Namespace: namespace, APIGroup: gr.Group, Resource: gr.Resource})
+				if !ok {
+					continue
+				}
+
+				for i := range sas {
+					add(&sas[i], CreatePods, namespace, fmt.Sprintf("create %v in namespace %v", gr.String(), namespace), by)
+				}
+				break
+			}
+
+			for _, verb := range []string{"get", "list"} {
+				by, ok := allowed(authorizer.AttributesRecord{Verb: verb, Namespace: namespace, Resource: "secrets"})
+				if !ok {
+					continue
+				}
+
+				for i := range sas {
+					add(&sas[i], ReadSecrets, namespace, fmt.Sprintf("%v secrets in namespace %v", verb, namespace), by)
+				}
+				break
+			}
+
+			for i := range sas {
+				sa := sas[i]
+
+				if by, ok := allowed(authorizer.AttributesRecord{Verb: "create", Namespace: namespace, Resource: "serviceaccounts", Subresource: "token", Name: sa.Name}); ok {
+					add(&sa, CreateToken, namespace, fmt.Sprintf("create serviceaccounts/token '%v' in namespace %v", sa.Name, namespace), by)
+				}
+
+				if by, ok := allowed(authorizer.AttributesRecord{Verb: "impersonate", Namespace: namespace, Resource: "serviceaccounts", Name: sa.Name}); ok {
+					add(&sa, Impersonate, namespace, fmt.Sprintf("impersonate ServiceAccount '%v/%v'", namespace, sa.Name), by)
+				}
+			}
+		}
+	}
+}
+
+// Paths returns the cheapest escalation path of every subject that can become cluster-admin -
+// subjects that already hold cluster-admin are not reported.
+// Paths are sorted by length and cost.
+func (g *Graph) Paths() []Path {
+	type distance struct {
+		cost  int
+		steps int
+	}
+
+	less := func(a, b distance) bool {
+		if a.cost != b.cost {
+			return a.cost < b.cost
+		}
+		return a.steps < b.steps
+	}
+
+	//Cheapest paths to cluster-admin (Dijkstra over the reversed edges)
+	dist := map[string]distance{clusterAdmin: {}}
+	next := map[string]edge{}
+	done := sets.NewString()
+
+	for {
+		current, found := "", false
+		for key, d := range dist {
+			if done.Has(key) {
+				continue
+			}
+			if !found || less(d, dist[current]) || (d == dist[current] && key < current) {
+				current, found = key, true
+			}
+		}
+
+		if !found {
+			break
+		}
+		done.Insert(current)
+
+		for _, e := range g.reverse[current] {
+			if done.Has(e.from) {
+				continue
+			}
+
+			d := distance{cost: dist[current].cost + e.step.Cost, steps: dist[current].steps + 1}
+			if existing, exist := dist[e.from]; !exist || less(d, existing) {
+				dist[e.from] = d
+				next[e.from] = e
+			}
+		}
+	}
+
+	paths := []Path{}
+	for _, key := range g.keys {
+		e, exist := next[key]
+		if !exist || e.step.Technique == HoldsClusterAdmin.Name {
+			continue
+		}
+
+		steps := []Step{}
+		for node := key; node != clusterAdmin; node = next[node].to {
+			steps = append(steps, next[node].step)
+		}
+
+		paths = append(paths, newPath(g.subjects[key], steps))
+	}
+
+	sort.SliceStable(paths, func(i, j int) bool {
+		if paths[i].Length != paths[j].Length {
+			return paths[i].Length < paths[j].Length
+		}
+		return paths[i].Cost < paths[j].Cost
+	})
+
+	klog.V(5).Infof("Found %v escalation paths to cluster-admin", len(paths))
+
+	return paths
+}
@@ -0,0 +1,93 @@
+package escalation
+
+import (
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+func Test__EscalationPaths(t *testing.T) {
+	defer klog.Flush()
+
+	objs := []runtime.Object{
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-admin"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "ops-admin"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "ops", Name: "admin"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "cluster-admin"},
+		},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret-reader", Namespace: "ops"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret-reader", Namespace: "ops"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "apps", Name: "builder"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "secret-reader"},
+		},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "apps"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"create"}}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "apps"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "alice"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "deployer"},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "impersonator"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"groups"}, Verbs: []string{"impersonate"}}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "impersonator"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "bob"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "impersonator"},
+		},
+	}
+
+	perms, err := rbac.NewPermissionsFromResourceList(objs)
+	if err != nil {
+		t.Fatalf("Failed to create permissions - %v", err)
+	}
+
+	paths := NewGraph(perms, true).Paths()
+
+	expected := []struct {
+		subject    string
+		length     int
+		techniques []string
+	}{
+		{"User//bob", 1, []string{Impersonate.Name}},
+		{"ServiceAccount/apps/builder", 1, []string{ReadSecrets.Name, HoldsClusterAdmin.Name}},
+		{"User//alice", 2, []string{CreatePods.Name, ReadSecrets.Name, HoldsClusterAdmin.Name}},
+	}
+
+	if len(paths) != len(expected) {
+		t.Fatalf("Expecting %v paths got %+v", len(expected), paths)
+	}
+
+	for i, e := range expected {
+		p := paths[i]
+		if SubjectKey(p.Subject) != e.subject || p.Length != e.length || len(p.Steps) != len(e.techniques) {
+			t.Fatalf("[%v] Unexpected path %+v", i, p)
+		}
+
+		for j, technique := range e.techniques {
+			if p.Steps[j].Technique != technique {
+				t.Fatalf("[%v] Expecting step %v to be %v got %+v", i, j, technique, p.Steps[j])
+			}
+		}
+	}
+
+	if paths[2].Steps[0].GrantedBy.Name != "deployer" || paths[2].Steps[1].To.Name != "admin" || paths[2].Steps[2].GrantedBy.Name != "ops-admin" {
+		t.Fatalf("Unexpected steps %+v", paths[2].Steps)
+	}
+}
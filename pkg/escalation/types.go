@@ -0,0 +1,104 @@
+package escalation
+
+import (
+	"fmt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+// Technique is a way for a subject to obtain the privileges of another subject (or of cluster-admin)
+type Technique struct {
+	Name        string
+	Description string
+
+	//The effort/uncertainty of the technique - paths are scored by the sum of their steps cost
+	Cost int
+}
+
+var (
+	// HoldsClusterAdmin is the last step of every path - the subject is granted every verb on every resource
+	HoldsClusterAdmin = Technique{Name: "cluster-admin", Description: "holds cluster-admin", Cost: 0}
+
+	// CreatePods - create a Pod (or a workload that creates Pods) that runs as a ServiceAccount of the namespace and read its token
+	CreatePods = Technique{Name: "create-pods", Description: "create a Pod that mounts the ServiceAccount token", Cost: 2}
+
+	// ReadSecrets - read the legacy token Secret of a ServiceAccount (not created by default since Kubernetes 1.24)
+	ReadSecrets = Technique{Name: "read-secrets", Description: "read the ServiceAccount token Secret", Cost: 2}
+
+	// CreateToken - request a token for a ServiceAccount with the TokenRequest API
+	CreateToken = Technique{Name: "create-token", Description: "request a ServiceAccount token", Cost: 1}
+
+	// Impersonate a user, a group or a ServiceAccount
+	Impersonate = Technique{Name: "impersonate", Description: "impersonate the subject", Cost: 1}
+
+	// BindClusterAdmin - create a ClusterRoleBinding to the cluster-admin ClusterRole
+	BindClusterAdmin = Technique{Name: "bind", Description: "bind the cluster-admin ClusterRole", Cost: 1}
+
+	// EscalateClusterRoles - add any permission to a ClusterRole bound to the subject
+	EscalateClusterRoles = Technique{Name: "escalate", Description: "add any permission to a bound ClusterRole", Cost: 1}
+)
+
+// Step is a single hop of an escalation path
+type Step struct {
+	From rbacv1.Subject `json:"from"`
+
+	//The subject obtained by the step - nil when the step grants cluster-admin
+	To *rbacv1.Subject `json:"to,omitempty"`
+
+	Technique string `json:"technique"`
+
+	//Human readable description of the step, e.g. 'create pods in namespace foo'
+	Description string `json:"description"`
+
+	//The namespace the step is performed in - empty for cluster-wide steps
+	Namespace string `json:"namespace,omitempty"`
+
+	//The binding that grants the permission the step relies on
+	GrantedBy rbac.BindingRef `json:"grantedBy"`
+
+	Cost int `json:"cost"`
+}
+
+// Path is the cheapest chain of steps that takes a subject to cluster-admin
+type Path struct {
+	Subject rbacv1.Subject `json:"subject"`
+
+	//Number of escalation steps (the final 'holds cluster-admin' step is not counted)
+	Length int `json:"length"`
+
+	//Sum of the steps cost
+	Cost int `json:"cost"`
+
+	//0-100 - the higher the score the easier the escalation
+	Score int `json:"score"`
+
+	Steps []Step `json:"steps"`
+}
+
+func newPath(subject rbacv1.Subject, steps []Step) Path {
+	p := Path{
+		Subject: subject,
+		Steps:   steps,
+	}
+
+	for _, step := range steps {
+		p.Cost += step.Cost
+		if step.Technique != HoldsClusterAdmin.Name {
+			p.Length++
+		}
+	}
+
+	p.Score = 100
+	if p.Cost > 0 {
+		p.Score = 100 / p.Cost
+	}
+
+	return p
+}
+
+// SubjectKey uniquely identifies a subject in the graph
+func SubjectKey(subject rbacv1.Subject) string {
+	return fmt.Sprintf("%v/%v/%v", subject.Kind, subject.Namespace, subject.Name)
+}
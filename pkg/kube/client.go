@@ -4,9 +4,13 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 
+	appsv1 "k8s.io/api/apps/v1"
 	authn "k8s.io/api/authentication/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	k8sserrs "k8s.io/apimachinery/pkg/api/errors"
@@ -18,7 +22,6 @@ import (
 	clientset "k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	restclient "k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog"
 )
 
@@ -30,23 +33,34 @@ type KubeClient struct {
 	// ServerPreferredResources returns the supported resources with the version preferred by the
 	// server.
 	ServerPreferredResources []*metav1.APIResourceList
-	masterVersion            *version.Info
+
+	// ServerResources returns all the resources served by the server - all versions, including subresources.
+	// Discovered on first use - see AllServerResources
+	ServerResources []*metav1.APIResourceList
+
+	// PageSize is the number of objects requested per list call - 0 lists without pagination
+	PageSize int64
+
+	// Progress is called after each listed page with the number of objects listed so far (optional)
+	Progress func(resource string, listed int)
+
+	masterVersion *version.Info
+
+	serverResourcesOnce sync.Once
 }
 
-func NewClient(context string) (*KubeClient, error) {
-	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-	// if you want to change the loading rules (which files in which order), you can do so here
+// DefaultPageSize is the number of objects requested per list call
+const DefaultPageSize = 500
 
-	configOverrides := &clientcmd.ConfigOverrides{
-		CurrentContext: context,
+func NewClient(context string) (*KubeClient, error) {
+	if len(overrides.AsGroups) > 0 && overrides.As == "" {
+		return nil, fmt.Errorf("--as-group requires --as")
 	}
-	// if you want to change override values or bind them to flags, there are methods to help you
 
 	var config *restclient.Config
 	var err error
 
-	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
-	config, err = kubeConfig.ClientConfig()
+	config, err = ClientConfig(context).ClientConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -76,10 +90,42 @@ func NewClient(context string) (*KubeClient, error) {
 		Client:                   client,
 		ServerPreferredResources: preferedResource,
 		Config:                   config,
+		PageSize:                 DefaultPageSize,
 		masterVersion:            k8sVer,
 	}, nil
 }
 
+// Contexts returns the names of the kubeconfig contexts
+func Contexts() ([]string, error) {
+	config, err := loadingRules().Load()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load kubeconfig - %v", err)
+	}
+
+	contexts := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+
+	return contexts, nil
+}
+
+// CurrentContext returns the name of the kubeconfig current context
+func CurrentContext() (string, error) {
+	config, err := loadingRules().Load()
+	if err != nil {
+		return "", fmt.Errorf("Failed to load kubeconfig - %v", err)
+	}
+
+	return config.CurrentContext, nil
+}
+
+// ServerVersion returns the version of the cluster API server (nil when not known)
+func (kubeClient *KubeClient) ServerVersion() *version.Info {
+	return kubeClient.masterVersion
+}
+
 func (kubeClient *KubeClient) GetWorldPermissions() ([]rbacv1.PolicyRule, error) {
 	errs := []error{}
 	computedPolicyRules := make([]rbacv1.PolicyRule, 0)
@@ -174,64 +220,121 @@ func (kubeClient *KubeClient) GetVerbsForResource(apiGroup string, resource stri
 	return verbs, errors.NewAggregate(errs)
 }
 
-func (kubeClient *KubeClient) ListPods(namespace string) ([]v1.Pod, error) {
-	objs, err := kubeClient.Client.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+// IsNamespaced returns whether the resource is namespace scoped - unknown resources are assumed to be namespaced
+func (kubeClient *KubeClient) IsNamespaced(gr schema.GroupResource) bool {
+	for _, apiResourceList := range kubeClient.ServerPreferredResources {
+		gv, err := schema.ParseGroupVersion(apiResourceList.GroupVersion)
+		if err != nil || gv.Group != gr.Group {
+			continue
+		}
 
-	if err != nil {
-		return nil, err
+		for _, apiResource := range apiResourceList.APIResources {
+			if apiResource.Name == gr.Resource {
+				return apiResource.Namespaced
+			}
+		}
 	}
 
-	return objs.Items, nil
+	return true
 }
 
-func (kubeClient *KubeClient) ListServiceAccounts(namespace string) ([]v1.ServiceAccount, error) {
-	objs, err := kubeClient.Client.CoreV1().ServiceAccounts(namespace).List(context.TODO(), metav1.ListOptions{})
+func (kubeClient *KubeClient) ListPods(namespace string) ([]v1.Pod, error) {
+	return listAll(kubeClient, "Pods", func(opts metav1.ListOptions) ([]v1.Pod, metav1.ListMeta, error) {
+		objs, err := kubeClient.Client.CoreV1().Pods(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, metav1.ListMeta{}, err
+		}
 
-	if err != nil {
-		return nil, err
-	}
+		return objs.Items, objs.ListMeta, nil
+	})
+}
 
-	return objs.Items, nil
+func (kubeClient *KubeClient) ListReplicaSets(namespace string) ([]appsv1.ReplicaSet, error) {
+	return listAll(kubeClient, "ReplicaSets", func(opts metav1.ListOptions) ([]appsv1.ReplicaSet, metav1.ListMeta, error) {
+		objs, err := kubeClient.Client.AppsV1().ReplicaSets(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, metav1.ListMeta{}, err
+		}
+
+		return objs.Items, objs.ListMeta, nil
+	})
 }
 
-func (kubeClient *KubeClient) ListRoles(namespace string) ([]rbacv1.Role, error) {
-	objs, err := kubeClient.Client.RbacV1().Roles(namespace).List(context.TODO(), metav1.ListOptions{})
+func (kubeClient *KubeClient) ListJobs(namespace string) ([]batchv1.Job, error) {
+	return listAll(kubeClient, "Jobs", func(opts metav1.ListOptions) ([]batchv1.Job, metav1.ListMeta, error) {
+		objs, err := kubeClient.Client.BatchV1().Jobs(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, metav1.ListMeta{}, err
+		}
 
-	if err != nil {
-		return nil, err
-	}
+		return objs.Items, objs.ListMeta, nil
+	})
+}
 
-	return objs.Items, nil
+func (kubeClient *KubeClient) ListNamespaces() ([]v1.Namespace, error) {
+	return listAll(kubeClient, "Namespaces", func(opts metav1.ListOptions) ([]v1.Namespace, metav1.ListMeta, error) {
+		objs, err := kubeClient.Client.CoreV1().Namespaces().List(context.TODO(), opts)
+		if err != nil {
+			return nil, metav1.ListMeta{}, err
+		}
+
+		return objs.Items, objs.ListMeta, nil
+	})
 }
 
-func (kubeClient *KubeClient) ListRoleBindings(namespace string) ([]rbacv1.RoleBinding, error) {
-	objs, err := kubeClient.Client.RbacV1().RoleBindings(namespace).List(context.TODO(), metav1.ListOptions{})
+func (kubeClient *KubeClient) ListServiceAccounts(namespace string) ([]v1.ServiceAccount, error) {
+	return listAll(kubeClient, "ServiceAccounts", func(opts metav1.ListOptions) ([]v1.ServiceAccount, metav1.ListMeta, error) {
+		objs, err := kubeClient.Client.CoreV1().ServiceAccounts(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, metav1.ListMeta{}, err
+		}
 
-	if err != nil {
-		return nil, err
-	}
+		return objs.Items, objs.ListMeta, nil
+	})
+}
 
-	return objs.Items, nil
+func (kubeClient *KubeClient) ListRoles(namespace string) ([]rbacv1.Role, error) {
+	return listAll(kubeClient, "Roles", func(opts metav1.ListOptions) ([]rbacv1.Role, metav1.ListMeta, error) {
+		objs, err := kubeClient.Client.RbacV1().Roles(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, metav1.ListMeta{}, err
+		}
+
+		return objs.Items, objs.ListMeta, nil
+	})
 }
 
-func (kubeClient *KubeClient) ListClusterRoles() ([]rbacv1.ClusterRole, error) {
-	objs, err := kubeClient.Client.RbacV1().ClusterRoles().List(context.TODO(), metav1.ListOptions{})
+func (kubeClient *KubeClient) ListRoleBindings(namespace string) ([]rbacv1.RoleBinding, error) {
+	return listAll(kubeClient, "RoleBindings", func(opts metav1.ListOptions) ([]rbacv1.RoleBinding, metav1.ListMeta, error) {
+		objs, err := kubeClient.Client.RbacV1().RoleBindings(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, metav1.ListMeta{}, err
+		}
 
-	if err != nil {
-		return nil, err
-	}
+		return objs.Items, objs.ListMeta, nil
+	})
+}
+
+func (kubeClient *KubeClient) ListClusterRoles() ([]rbacv1.ClusterRole, error) {
+	return listAll(kubeClient, "ClusterRoles", func(opts metav1.ListOptions) ([]rbacv1.ClusterRole, metav1.ListMeta, error) {
+		objs, err := kubeClient.Client.RbacV1().ClusterRoles().List(context.TODO(), opts)
+		if err != nil {
+			return nil, metav1.ListMeta{}, err
+		}
 
-	return objs.Items, nil
+		return objs.Items, objs.ListMeta, nil
+	})
 }
 
 func (kubeClient *KubeClient) ListClusterRoleBindings() ([]rbacv1.ClusterRoleBinding, error) {
-	objs, err := kubeClient.Client.RbacV1().ClusterRoleBindings().List(context.TODO(), metav1.ListOptions{})
-
-	if err != nil {
-		return nil, err
-	}
+	return listAll(kubeClient, "ClusterRoleBindings", func(opts metav1.ListOptions) ([]rbacv1.ClusterRoleBinding, metav1.ListMeta, error) {
+		objs, err := kubeClient.Client.RbacV1().ClusterRoleBindings().List(context.TODO(), opts)
+		if err != nil {
+			return nil, metav1.ListMeta{}, err
+		}
 
-	return objs.Items, nil
+		return objs.Items, objs.ListMeta, nil
+	})
 }
 
 func (kubeClient *KubeClient) TokenReview(token string) (authn.UserInfo, error) {
@@ -315,7 +418,17 @@ func (kubeClient *KubeClient) Resolve(verb, groupresource string, subResource st
 				}
 			}
 
-			possibleVerbs := sets.NewString(apiResource.Verbs...)
+			verbs := apiResource.Verbs
+			if subResource != "" {
+				sub := kubeClient.findSubresource(gv.Group, apiResource.Name, subResource)
+				if sub == nil {
+					return r, fmt.Errorf("The subresource '%s' is not supported by %v", strings.ToLower(subResource), r.String())
+				}
+
+				verbs = sub.Verbs
+			}
+
+			possibleVerbs := sets.NewString(verbs...)
 			if !possibleVerbs.Has(strings.ToLower(verb)) {
 				klog.V(8).Infof("skip - gr=%v '%v' is not in [%v]", gr.String(), verb, strings.Join(possibleVerbs.List(), ","))
 				return r, fmt.Errorf("The verb '%s' is not supported by %v", strings.ToLower(verb), r.String())
@@ -328,3 +441,92 @@ func (kubeClient *KubeClient) Resolve(verb, groupresource string, subResource st
 
 	return schema.GroupResource{}, fmt.Errorf("Failed find a matching API resource")
 }
+
+// IsSubresource returns whether the resource (short names and API groups are resolved) has the subresource, e.g. pods/exec
+func (kubeClient *KubeClient) IsSubresource(groupresource string, subResource string) bool {
+	gr := schema.ParseGroupResource(groupresource)
+
+	for _, apiResourceList := range kubeClient.ServerPreferredResources {
+		gv, err := schema.ParseGroupVersion(apiResourceList.GroupVersion)
+		if err != nil {
+			continue
+		}
+
+		if gr.Group != "" && strings.ToLower(gv.Group) != strings.ToLower(gr.Group) {
+			continue
+		}
+
+		for _, apiResource := range apiResourceList.APIResources {
+			possibleNames := sets.NewString(apiResource.ShortNames...)
+			possibleNames.Insert(strings.ToLower(apiResource.Name))
+			possibleNames.Insert(strings.ToLower(apiResource.Kind))
+
+			if !possibleNames.Has(strings.ToLower(gr.Resource)) {
+				continue
+			}
+
+			if kubeClient.findSubresource(gv.Group, apiResource.Name, subResource) != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// AllServerResources returns all the resources served by the server - all versions, including subresources.
+// The (expensive) discovery runs on first use, as only the subresource lookups need it.
+func (kubeClient *KubeClient) AllServerResources() []*metav1.APIResourceList {
+	kubeClient.serverResourcesOnce.Do(func() {
+		if kubeClient.ServerResources != nil || kubeClient.Client == nil {
+			return
+		}
+
+		_, serverResources, err := kubeClient.Client.Discovery().ServerGroupsAndResources()
+		if err != nil {
+			klog.V(3).Infof("ServerGroupsAndResources completed with errors %v (%v)", err, len(serverResources))
+		}
+
+		kubeClient.ServerResources = serverResources
+	})
+
+	return kubeClient.ServerResources
+}
+
+// HasSubresources reports whether the discovery data lists subresources (e.g. pods/exec) - discovery snapshots may not
+func (kubeClient *KubeClient) HasSubresources() bool {
+	for _, apiResourceList := range kubeClient.AllServerResources() {
+		for _, apiResource := range apiResourceList.APIResources {
+			if strings.Contains(apiResource.Name, "/") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// findSubresource looks up the subresource in all the served resources - ServerPreferredResources does not list subresources
+func (kubeClient *KubeClient) findSubresource(group string, resource string, subResource string) *metav1.APIResource {
+	resources := kubeClient.AllServerResources()
+	if len(resources) == 0 {
+		resources = kubeClient.ServerPreferredResources
+	}
+
+	name := resource + "/" + strings.ToLower(subResource)
+
+	for _, apiResourceList := range resources {
+		gv, err := schema.ParseGroupVersion(apiResourceList.GroupVersion)
+		if err != nil || gv.Group != group {
+			continue
+		}
+
+		for i := range apiResourceList.APIResources {
+			if apiResourceList.APIResources[i].Name == name {
+				return &apiResourceList.APIResources[i]
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package kube
+
+import (
+	_ "embed"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed default-discovery.yaml
+var defaultDiscovery []byte
+
+// DefaultDiscovery returns the built-in discovery snapshot of the Kubernetes API resources
+func DefaultDiscovery() []*metav1.APIResourceList {
+	resources := []*metav1.APIResourceList{}
+
+	if err := yaml.Unmarshal(defaultDiscovery, &resources); err != nil {
+		return []*metav1.APIResourceList{}
+	}
+
+	return resources
+}
+
+// LoadDiscovery reads a discovery snapshot - a JSON or YAML list of APIResourceList objects (e.g. ServerPreferredResources)
+func LoadDiscovery(fname string) ([]*metav1.APIResourceList, error) {
+	resources := []*metav1.APIResourceList{}
+
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, &resources); err != nil {
+		return nil, fmt.Errorf("Failed to read discovery snapshot '%v' - %v", fname, err)
+	}
+
+	return resources, nil
+}
+
+// NewOfflineClient creates a client that is not connected to any cluster.
+// Only discovery based helpers (e.g. Resolve) are functional.
+// The subresources of the discovery data (e.g. pods/exec) are kept out of ServerPreferredResources - like the discovery of a cluster.
+func NewOfflineClient(resources []*metav1.APIResourceList, serverVersion *version.Info) *KubeClient {
+	if resources == nil {
+		resources = DefaultDiscovery()
+	}
+
+	return &KubeClient{
+		ServerPreferredResources: withoutSubresources(resources),
+		ServerResources:          resources,
+		masterVersion:            serverVersion,
+	}
+}
+
+// withoutSubresources returns the resource lists without the subresources
+func withoutSubresources(resources []*metav1.APIResourceList) []*metav1.APIResourceList {
+	res := make([]*metav1.APIResourceList, 0, len(resources))
+	for _, apiResourceList := range resources {
+		if apiResourceList == nil {
+			continue
+		}
+
+		l := &metav1.APIResourceList{TypeMeta: apiResourceList.TypeMeta, GroupVersion: apiResourceList.GroupVersion}
+		for _, apiResource := range apiResourceList.APIResources {
+			if !strings.Contains(apiResource.Name, "/") {
+				l.APIResources = append(l.APIResources, apiResource)
+			}
+		}
+
+		res = append(res, l)
+	}
+
+	return res
+}
@@ -0,0 +1,31 @@
+package kube
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test__NewOfflineClient(t *testing.T) {
+	client := NewOfflineClient(nil, nil)
+
+	for _, apiResourceList := range client.ServerPreferredResources {
+		for _, apiResource := range apiResourceList.APIResources {
+			if strings.Contains(apiResource.Name, "/") {
+				t.Fatalf("Expecting no subresources in the preferred resources got '%v'", apiResource.Name)
+			}
+		}
+	}
+
+	if !client.HasSubresources() || !client.IsSubresource("pods", "exec") || client.IsSubresource("secrets", "some-secret") {
+		t.Fatalf("Expecting the subresources of the built-in discovery")
+	}
+
+	if _, err := client.Resolve("create", "pods", "exec"); err != nil {
+		t.Fatalf("Failed to resolve pods/exec - %v", err)
+	}
+
+	preferred := NewOfflineClient(client.ServerPreferredResources, nil)
+	if preferred.HasSubresources() || preferred.IsSubresource("pods", "exec") {
+		t.Fatalf("Expecting no subresources")
+	}
+}
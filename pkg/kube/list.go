@@ -0,0 +1,40 @@
+package kube
+
+import (
+	k8sserrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// listAll lists all the objects of a resource page by page (see KubeClient.PageSize) and reports the progress
+func listAll[T any](kubeClient *KubeClient, resource string, list func(opts metav1.ListOptions) ([]T, metav1.ListMeta, error)) ([]T, error) {
+	items := []T{}
+	opts := metav1.ListOptions{Limit: kubeClient.PageSize}
+
+	for {
+		page, meta, err := list(opts)
+		if err != nil {
+			if k8sserrs.IsResourceExpired(err) && opts.Continue != "" {
+				//The continue token expired (listing took longer than the compaction interval) - list everything at once
+				klog.V(3).Infof("Listing %v expired after %v objects - listing without pagination", resource, len(items))
+				items = items[:0]
+				opts = metav1.ListOptions{}
+				continue
+			}
+
+			return nil, err
+		}
+
+		items = append(items, page...)
+		klog.V(6).Infof("Listed %v %v", len(items), resource)
+
+		if kubeClient.Progress != nil {
+			kubeClient.Progress(resource, len(items))
+		}
+
+		if meta.Continue == "" {
+			return items, nil
+		}
+		opts.Continue = meta.Continue
+	}
+}
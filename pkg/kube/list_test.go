@@ -0,0 +1,50 @@
+package kube
+
+import (
+	"fmt"
+	"testing"
+
+	k8sserrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test__ListAll(t *testing.T) {
+	objs := []int{}
+	for i := 0; i < 12; i++ {
+		objs = append(objs, i)
+	}
+
+	//Serves pages of opts.Limit objects - the continue token is the offset
+	expireAt := ""
+	list := func(opts metav1.ListOptions) ([]int, metav1.ListMeta, error) {
+		if opts.Continue != "" && opts.Continue == expireAt {
+			expireAt = ""
+			return nil, metav1.ListMeta{}, k8sserrs.NewResourceExpired("continue token expired")
+		}
+
+		offset := 0
+		fmt.Sscanf(opts.Continue, "%d", &offset)
+
+		if opts.Limit == 0 || offset+int(opts.Limit) >= len(objs) {
+			return objs[offset:], metav1.ListMeta{}, nil
+		}
+
+		end := offset + int(opts.Limit)
+		return objs[offset:end], metav1.ListMeta{Continue: fmt.Sprintf("%d", end)}, nil
+	}
+
+	pages := 0
+	client := &KubeClient{PageSize: 5, Progress: func(resource string, listed int) { pages++ }}
+
+	items, err := listAll(client, "Ints", list)
+	if err != nil || len(items) != len(objs) || pages != 3 {
+		t.Fatalf("Expecting %v items in 3 pages got %v in %v pages (%v)", len(objs), len(items), pages, err)
+	}
+
+	//The list restarts without pagination when the continue token expires
+	expireAt = "10"
+	items, err = listAll(client, "Ints", list)
+	if err != nil || len(items) != len(objs) || items[11] != 11 {
+		t.Fatalf("Expecting %v items after the expired continue token got %v (%v)", len(objs), items, err)
+	}
+}
@@ -0,0 +1,115 @@
+package kube
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ClientOverrides override the kubeconfig settings of the clients - like the kubectl flags of the same names
+type ClientOverrides struct {
+	//Path to the kubeconfig file - instead of $KUBECONFIG or ~/.kube/config
+	Kubeconfig string
+
+	//The address of the API server
+	Server string
+
+	//Bearer token to authenticate with
+	Token string
+
+	//The user and groups to impersonate
+	As       string
+	AsGroups []string
+}
+
+var overrides = ClientOverrides{}
+
+// AddClientFlags adds the flags that override the kubeconfig settings of every client
+func AddClientFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&overrides.Kubeconfig, "kubeconfig", "", "Path to the kubeconfig file to use")
+	flags.StringVar(&overrides.Server, "server", "", "The address and port of the Kubernetes API server")
+	flags.StringVar(&overrides.Token, "token", "", "Bearer token for authentication to the API server")
+	flags.StringVar(&overrides.As, "as", "", "Username to impersonate for the operation. User could be a regular user or a service account in a namespace")
+	flags.StringArrayVar(&overrides.AsGroups, "as-group", nil, "Group to impersonate for the operation, this flag can be repeated to specify multiple groups")
+}
+
+// Overrides returns the kubeconfig overrides
+func Overrides() ClientOverrides {
+	return overrides
+}
+
+// ClientConfig is the kubeconfig of the context ("" is the current context) with the overrides applied
+func ClientConfig(context string) clientcmd.ClientConfig {
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules(), configOverrides(context))
+}
+
+// Identity identifies the cluster and the credentials the clients of the context ("" is the current context) use -
+// a hash of the overrides, the kubeconfig files, and the resolved server and user
+func Identity(context string) (string, error) {
+	config := ClientConfig(context)
+
+	raw, err := config.RawConfig()
+	if err != nil {
+		return "", err
+	}
+
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return "", err
+	}
+
+	if context == "" {
+		context = raw.CurrentContext
+	}
+
+	authInfo := ""
+	if c, exist := raw.Contexts[context]; exist {
+		authInfo = c.AuthInfo
+	}
+
+	h := sha256.New()
+	for _, v := range []string{
+		context,
+		strings.Join(loadingRules().GetLoadingPrecedence(), ","),
+		overrides.Kubeconfig,
+		overrides.Server,
+		overrides.Token,
+		overrides.As,
+		strings.Join(overrides.AsGroups, ","),
+		restConfig.Host,
+		authInfo,
+		restConfig.Username,
+		restConfig.Impersonate.UserName,
+		strings.Join(restConfig.Impersonate.Groups, ","),
+	} {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+func loadingRules() *clientcmd.ClientConfigLoadingRules {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if overrides.Kubeconfig != "" {
+		rules.ExplicitPath = overrides.Kubeconfig
+	}
+
+	return rules
+}
+
+func configOverrides(context string) *clientcmd.ConfigOverrides {
+	o := &clientcmd.ConfigOverrides{
+		CurrentContext: context,
+	}
+
+	o.ClusterInfo.Server = overrides.Server
+	o.AuthInfo.Token = overrides.Token
+	o.AuthInfo.Impersonate = overrides.As
+	o.AuthInfo.ImpersonateGroups = overrides.AsGroups
+
+	return o
+}
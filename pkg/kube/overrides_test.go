@@ -0,0 +1,84 @@
+package kube
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: admin
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com
+- name: staging
+  cluster:
+    server: https://staging.example.com
+users:
+- name: admin
+  user:
+    token: admin-token
+- name: auditor
+  user:
+    token: auditor-token
+contexts:
+- name: admin
+  context:
+    cluster: prod
+    user: admin
+- name: auditor
+  context:
+    cluster: prod
+    user: auditor
+- name: staging
+  context:
+    cluster: staging
+    user: admin
+`
+
+func Test__Identity(t *testing.T) {
+	saved := overrides
+	defer func() { overrides = saved }()
+
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(kubeconfig, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatalf("Failed to write kubeconfig - %v", err)
+	}
+
+	overrides = ClientOverrides{Kubeconfig: kubeconfig}
+
+	identity := func(context string) string {
+		id, err := Identity(context)
+		if err != nil {
+			t.Fatalf("Failed to resolve the identity of '%v' - %v", context, err)
+		}
+		return id
+	}
+
+	admin := identity("admin")
+	if admin != identity("admin") || admin != identity("") {
+		t.Fatalf("Expecting a stable identity of the current context")
+	}
+
+	if admin == identity("auditor") || admin == identity("staging") {
+		t.Fatalf("Expecting the user and the server in the identity")
+	}
+
+	overrides.As = "readonly"
+	readonly := identity("admin")
+	if admin == readonly {
+		t.Fatalf("Expecting the impersonated user in the identity")
+	}
+
+	overrides.AsGroups = []string{"auditors"}
+	if readonly == identity("admin") {
+		t.Fatalf("Expecting the impersonated groups in the identity")
+	}
+
+	overrides = ClientOverrides{Kubeconfig: kubeconfig, Server: "https://other.example.com"}
+	if admin == identity("admin") {
+		t.Fatalf("Expecting the server override in the identity")
+	}
+}
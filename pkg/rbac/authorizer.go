@@ -0,0 +1,365 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	rbacauthorizer "k8s.io/kubernetes/plugin/pkg/auth/authorizer/rbac"
+)
+
+// Authorizer evaluates requests against the RBAC resources with the semantics of the Kubernetes RBAC authorizer
+// (resourceNames, subresources, non-resource URL prefixes and namespace scoping)
+type Authorizer struct {
+	roles *StaticRoles
+}
+
+var _ authorizer.Authorizer = &Authorizer{}
+
+func NewAuthorizer(perms *Permissions) *Authorizer {
+	return &Authorizer{
+		roles: NewStaticRoles(perms),
+	}
+}
+
+// Authorize implements authorizer.Authorizer
+func (a *Authorizer) Authorize(ctx context.Context, attrs authorizer.Attributes) (authorizer.Decision, string, error) {
+	var reason string
+	errs := []error{}
+
+	a.VisitRulesFor(attrs.GetUser(), attrs.GetNamespace(), func(source fmt.Stringer, rule *rbacv1.PolicyRule, err error) bool {
+		if rule != nil && rbacauthorizer.RuleAllows(attrs, rule) {
+			reason = fmt.Sprintf("RBAC: allowed by %s", source.String())
+			return false
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return true
+	})
+
+	if reason != "" {
+		return authorizer.DecisionAllow, reason, nil
+	}
+
+	if len(errs) > 0 {
+		reason = fmt.Sprintf("RBAC: %v", utilerrors.NewAggregate(errs))
+	}
+
+	return authorizer.DecisionNoOpinion, reason, nil
+}
+
+// VisitRulesFor visits the rules that apply to the user - the ClusterRoleBindings rules and,
+// when a namespace is specified, the namespace RoleBindings rules.
+// The source of each rule is a *ClusterRoleBindingDescriber or a *RoleBindingDescriber.
+func (a *Authorizer) VisitRulesFor(user user.Info, namespace string, visitor func(source fmt.Stringer, rule *rbacv1.PolicyRule, err error) bool) {
+	clusterRoleBindings, _ := a.roles.ListClusterRoleBindings()
+	for _, clusterRoleBinding := range clusterRoleBindings {
+		subjectIndex, applies := appliesTo(user, clusterRoleBinding.Subjects, "")
+		if !applies {
+			continue
+		}
+
+		rules, err := a.getRoleReferenceRules(clusterRoleBinding.RoleRef, "")
+		if err != nil {
+			if !visitor(nil, nil, err) {
+				return
+			}
+			continue
+		}
+
+		source := &ClusterRoleBindingDescriber{binding: clusterRoleBinding, subject: &clusterRoleBinding.Subjects[subjectIndex]}
+		for i := range rules {
+			if !visitor(source, &rules[i], nil) {
+				return
+			}
+		}
+	}
+
+	if len(namespace) == 0 {
+		return
+	}
+
+	roleBindings, err := a.roles.ListRoleBindings(namespace)
+	if err != nil {
+		visitor(nil, nil, err)
+		return
+	}
+
+	for _, roleBinding := range roleBindings {
+		subjectIndex, applies := appliesTo(user, roleBinding.Subjects, namespace)
+		if !applies {
+			continue
+		}
+
+		rules, err := a.getRoleReferenceRules(roleBinding.RoleRef, namespace)
+		if err != nil {
+			if !visitor(nil, nil, err) {
+				return
+			}
+			continue
+		}
+
+		source := &RoleBindingDescriber{binding: roleBinding, subject: &roleBinding.Subjects[subjectIndex]}
+		for i := range rules {
+			if !visitor(source, &rules[i], nil) {
+				return
+			}
+		}
+	}
+}
+
+// RulesFor returns the rules that apply to the user in the namespace - the cluster-wide rules and the namespace rules.
+// The errors are of roles that could not be resolved.
+func (a *Authorizer) RulesFor(user user.Info, namespace string) ([]rbacv1.PolicyRule, error) {
+	rules := []rbacv1.PolicyRule{}
+	errs := []error{}
+
+	a.VisitRulesFor(user, namespace, func(source fmt.Stringer, rule *rbacv1.PolicyRule, err error) bool {
+		if rule != nil {
+			rules = append(rules, *rule)
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return true
+	})
+
+	return rules, utilerrors.NewAggregate(errs)
+}
+
+// Namespaces returns the namespaces with RoleBindings - the namespaces where namespaced grants may exist
+func (a *Authorizer) Namespaces() []string {
+	namespaces := sets.NewString()
+	for _, roleBinding := range a.roles.roleBindings {
+		namespaces.Insert(roleBinding.Namespace)
+	}
+
+	return namespaces.List()
+}
+
+// Grant is a rule that allows a request, with the binding and role it was granted by
+type Grant struct {
+	//The namespace the grant applies to - empty for cluster-wide grants (ClusterRoleBindings)
+	Namespace string `json:"namespace,omitempty"`
+
+	Binding BindingRef     `json:"binding"`
+	RoleRef rbacv1.RoleRef `json:"roleRef"`
+
+	//The binding subject that applied - a group when the access is granted through a group membership
+	BindingSubject rbacv1.Subject `json:"bindingSubject"`
+
+	Rule rbacv1.PolicyRule `json:"rule"`
+}
+
+// SubjectGrants captures the grants that allow a subject to perform a request
+type SubjectGrants struct {
+	rbacv1.Subject
+
+	Grants []Grant `json:"grants"`
+}
+
+// Grants returns every rule that allows the request - unlike Authorize it does not stop at the first allowing rule
+func (a *Authorizer) Grants(attrs authorizer.Attributes) []Grant {
+	grants := []Grant{}
+
+	a.VisitRulesFor(attrs.GetUser(), attrs.GetNamespace(), func(source fmt.Stringer, rule *rbacv1.PolicyRule, err error) bool {
+		if rule == nil || !rbacauthorizer.RuleAllows(attrs, rule) {
+			return true
+		}
+
+		d := source.(BindingDescriber)
+		grants = append(grants, Grant{
+			Namespace:      d.Binding().Namespace,
+			Binding:        d.Binding(),
+			RoleRef:        d.RoleRef(),
+			BindingSubject: d.Subject(),
+			Rule:           *rule,
+		})
+
+		return true
+	})
+
+	return grants
+}
+
+// GrantsIn returns the rules that allow the request in any of the namespaces ("" evaluates cluster-wide grants only)
+func (a *Authorizer) GrantsIn(attrs authorizer.AttributesRecord, namespaces []string) []Grant {
+	//Cluster-wide grants are visited again for every namespace
+	seen := sets.NewString()
+	grants := []Grant{}
+
+	for _, namespace := range namespaces {
+		attrs.Namespace = namespace
+
+		for _, grant := range a.Grants(attrs) {
+			key := fmt.Sprintf("%v|%v|%v", grant.Binding.String(), grant.RoleRef.Name, grant.Rule.String())
+			if seen.Has(key) {
+				continue
+			}
+
+			seen.Insert(key)
+			grants = append(grants, grant)
+		}
+	}
+
+	return grants
+}
+
+// WhoCan returns the subjects allowed to perform the request in any of the namespaces ("" evaluates cluster-wide grants only),
+// with the grants that allow them.
+// The user of the request attributes is ignored - each candidate subject is evaluated with the user info
+// the API server would authenticate it as.
+func (a *Authorizer) WhoCan(perms *Permissions, attrs authorizer.AttributesRecord, namespaces []string, implicitGroups bool) []SubjectGrants {
+	allowed := []SubjectGrants{}
+
+	for _, subject := range Subjects(perms) {
+		attrs.User = SubjectUser(subject, implicitGroups)
+
+		if grants := a.GrantsIn(attrs, namespaces); len(grants) > 0 {
+			allowed = append(allowed, SubjectGrants{Subject: subject, Grants: grants})
+		}
+	}
+
+	return allowed
+}
+
+// Subjects returns the subjects referenced by the bindings and the ServiceAccounts
+func Subjects(perms *Permissions) []rbacv1.Subject {
+	subjects := map[string]rbacv1.Subject{}
+
+	for _, bindings := range perms.RoleBindings {
+		for _, binding := range bindings {
+			for _, subject := range binding.Subjects {
+				if subject.Kind == rbacv1.ServiceAccountKind && subject.Namespace == "" {
+					subject.Namespace = binding.Namespace
+				}
+
+				subjects[fmt.Sprintf("%v/%v/%v", subject.Kind, subject.Namespace, subject.Name)] = subject
+			}
+		}
+	}
+
+	for namespace, sas := range perms.ServiceAccounts {
+		for name := range sas {
+			subject := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: namespace, Name: name}
+			key := fmt.Sprintf("%v/%v/%v", subject.Kind, subject.Namespace, subject.Name)
+
+			if _, exist := subjects[key]; !exist {
+				subjects[key] = subject
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(subjects))
+	for k := range subjects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := make([]rbacv1.Subject, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, subjects[k])
+	}
+
+	return res
+}
+
+// SubjectUser returns the user info the API server would authenticate the subject as.
+// A Group subject is evaluated as an anonymous member of the group.
+func SubjectUser(subject rbacv1.Subject, implicitGroups bool) user.Info {
+	switch subject.Kind {
+	case rbacv1.ServiceAccountKind:
+		info := &user.DefaultInfo{Name: serviceaccount.MakeUsername(subject.Namespace, subject.Name)}
+		if implicitGroups {
+			info.Groups = ServiceAccountGroups(subject.Namespace)
+		}
+		return info
+	case rbacv1.GroupKind:
+		return &user.DefaultInfo{Groups: []string{subject.Name}}
+	default:
+		return &user.DefaultInfo{Name: subject.Name}
+	}
+}
+
+// RequestUser returns the user info of a request made by the subject - like 'kubectl auth can-i --as'.
+// With implicitGroups Users and Groups are also members of system:authenticated (system:unauthenticated for system:anonymous).
+func RequestUser(subject rbacv1.Subject, implicitGroups bool) user.Info {
+	info := SubjectUser(subject, implicitGroups)
+	if !implicitGroups || subject.Kind == rbacv1.ServiceAccountKind {
+		return info
+	}
+
+	groups := info.GetGroups()
+	switch {
+	case subject.Kind == rbacv1.UserKind && subject.Name == user.Anonymous:
+		groups = append(groups, user.AllUnauthenticated)
+	case subject.Kind == rbacv1.GroupKind && (subject.Name == user.AllAuthenticated || subject.Name == user.AllUnauthenticated):
+	default:
+		groups = append(groups, user.AllAuthenticated)
+	}
+
+	return &user.DefaultInfo{Name: info.GetName(), Groups: groups}
+}
+
+func (a *Authorizer) getRoleReferenceRules(roleRef rbacv1.RoleRef, bindingNamespace string) ([]rbacv1.PolicyRule, error) {
+	switch roleRef.Kind {
+	case "Role":
+		role, err := a.roles.GetRole(bindingNamespace, roleRef.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%v '%v/%v' - %v", roleRef.Kind, bindingNamespace, roleRef.Name, err)
+		}
+		return role.Rules, nil
+
+	case "ClusterRole":
+		clusterRole, err := a.roles.GetClusterRole(roleRef.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%v '%v' - %v", roleRef.Kind, roleRef.Name, err)
+		}
+		return clusterRole.Rules, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported role reference kind: %q", roleRef.Kind)
+	}
+}
+
+// appliesTo returns whether any of the bindingSubjects applies to the user, and if true, the index of the first subject that applies
+func appliesTo(user user.Info, bindingSubjects []rbacv1.Subject, namespace string) (int, bool) {
+	for i, bindingSubject := range bindingSubjects {
+		if appliesToUser(user, bindingSubject, namespace) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func appliesToUser(user user.Info, subject rbacv1.Subject, namespace string) bool {
+	switch subject.Kind {
+	case rbacv1.UserKind:
+		return len(user.GetName()) > 0 && user.GetName() == subject.Name
+
+	case rbacv1.GroupKind:
+		return sets.NewString(user.GetGroups()...).Has(subject.Name)
+
+	case rbacv1.ServiceAccountKind:
+		// default the namespace to the binding namespace - RoleBindings may reference ServiceAccounts in the local namespace unqualified
+		saNamespace := namespace
+		if len(subject.Namespace) > 0 {
+			saNamespace = subject.Namespace
+		}
+		if len(saNamespace) == 0 {
+			return false
+		}
+		return serviceaccount.MatchesUsername(saNamespace, subject.Name, user.GetName())
+	default:
+		return false
+	}
+}
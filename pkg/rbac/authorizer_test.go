@@ -0,0 +1,204 @@
+package rbac
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/klog"
+)
+
+func Test__Authorizer(t *testing.T) {
+	defer klog.Flush()
+
+	objs := []runtime.Object{
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-reader"},
+			Rules:      []rbacv1.PolicyRule{{NonResourceURLs: []string{"/api*"}, Verbs: []string{"get"}}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-reader"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "alice"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "api-reader"},
+		},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-exec", Namespace: "payments"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods/exec"}, Verbs: []string{"create"}}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-exec", Namespace: "payments"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "debugger"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "pod-exec"},
+		},
+	}
+
+	perms, err := NewPermissionsFromResourceList(objs)
+	if err != nil {
+		t.Fatalf("Failed to create permissions - %v", err)
+	}
+
+	authz := NewAuthorizer(perms)
+	alice := SubjectUser(rbacv1.Subject{Kind: rbacv1.UserKind, Name: "alice"}, true)
+	debugger := SubjectUser(rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: "payments", Name: "debugger"}, true)
+
+	tests := []struct {
+		attrs    authorizer.AttributesRecord
+		expected authorizer.Decision
+	}{
+		{authorizer.AttributesRecord{User: alice, Verb: "get", Path: "/apis/apps"}, authorizer.DecisionAllow},
+		{authorizer.AttributesRecord{User: alice, Verb: "get", Path: "/healthz"}, authorizer.DecisionNoOpinion},
+		{authorizer.AttributesRecord{User: debugger, Verb: "create", Namespace: "payments", Resource: "pods", Subresource: "exec", ResourceRequest: true}, authorizer.DecisionAllow},
+		{authorizer.AttributesRecord{User: debugger, Verb: "create", Namespace: "payments", Resource: "pods", ResourceRequest: true}, authorizer.DecisionNoOpinion},
+		{authorizer.AttributesRecord{User: debugger, Verb: "create", Namespace: "default", Resource: "pods", Subresource: "exec", ResourceRequest: true}, authorizer.DecisionNoOpinion},
+		{authorizer.AttributesRecord{User: debugger, Verb: "create", Resource: "pods", Subresource: "exec", ResourceRequest: true}, authorizer.DecisionNoOpinion},
+	}
+
+	for i, test := range tests {
+		decision, reason, _ := authz.Authorize(context.Background(), test.attrs)
+		if decision != test.expected {
+			t.Fatalf("[%v] Expecting decision %v got %v (%v)", i, test.expected, decision, reason)
+		}
+	}
+
+	subjects := authz.WhoCan(perms, authorizer.AttributesRecord{Verb: "create", Resource: "pods", Subresource: "exec", ResourceRequest: true}, append([]string{""}, authz.Namespaces()...), true)
+	if len(subjects) != 1 || subjects[0].Name != "debugger" || subjects[0].Namespace != "payments" ||
+		len(subjects[0].Grants) != 1 || subjects[0].Grants[0].Binding.Name != "pod-exec" || subjects[0].Grants[0].Namespace != "payments" {
+		t.Fatalf("Unexpected subjects %+v", subjects)
+	}
+}
+
+func Test__RulesForImplicitGroups(t *testing.T) {
+	defer klog.Flush()
+
+	objs := []runtime.Object{
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "configmap-reader"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "authenticated-configmap-reader"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "system:authenticated"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "configmap-reader"},
+		},
+	}
+
+	perms, err := NewPermissionsFromResourceList(objs)
+	if err != nil {
+		t.Fatalf("Failed to create permissions - %v", err)
+	}
+
+	authz := NewAuthorizer(perms)
+
+	tests := []struct {
+		subject        rbacv1.Subject
+		implicitGroups bool
+		expected       int
+	}{
+		{rbacv1.Subject{Kind: rbacv1.UserKind, Name: "bob"}, true, 1},
+		{rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: "build", Name: "ci"}, true, 1},
+		{rbacv1.Subject{Kind: rbacv1.GroupKind, Name: "devs"}, true, 1},
+		{rbacv1.Subject{Kind: rbacv1.UserKind, Name: "bob"}, false, 0},
+		{rbacv1.Subject{Kind: rbacv1.UserKind, Name: "system:anonymous"}, true, 0},
+		{rbacv1.Subject{Kind: rbacv1.GroupKind, Name: "system:unauthenticated"}, true, 0},
+	}
+
+	for i, test := range tests {
+		rules, err := authz.RulesFor(RequestUser(test.subject, test.implicitGroups), "payments")
+		if err != nil {
+			t.Fatalf("[%v] Failed to list rules - %v", i, err)
+		}
+
+		if len(rules) != test.expected {
+			t.Fatalf("[%v] Expecting %v rules for %v got %+v", i, test.expected, test.subject, rules)
+		}
+	}
+}
+
+func Test__WhoCanImplicitGroups(t *testing.T) {
+	defer klog.Flush()
+
+	objs := []runtime.Object{
+		&v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "build"}},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "configmap-reader"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "authenticated-configmap-reader"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "system:authenticated"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "configmap-reader"},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "payments-configmap-reader", Namespace: "payments"},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "alice"},
+				{Kind: rbacv1.ServiceAccountKind, Namespace: "build", Name: "ci"},
+			},
+			RoleRef: rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "configmap-reader"},
+		},
+	}
+
+	perms, err := NewPermissionsFromResourceList(objs)
+	if err != nil {
+		t.Fatalf("Failed to create permissions - %v", err)
+	}
+
+	authz := NewAuthorizer(perms)
+	attrs := authorizer.AttributesRecord{Verb: "get", Resource: "configmaps", ResourceRequest: true}
+
+	whoCan := func(implicitGroups bool) []string {
+		names := []string{}
+		for _, s := range authz.WhoCan(perms, attrs, []string{"", "payments"}, implicitGroups) {
+			names = append(names, s.Subject.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	// Users and Groups are not credited with system:authenticated - only ServiceAccounts are
+	if names := strings.Join(whoCan(true), ","); names != "alice,ci,deployer,system:authenticated" {
+		t.Fatalf("Unexpected who-can subjects with implicit groups '%v'", names)
+	}
+
+	if names := strings.Join(whoCan(false), ","); names != "alice,ci,system:authenticated" {
+		t.Fatalf("Unexpected who-can subjects without implicit groups '%v'", names)
+	}
+
+	// explain - the grants of the subject
+	explain := func(subject rbacv1.Subject, namespace string, implicitGroups bool) []string {
+		attrs := attrs
+		attrs.Namespace = namespace
+		attrs.User = SubjectUser(subject, implicitGroups)
+
+		bindings := []string{}
+		for _, g := range authz.Grants(attrs) {
+			bindings = append(bindings, g.Binding.String())
+		}
+		sort.Strings(bindings)
+		return bindings
+	}
+
+	ci := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: "build", Name: "ci"}
+	if bindings := strings.Join(explain(ci, "payments", true), ","); bindings != "ClusterRoleBinding>>authenticated-configmap-reader,RoleBinding>>payments/payments-configmap-reader" {
+		t.Fatalf("Unexpected grants of the ServiceAccount with implicit groups '%v'", bindings)
+	}
+
+	if bindings := strings.Join(explain(ci, "payments", false), ","); bindings != "RoleBinding>>payments/payments-configmap-reader" {
+		t.Fatalf("Unexpected grants of the ServiceAccount without implicit groups '%v'", bindings)
+	}
+
+	bob := rbacv1.Subject{Kind: rbacv1.UserKind, Name: "bob"}
+	if bindings := explain(bob, "payments", true); len(bindings) != 0 {
+		t.Fatalf("Expecting no grants of the User got %v", bindings)
+	}
+
+	if rules, err := authz.RulesFor(RequestUser(bob, true), "payments"); err != nil || len(rules) != 1 {
+		t.Fatalf("Expecting can-i to credit the User with system:authenticated got %+v (%v)", rules, err)
+	}
+}
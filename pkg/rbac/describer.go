@@ -44,3 +44,38 @@ func (d *RoleBindingDescriber) String() string {
 		DescribeSubject(d.subject, d.binding.Namespace),
 	)
 }
+
+func (d *ClusterRoleBindingDescriber) Binding() BindingRef {
+	return BindingRef{Kind: "ClusterRoleBinding", Name: d.binding.Name}
+}
+
+func (d *ClusterRoleBindingDescriber) RoleRef() rbacv1.RoleRef {
+	return d.binding.RoleRef
+}
+
+func (d *ClusterRoleBindingDescriber) Subject() rbacv1.Subject {
+	return *d.subject
+}
+
+func (d *RoleBindingDescriber) Binding() BindingRef {
+	return BindingRef{Kind: "RoleBinding", Namespace: d.binding.Namespace, Name: d.binding.Name}
+}
+
+func (d *RoleBindingDescriber) RoleRef() rbacv1.RoleRef {
+	return d.binding.RoleRef
+}
+
+func (d *RoleBindingDescriber) Subject() rbacv1.Subject {
+	return *d.subject
+}
+
+// BindingDescriber describes the binding a rule was granted by
+type BindingDescriber interface {
+	fmt.Stringer
+
+	Binding() BindingRef
+	RoleRef() rbacv1.RoleRef
+
+	//The binding subject that applied to the user (e.g. a group the user is a member of)
+	Subject() rbacv1.Subject
+}
@@ -0,0 +1,97 @@
+package rbac
+
+import (
+	"regexp"
+	"sort"
+
+	v1 "k8s.io/api/rbac/v1"
+)
+
+// SubjectBinding is a Role/ClusterRole bound to a subject
+type SubjectBinding struct {
+	Subject v1.Subject `json:"subject"`
+
+	//ClusterRole or Role
+	Scope string `json:"scope"`
+
+	//The namespace of the RoleBinding - empty for ClusterRoleBindings
+	Namespace string `json:"namespace,omitempty"`
+
+	Role    string `json:"role"`
+	Binding string `json:"binding"`
+}
+
+// matchSubjectName reports whether the subject name matches the regex - or does not match it when inverse is set
+func matchSubjectName(re *regexp.Regexp, inverse bool, name string) bool {
+	//  match    inverse
+	//  -----------------
+	//  true     true   --> skip
+	//  true     false  --> keep
+	//  false    true   --> keep
+	//  false    false  --> skip
+	return re.MatchString(name) != inverse
+}
+
+// Lookup returns the Roles/ClusterRoles bound to the subjects whose name matches the regex (or does not match it when inverse is set),
+// sorted by subject name and namespace
+func Lookup(perms *Permissions, re *regexp.Regexp, inverse bool) []SubjectBinding {
+	result := []SubjectBinding{}
+
+	for _, bindings := range perms.RoleBindings {
+		for _, binding := range bindings {
+			for _, subject := range binding.Subjects {
+				if !matchSubjectName(re, inverse, subject.Name) {
+					continue
+				}
+
+				//Subject match
+				roleNamespace := binding.Namespace
+				if binding.RoleRef.Kind == "ClusterRole" {
+					roleNamespace = ""
+				}
+				_, exist := perms.Roles[roleNamespace]
+				if !exist {
+					continue
+				}
+
+				scope := "Role"
+				if roleNamespace == "" {
+					scope = "ClusterRole"
+				}
+
+				result = append(result, SubjectBinding{
+					Subject:   subject,
+					Scope:     scope,
+					Namespace: binding.Namespace,
+					Role:      binding.RoleRef.Name,
+					Binding:   binding.Name,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Subject.Name == result[j].Subject.Name {
+			return result[i].Namespace < result[j].Namespace
+		}
+
+		return result[i].Subject.Name < result[j].Subject.Name
+	})
+
+	return result
+}
+
+// FilterSubjectPermissions returns the policies of the subjects whose name matches the regex (or does not match it when inverse is set)
+func FilterSubjectPermissions(policies []SubjectPermissions, re *regexp.Regexp, inverse bool) []SubjectPermissions {
+	filtered := []SubjectPermissions{}
+
+	for _, policy := range policies {
+		if !matchSubjectName(re, inverse, policy.Subject.Name) {
+			continue
+		}
+
+		filtered = append(filtered, policy)
+	}
+
+	return filtered
+}
@@ -1,12 +1,19 @@
 package rbac
 
 import (
+	"sort"
+
 	v1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog"
 
 	"github.com/alcideio/rbac-tool/pkg/kube"
+	"github.com/alcideio/rbac-tool/pkg/utils"
 )
 
 type Permissions struct {
@@ -17,6 +24,10 @@ type Permissions struct {
 	// - ClusterRoleBindings are stored in RoleBindings[""]
 	Roles        map[string]map[string]rbacv1.Role
 	RoleBindings map[string]map[string]rbacv1.RoleBinding
+
+	// AggregatedRules captures the effective rules of aggregated ClusterRoles (ClusterRoles with an aggregationRule).
+	// Each rule records in OriginatedFrom the contributing ClusterRole it was selected from.
+	AggregatedRules map[string][]PolicyRule
 }
 
 func (p *Permissions) populateServiceAccounts(sas []v1.ServiceAccount) {
@@ -58,6 +69,115 @@ func (p *Permissions) populateClusterRoles(clusterRoles []rbacv1.ClusterRole) {
 		p.Roles[role.Namespace][role.Name] = aRole
 		klog.V(6).Infof("ClusterRole %v", role.Name)
 	}
+
+	p.aggregateClusterRoles(clusterRoles)
+}
+
+// aggregateClusterRoles computes the rules of ClusterRoles with an aggregationRule the same way the
+// clusterrole-aggregation controller does - the union of the rules of all the ClusterRoles selected by the aggregation rule.
+// Offline manifests typically carry an empty rules list for such ClusterRoles, so we always compute them.
+func (p *Permissions) aggregateClusterRoles(clusterRoles []rbacv1.ClusterRole) {
+	if p.AggregatedRules == nil {
+		p.AggregatedRules = make(map[string][]PolicyRule)
+	}
+
+	byName := map[string]*rbacv1.ClusterRole{}
+	for i := range clusterRoles {
+		byName[clusterRoles[i].Name] = &clusterRoles[i]
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		role := byName[name]
+		if role.AggregationRule == nil {
+			continue
+		}
+
+		rules := aggregatedRulesFor(role, names, byName, sets.NewString())
+
+		aRole := p.Roles[""][role.Name]
+		aRole.Rules = make([]rbacv1.PolicyRule, len(rules))
+		for i := range rules {
+			aRole.Rules[i] = rules[i].PolicyRule
+		}
+		p.Roles[""][role.Name] = aRole
+		p.AggregatedRules[role.Name] = rules
+
+		klog.V(6).Infof("ClusterRole %v aggregated %v rules", role.Name, len(rules))
+	}
+}
+
+func aggregatedRulesFor(role *rbacv1.ClusterRole, names []string, byName map[string]*rbacv1.ClusterRole, visiting sets.String) []PolicyRule {
+	visiting.Insert(role.Name)
+	defer visiting.Delete(role.Name)
+
+	rules := []PolicyRule{}
+	for i := range role.AggregationRule.ClusterRoleSelectors {
+		selector, err := metav1.LabelSelectorAsSelector(&role.AggregationRule.ClusterRoleSelectors[i])
+		if err != nil {
+			klog.V(5).Infof("ClusterRole %v has an invalid aggregation selector - %v", role.Name, err)
+			continue
+		}
+
+		// names are sorted - same ordering as the aggregation controller
+		for _, name := range names {
+			selected := byName[name]
+			if selected.Name == role.Name {
+				continue
+			}
+
+			if !selector.Matches(labels.Set(selected.Labels)) {
+				continue
+			}
+
+			var selectedRules []PolicyRule
+			if selected.AggregationRule != nil && !visiting.Has(selected.Name) {
+				// Nested aggregation - attribute the rules to the ClusterRoles that actually define them
+				selectedRules = aggregatedRulesFor(selected, names, byName, visiting)
+			} else {
+				selectedRules = make([]PolicyRule, len(selected.Rules))
+				for j := range selected.Rules {
+					selectedRules[j].PolicyRule = selected.Rules[j]
+					selectedRules[j].OriginatedFrom = []rbacv1.RoleRef{
+						{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: selected.Name},
+					}
+				}
+			}
+
+			for _, rule := range selectedRules {
+				if !ruleExists(rules, rule.PolicyRule) {
+					rules = append(rules, rule)
+				}
+			}
+		}
+	}
+
+	return rules
+}
+
+// AggregatedFrom returns the ClusterRole an aggregated ClusterRole rule was aggregated from
+func (p *Permissions) AggregatedFrom(clusterRole string, rule rbacv1.PolicyRule) (rbacv1.RoleRef, bool) {
+	for _, aggregated := range p.AggregatedRules[clusterRole] {
+		if len(aggregated.OriginatedFrom) > 0 && equality.Semantic.DeepEqual(aggregated.PolicyRule, rule) {
+			return aggregated.OriginatedFrom[0], true
+		}
+	}
+
+	return rbacv1.RoleRef{}, false
+}
+
+func ruleExists(haystack []PolicyRule, needle rbacv1.PolicyRule) bool {
+	for _, curr := range haystack {
+		if equality.Semantic.DeepEqual(curr.PolicyRule, needle) {
+			return true
+		}
+	}
+	return false
 }
 
 func (p *Permissions) populateRoleBindings(bindings []rbacv1.RoleBinding) {
@@ -94,36 +214,44 @@ func NewPermissionsFromCluster(client *kube.KubeClient) (*Permissions, error) {
 	permissions.ServiceAccounts = make(map[string]map[string]v1.ServiceAccount)
 	permissions.Roles = make(map[string]map[string]rbacv1.Role)
 	permissions.RoleBindings = make(map[string]map[string]rbacv1.RoleBinding)
-
-	sas, err := client.ListServiceAccounts(v1.NamespaceAll)
+	permissions.AggregatedRules = make(map[string][]PolicyRule)
+
+	var sas []v1.ServiceAccount
+	var roles []rbacv1.Role
+	var clusterRoles []rbacv1.ClusterRole
+	var bindings []rbacv1.RoleBinding
+	var clusterBindings []rbacv1.ClusterRoleBinding
+
+	err := utils.RunConcurrently(
+		func() (err error) {
+			sas, err = client.ListServiceAccounts(v1.NamespaceAll)
+			return err
+		},
+		func() (err error) {
+			roles, err = client.ListRoles(v1.NamespaceAll)
+			return err
+		},
+		func() (err error) {
+			clusterRoles, err = client.ListClusterRoles()
+			return err
+		},
+		func() (err error) {
+			bindings, err = client.ListRoleBindings(v1.NamespaceAll)
+			return err
+		},
+		func() (err error) {
+			clusterBindings, err = client.ListClusterRoleBindings()
+			return err
+		},
+	)
 	if err != nil {
 		return nil, err
 	}
 
 	permissions.populateServiceAccounts(sas)
-
-	roles, err := client.ListRoles(v1.NamespaceAll)
-	if err != nil {
-		return nil, err
-	}
 	permissions.populateRoles(roles)
-
-	clusterRoles, err := client.ListClusterRoles()
-	if err != nil {
-		return nil, err
-	}
 	permissions.populateClusterRoles(clusterRoles)
-
-	bindings, err := client.ListRoleBindings(v1.NamespaceAll)
-	if err != nil {
-		return nil, err
-	}
 	permissions.populateRoleBindings(bindings)
-
-	clusterBindings, err := client.ListClusterRoleBindings()
-	if err != nil {
-		return nil, err
-	}
 	permissions.populateClusterRoleBindings(clusterBindings)
 
 	return permissions, nil
@@ -135,6 +263,7 @@ func NewPermissionsFromResourceList(objs []runtime.Object) (*Permissions, error)
 	permissions.ServiceAccounts = make(map[string]map[string]v1.ServiceAccount)
 	permissions.Roles = make(map[string]map[string]rbacv1.Role)
 	permissions.RoleBindings = make(map[string]map[string]rbacv1.RoleBinding)
+	permissions.AggregatedRules = make(map[string][]PolicyRule)
 
 	sas := []v1.ServiceAccount{}
 	roles := []rbacv1.Role{}
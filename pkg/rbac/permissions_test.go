@@ -0,0 +1,92 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/alcideio/rbac-tool/pkg/utils"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+)
+
+func Test__AggregatedClusterRole(t *testing.T) {
+	defer klog.Flush()
+
+	objs, err := utils.ReadObjectsFromFile("../../testdata/whocan/clusterrole-aggregate.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read resources - %v", err)
+	}
+
+	perms, err := NewPermissionsFromResourceList(objs)
+	if err != nil {
+		t.Fatalf("Failed to create permissions - %v", err)
+	}
+
+	if len(perms.Roles[""]["test-aggregate"].Rules) != 2 {
+		t.Fatalf("Expecting 2 aggregated rules, got %v", len(perms.Roles[""]["test-aggregate"].Rules))
+	}
+
+	policies := NewSubjectPermissionsList(NewSubjectPermissions(perms))
+	if len(policies) != 3 {
+		t.Fatalf("Expecting 3 subjects, got %v", len(policies))
+	}
+
+	for _, policy := range policies {
+		verbs := sets.NewString()
+		for _, rule := range policy.AllowedTo {
+			verbs.Insert(rule.Verb)
+
+			if len(rule.OriginatedFrom) != 2 || rule.OriginatedFrom[0].Name != "test-aggregate" {
+				t.Fatalf("%v - unexpected rule origin %+v", policy.Name, rule.OriginatedFrom)
+			}
+		}
+
+		if !verbs.HasAll("get", "list", "watch", "create") {
+			t.Fatalf("%v - missing aggregated verbs %v", policy.Name, verbs.List())
+		}
+	}
+}
+
+func Test__ImplicitGroups(t *testing.T) {
+	defer klog.Flush()
+
+	objs := []runtime.Object{
+		&v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "test-sa", Namespace: "test"}},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret-reader"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "all-sa-secret-reader", Namespace: "test"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "system:serviceaccounts:test"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "secret-reader"},
+		},
+	}
+
+	perms, err := NewPermissionsFromResourceList(objs)
+	if err != nil {
+		t.Fatalf("Failed to create permissions - %v", err)
+	}
+
+	if policies := NewSubjectPermissions(perms); len(policies) != 1 {
+		t.Fatalf("Expecting only the group subject, got %v", len(policies))
+	}
+
+	policies := NewSubjectPermissionsList(NewEffectiveSubjectPermissions(perms))
+	if len(policies) != 2 {
+		t.Fatalf("Expecting group and service account subjects, got %v", len(policies))
+	}
+
+	for _, policy := range policies {
+		if policy.Kind != rbacv1.ServiceAccountKind {
+			continue
+		}
+
+		if len(policy.AllowedTo) != 1 || policy.AllowedTo[0].Resource != "secrets" || policy.AllowedTo[0].InheritedFrom != "system:serviceaccounts:test" {
+			t.Fatalf("Unexpected inherited rules %+v", policy.AllowedTo)
+		}
+	}
+}
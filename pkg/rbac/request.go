@@ -0,0 +1,77 @@
+package rbac
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	"github.com/alcideio/rbac-tool/pkg/kube"
+)
+
+// NewRequestAttributes parses the <VERB> ( KIND | KIND/SUBRESOURCE | KIND/NAME | KIND/SUBRESOURCE/NAME | NON-RESOURCE-URL ) arguments
+// into API request attributes. KIND/X is a subresource when the discovery data has it - otherwise X is a resource name.
+// KIND/X is rejected when the discovery data has no subresources at all (e.g. a discovery snapshot of the preferred resources).
+func NewRequestAttributes(client *kube.KubeClient, verb string, target string, namespace string) (authorizer.AttributesRecord, error) {
+	attrs := authorizer.AttributesRecord{
+		Verb: strings.ToLower(verb),
+	}
+
+	if strings.HasPrefix(target, "/") {
+		attrs.Path = target
+		return attrs, nil
+	}
+
+	kind, subResource, name := target, "", ""
+
+	parts := strings.Split(target, "/")
+	switch len(parts) {
+	case 1:
+	case 2:
+		kind = parts[0]
+		switch {
+		case client.IsSubresource(parts[0], parts[1]):
+			subResource = parts[1]
+		case !client.HasSubresources():
+			return attrs, fmt.Errorf("Failed to parse '%v' - the discovery data has no subresources to tell KIND/SUBRESOURCE from KIND/NAME", target)
+		default:
+			name = parts[1]
+		}
+	case 3:
+		kind, subResource, name = parts[0], parts[1], parts[2]
+	default:
+		return attrs, fmt.Errorf("Failed to parse '%v' - expecting KIND, KIND/NAME, KIND/SUBRESOURCE or KIND/SUBRESOURCE/NAME", target)
+	}
+
+	gr, err := client.Resolve(attrs.Verb, kind, subResource)
+	if err != nil {
+		return attrs, err
+	}
+
+	attrs.ResourceRequest = true
+	attrs.APIGroup = gr.Group
+	attrs.Resource = gr.Resource
+	attrs.Subresource = strings.ToLower(subResource)
+	attrs.Name = name
+
+	if client.IsNamespaced(schema.GroupResource{Group: gr.Group, Resource: gr.Resource}) {
+		attrs.Namespace = namespace
+	}
+
+	return attrs, nil
+}
+
+// RequestNamespaces returns the namespaces a request is evaluated in - "" evaluates the cluster-wide grants.
+// Requests for namespaced resources without a namespace are evaluated in every namespace.
+func RequestNamespaces(client *kube.KubeClient, authz *Authorizer, attrs authorizer.AttributesRecord) []string {
+	if !attrs.ResourceRequest || !client.IsNamespaced(schema.GroupResource{Group: attrs.APIGroup, Resource: attrs.Resource}) {
+		return []string{""}
+	}
+
+	if attrs.Namespace != "" {
+		return []string{attrs.Namespace}
+	}
+
+	return append([]string{""}, authz.Namespaces()...)
+}
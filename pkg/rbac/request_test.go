@@ -0,0 +1,67 @@
+package rbac
+
+import (
+	"testing"
+
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/kube"
+)
+
+func Test__NewRequestAttributes(t *testing.T) {
+	defer klog.Flush()
+
+	client := kube.NewOfflineClient(nil, nil)
+
+	tests := []struct {
+		verb        string
+		target      string
+		group       string
+		resource    string
+		subresource string
+		name        string
+		namespace   string
+		path        string
+	}{
+		{verb: "get", target: "pods", resource: "pods", namespace: "payments"},
+		{verb: "create", target: "pods/exec", resource: "pods", subresource: "exec", namespace: "payments"},
+		{verb: "get", target: "secret/some-secret", resource: "secrets", name: "some-secret", namespace: "payments"},
+		{verb: "create", target: "po/exec/api-0", resource: "pods", subresource: "exec", name: "api-0", namespace: "payments"},
+		{verb: "list", target: "deployments", group: "apps", resource: "deployments", namespace: "payments"},
+		{verb: "get", target: "nodes/node-1", resource: "nodes", name: "node-1"},
+		{verb: "get", target: "/healthz", path: "/healthz"},
+	}
+
+	for _, tc := range tests {
+		attrs, err := NewRequestAttributes(client, tc.verb, tc.target, "payments")
+		if err != nil {
+			t.Fatalf("Failed to parse '%v %v' - %v", tc.verb, tc.target, err)
+		}
+
+		if attrs.APIGroup != tc.group || attrs.Resource != tc.resource || attrs.Subresource != tc.subresource ||
+			attrs.Name != tc.name || attrs.Namespace != tc.namespace || attrs.Path != tc.path || attrs.ResourceRequest != (tc.path == "") {
+			t.Fatalf("Unexpected attributes of '%v %v' - %+v", tc.verb, tc.target, attrs)
+		}
+	}
+
+	if _, err := NewRequestAttributes(client, "get", "pods/exec/api-0/extra", "payments"); err == nil {
+		t.Fatalf("Expecting an error parsing too many parts")
+	}
+
+	// A discovery snapshot without subresources can't tell KIND/SUBRESOURCE from KIND/NAME
+	preferred := client.ServerPreferredResources
+	noSubresources := kube.NewOfflineClient(preferred, nil)
+	for _, target := range []string{"pods/exec", "secret/some-secret"} {
+		if _, err := NewRequestAttributes(noSubresources, "get", target, "payments"); err == nil {
+			t.Fatalf("Expecting an error parsing '%v' without subresources", target)
+		}
+	}
+
+	if _, err := NewRequestAttributes(noSubresources, "create", "pods/exec/api-0", "payments"); err == nil {
+		t.Fatalf("Expecting an error resolving an unknown subresource")
+	}
+
+	if _, err := NewRequestAttributes(noSubresources, "get", "secrets", "payments"); err != nil {
+		t.Fatalf("Failed to parse 'get secrets' without subresources - %v", err)
+	}
+}
@@ -53,3 +53,49 @@ func (r *StaticRoles) ListRoleBindings(namespace string) ([]*rbacv1.RoleBinding,
 func (r *StaticRoles) ListClusterRoleBindings() ([]*rbacv1.ClusterRoleBinding, error) {
 	return r.clusterRoleBindings, nil
 }
+
+// NewStaticRoles creates a StaticRoles resolver from the permissions model.
+// ClusterRoles carry their effective (aggregated) rules.
+func NewStaticRoles(perms *Permissions) *StaticRoles {
+	r := &StaticRoles{
+		roles:               []*rbacv1.Role{},
+		roleBindings:        []*rbacv1.RoleBinding{},
+		clusterRoles:        []*rbacv1.ClusterRole{},
+		clusterRoleBindings: []*rbacv1.ClusterRoleBinding{},
+	}
+
+	for namespace, roles := range perms.Roles {
+		for _, role := range roles {
+			role := role
+
+			if namespace != "" {
+				r.roles = append(r.roles, &role)
+				continue
+			}
+
+			r.clusterRoles = append(r.clusterRoles, &rbacv1.ClusterRole{
+				ObjectMeta: role.ObjectMeta,
+				Rules:      role.Rules,
+			})
+		}
+	}
+
+	for namespace, bindings := range perms.RoleBindings {
+		for _, binding := range bindings {
+			binding := binding
+
+			if namespace != "" {
+				r.roleBindings = append(r.roleBindings, &binding)
+				continue
+			}
+
+			r.clusterRoleBindings = append(r.clusterRoleBindings, &rbacv1.ClusterRoleBinding{
+				ObjectMeta: binding.ObjectMeta,
+				Subjects:   binding.Subjects,
+				RoleRef:    binding.RoleRef,
+			})
+		}
+	}
+
+	return r
+}
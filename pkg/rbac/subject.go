@@ -0,0 +1,46 @@
+package rbac
+
+import (
+	"fmt"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+)
+
+// ParseSubject parses a subject denoted as sa:<namespace>/<name>, user:<name> or group:<name>.
+// A ServiceAccount username (system:serviceaccount:<namespace>:<name>) is accepted as well.
+func ParseSubject(s string) (rbacv1.Subject, error) {
+	if namespace, name, err := serviceaccount.SplitUsername(s); err == nil {
+		return rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: namespace, Name: name}, nil
+	}
+
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return rbacv1.Subject{}, fmt.Errorf("Failed to parse subject '%v' - expecting sa:<namespace>/<name>, user:<name> or group:<name>", s)
+	}
+
+	switch strings.ToLower(parts[0]) {
+	case "sa", "serviceaccount":
+		nsName := strings.SplitN(parts[1], "/", 2)
+		if len(nsName) != 2 || nsName[0] == "" || nsName[1] == "" {
+			return rbacv1.Subject{}, fmt.Errorf("Failed to parse subject '%v' - expecting sa:<namespace>/<name>", s)
+		}
+		return rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: nsName[0], Name: nsName[1]}, nil
+	case "user", "u":
+		return rbacv1.Subject{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: parts[1]}, nil
+	case "group", "g":
+		return rbacv1.Subject{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: parts[1]}, nil
+	default:
+		return rbacv1.Subject{}, fmt.Errorf("Failed to parse subject '%v' - unknown subject kind '%v'", s, parts[0])
+	}
+}
+
+// SubjectName returns the subject name - <namespace>/<name> for ServiceAccounts
+func SubjectName(s rbacv1.Subject) string {
+	if s.Kind == rbacv1.ServiceAccountKind {
+		return fmt.Sprintf("%v/%v", s.Namespace, s.Name)
+	}
+
+	return s.Name
+}
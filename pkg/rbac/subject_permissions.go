@@ -1,11 +1,14 @@
 package rbac
 
 import (
+	"fmt"
 	"sort"
 	"strings"
 
 	"github.com/kylelemons/godebug/pretty"
 	v1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/klog"
 )
 
@@ -14,6 +17,36 @@ type PolicyRule struct {
 
 	//Specify the Roles or ClusterRoles this rule originated from
 	OriginatedFrom []v1.RoleRef
+
+	//The implicit group (e.g. system:serviceaccounts) this rule was inherited from - empty when bound directly
+	InheritedFrom string
+
+	//The RoleBinding or ClusterRoleBinding that granted this rule
+	GrantedBy BindingRef
+}
+
+// BindingRef references a RoleBinding or a ClusterRoleBinding
+type BindingRef struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func (b BindingRef) String() string {
+	if b.Namespace == "" {
+		return fmt.Sprintf("%v>>%v", b.Kind, b.Name)
+	}
+
+	return fmt.Sprintf("%v>>%v/%v", b.Kind, b.Namespace, b.Name)
+}
+
+func newBindingRef(binding v1.RoleBinding) BindingRef {
+	//ClusterRoleBindings are captured as RoleBindings in namespace ""
+	if binding.Namespace == "" {
+		return BindingRef{Kind: "ClusterRoleBinding", Name: binding.Name}
+	}
+
+	return BindingRef{Kind: "RoleBinding", Namespace: binding.Namespace, Name: binding.Name}
 }
 
 type SubjectPermissions struct {
@@ -75,6 +108,14 @@ func NewSubjectPermissions(perms *Permissions) []SubjectPermissions {
 				for i, _ := range role.Rules {
 					roleRules[i].PolicyRule = role.Rules[i]
 					roleRules[i].OriginatedFrom = []v1.RoleRef{binding.RoleRef}
+					roleRules[i].GrantedBy = newBindingRef(binding)
+				}
+
+				//Aggregated ClusterRoles - track the ClusterRole each rule was aggregated from
+				if aggregated, exist := perms.AggregatedRules[binding.RoleRef.Name]; exist && ns == "" {
+					for i := range aggregated {
+						roleRules[i].OriginatedFrom = append(roleRules[i].OriginatedFrom, aggregated[i].OriginatedFrom...)
+					}
 				}
 
 				klog.V(6).Infof("[%v] %+v -- UPDATE -- %v %v %+v", subject.String(), subject, len(rules), len(role.Rules), roleRules)
@@ -96,6 +137,83 @@ func NewSubjectPermissions(perms *Permissions) []SubjectPermissions {
 	return res
 }
 
+// ServiceAccountGroups returns the groups the API server implicitly adds to every ServiceAccount in the namespace
+func ServiceAccountGroups(namespace string) []string {
+	return []string{
+		serviceaccount.AllServiceAccountsGroup,
+		serviceaccount.MakeNamespaceGroupName(namespace),
+		user.AllAuthenticated,
+	}
+}
+
+// NewEffectiveSubjectPermissions returns the subject permissions where each ServiceAccount is also credited
+// with the rules it inherits from its implicit groups (system:serviceaccounts, system:serviceaccounts:<namespace> and system:authenticated).
+// Inherited rules are marked with the group they came from.
+func NewEffectiveSubjectPermissions(perms *Permissions) []SubjectPermissions {
+	subjectPermissions := NewSubjectPermissions(perms)
+
+	//The same group may be referenced with and without an API group
+	groups := map[string][]*SubjectPermissions{}
+	serviceAccounts := map[string]*SubjectPermissions{}
+
+	for i := range subjectPermissions {
+		p := &subjectPermissions[i]
+		switch p.Subject.Kind {
+		case v1.GroupKind:
+			groups[p.Subject.Name] = append(groups[p.Subject.Name], p)
+		case v1.ServiceAccountKind:
+			serviceAccounts[p.Subject.Namespace+"/"+p.Subject.Name] = p
+		}
+	}
+
+	//ServiceAccounts with no bindings of their own may still inherit permissions
+	added := []SubjectPermissions{}
+	for namespace, sas := range perms.ServiceAccounts {
+		for name := range sas {
+			if _, exist := serviceAccounts[namespace+"/"+name]; exist {
+				continue
+			}
+
+			added = append(added, SubjectPermissions{
+				Subject: v1.Subject{Kind: v1.ServiceAccountKind, Name: name, Namespace: namespace},
+				Rules:   map[string][]PolicyRule{},
+			})
+		}
+	}
+
+	subjectPermissions = append(subjectPermissions, added...)
+
+	res := []SubjectPermissions{}
+	for _, p := range subjectPermissions {
+		if p.Subject.Kind != v1.ServiceAccountKind {
+			res = append(res, p)
+			continue
+		}
+
+		for _, group := range ServiceAccountGroups(p.Subject.Namespace) {
+			for _, groupPerms := range groups[group] {
+				klog.V(6).Infof("[%v/%v] inherit permissions from group '%v'", p.Subject.Namespace, p.Subject.Name, group)
+
+				for namespace, rules := range groupPerms.Rules {
+					for _, rule := range rules {
+						rule.InheritedFrom = group
+						p.Rules[namespace] = append(p.Rules[namespace], rule)
+					}
+				}
+			}
+		}
+
+		//Skip ServiceAccounts that ended up with no permissions at all
+		if len(p.Rules) == 0 {
+			continue
+		}
+
+		res = append(res, p)
+	}
+
+	return res
+}
+
 func ReplaceToWildCard(l []string) {
 	for i, _ := range l {
 		if l[i] == "" {
@@ -133,6 +251,12 @@ type NamespacedPolicyRule struct {
 
 	//The Role/ClusterRole rule references
 	OriginatedFrom []v1.RoleRef `json:"originatedFrom,omitempty"`
+
+	//The implicit group this rule was inherited from (e.g. system:serviceaccounts)
+	InheritedFrom string `json:"inheritedFrom,omitempty"`
+
+	//The RoleBinding/ClusterRoleBinding that granted the rule
+	GrantedBy BindingRef `json:"grantedBy"`
 }
 
 type SubjectPolicyList struct {
@@ -152,6 +276,9 @@ func NewSubjectPermissionsList(policies []SubjectPermissions) []SubjectPolicyLis
 			}
 
 			for _, rule := range rules {
+				//Rules are shared with the Permissions model - normalize a copy
+				rule.PolicyRule = *rule.PolicyRule.DeepCopy()
+
 				//Normalize the strings
 				ReplaceToCore(rule.APIGroups)
 				ReplaceToWildCard(rule.Resources)
@@ -179,6 +306,8 @@ func NewSubjectPermissionsList(policies []SubjectPermissions) []SubjectPolicyLis
 									ResourceNames:   rule.ResourceNames,
 									NonResourceURLs: rule.NonResourceURLs,
 									OriginatedFrom:  rule.OriginatedFrom,
+									InheritedFrom:   rule.InheritedFrom,
+									GrantedBy:       rule.GrantedBy,
 								}
 
 								nsrules = append(nsrules, subjectPolicy)
@@ -192,6 +321,8 @@ func NewSubjectPermissionsList(policies []SubjectPermissions) []SubjectPolicyLis
 							Verb:            verb,
 							NonResourceURLs: rule.NonResourceURLs,
 							OriginatedFrom:  rule.OriginatedFrom,
+							InheritedFrom:   rule.InheritedFrom,
+							GrantedBy:       rule.GrantedBy,
 						}
 
 						nsrules = append(nsrules, subjectPolicy)
@@ -0,0 +1,36 @@
+package rbac
+
+import (
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func Test__ParseSubject(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected rbacv1.Subject
+		fail     bool
+	}{
+		{in: "sa:payments/deployer", expected: rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: "payments", Name: "deployer"}},
+		{in: "system:serviceaccount:payments:deployer", expected: rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: "payments", Name: "deployer"}},
+		{in: "user:alice", expected: rbacv1.Subject{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "alice"}},
+		{in: "group:system:masters", expected: rbacv1.Subject{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "system:masters"}},
+		{in: "sa:deployer", fail: true},
+		{in: "alice", fail: true},
+	}
+
+	for _, test := range tests {
+		subject, err := ParseSubject(test.in)
+		if test.fail {
+			if err == nil {
+				t.Fatalf("'%v' - expecting failure, got %+v", test.in, subject)
+			}
+			continue
+		}
+
+		if err != nil || subject != test.expected {
+			t.Fatalf("'%v' - expecting %+v, got %+v (%v)", test.in, test.expected, subject, err)
+		}
+	}
+}
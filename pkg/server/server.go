@@ -0,0 +1,246 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/analysis"
+	"github.com/alcideio/rbac-tool/pkg/kube"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+// Health is the response of the health endpoint
+type Health struct {
+	Status string `json:"status"`
+
+	//The last time the Permissions model was updated
+	Updated metav1.Time `json:"updated"`
+
+	Subjects int `json:"subjects"`
+}
+
+// Server exposes the RBAC queries as JSON endpoints:
+//
+//	GET /healthz
+//	GET /api/v1/who-can?verb=<verb>&resource=<kind|kind/name|kind/subresource|url>[&namespace=<ns>][&implicitGroups=false]
+//	GET /api/v1/policy-rules[?regex=<regex>][&not=true][&implicitGroups=false]
+//	GET /api/v1/lookup[?regex=<regex>][&not=true]
+//	GET /api/v1/analysis
+type Server struct {
+	//Resolves resource kinds/shortcuts of who-can requests
+	client *kube.KubeClient
+
+	analysisConfig *analysis.AnalysisConfig
+
+	mu        sync.RWMutex
+	perms     *rbac.Permissions
+	report    *analysis.AnalysisReport
+	reportErr error
+	updated   metav1.Time
+}
+
+// NewServer creates a server of the permissions - the analysis rules are evaluated with analysisConfig
+func NewServer(client *kube.KubeClient, perms *rbac.Permissions, analysisConfig *analysis.AnalysisConfig) *Server {
+	s := &Server{
+		client:         client,
+		analysisConfig: analysisConfig,
+	}
+
+	s.Update(perms)
+
+	return s
+}
+
+// Update replaces the Permissions model the queries are evaluated against and re-runs the analysis
+func (s *Server) Update(perms *rbac.Permissions) {
+	var report *analysis.AnalysisReport
+	var err error
+
+	policies := rbac.NewSubjectPermissionsList(rbac.NewEffectiveSubjectPermissions(perms))
+	analyzer := analysis.CreateAnalyzer(s.analysisConfig, policies)
+	if analyzer == nil {
+		err = fmt.Errorf("Failed to create analyzer")
+	} else {
+		report, err = analyzer.Analyze()
+	}
+
+	if err != nil {
+		klog.Errorf("Failed to analyze permissions - %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.perms = perms
+	s.report = report
+	s.reportErr = err
+	s.updated = metav1.NewTime(time.Now().UTC())
+}
+
+func (s *Server) permissions() *rbac.Permissions {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.perms
+}
+
+// Handler returns the HTTP handler of the endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /healthz", s.health)
+	mux.HandleFunc("GET /api/v1/who-can", s.whoCan)
+	mux.HandleFunc("GET /api/v1/policy-rules", s.policyRules)
+	mux.HandleFunc("GET /api/v1/lookup", s.lookup)
+	mux.HandleFunc("GET /api/v1/analysis", s.analysis)
+
+	return mux
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	health := Health{
+		Status:   "ok",
+		Updated:  s.updated,
+		Subjects: len(rbac.Subjects(s.perms)),
+	}
+	s.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, &health)
+}
+
+func (s *Server) whoCan(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	verb, resource := query.Get("verb"), query.Get("resource")
+	if verb == "" || resource == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("'verb' and 'resource' are required"))
+		return
+	}
+
+	implicitGroups, err := boolParam(r, "implicitGroups", true)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	attrs, err := rbac.NewRequestAttributes(s.client, verb, resource, query.Get("namespace"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	perms := s.permissions()
+	authz := rbac.NewAuthorizer(perms)
+	namespaces := rbac.RequestNamespaces(s.client, authz, attrs)
+
+	writeJSON(w, http.StatusOK, authz.WhoCan(perms, attrs, namespaces, implicitGroups))
+}
+
+func (s *Server) policyRules(w http.ResponseWriter, r *http.Request) {
+	re, inverse, err := subjectFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	implicitGroups, err := boolParam(r, "implicitGroups", true)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var policies []rbac.SubjectPermissions
+	if implicitGroups {
+		policies = rbac.NewEffectiveSubjectPermissions(s.permissions())
+	} else {
+		policies = rbac.NewSubjectPermissions(s.permissions())
+	}
+
+	writeJSON(w, http.StatusOK, rbac.NewSubjectPermissionsList(rbac.FilterSubjectPermissions(policies, re, inverse)))
+}
+
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) {
+	re, inverse, err := subjectFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rbac.Lookup(s.permissions(), re, inverse))
+}
+
+func (s *Server) analysis(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	report, err := s.report, s.reportErr
+	s.mu.RUnlock()
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// subjectFilter parses the 'regex' (default: all subjects) and 'not' query parameters
+func subjectFilter(r *http.Request) (*regexp.Regexp, bool, error) {
+	expr := r.URL.Query().Get("regex")
+	if expr == "" {
+		expr = ".*"
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, false, fmt.Errorf("Failed to parse 'regex' - %v", err)
+	}
+
+	inverse, err := boolParam(r, "not", false)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return re, inverse, nil
+}
+
+func boolParam(r *http.Request, name string, defaultValue bool) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("Failed to parse '%v' - %v", name, err)
+	}
+
+	return b, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("Processing error - %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
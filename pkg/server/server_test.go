@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/analysis"
+	"github.com/alcideio/rbac-tool/pkg/kube"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+	"github.com/alcideio/rbac-tool/pkg/utils"
+)
+
+func Test__Server(t *testing.T) {
+	defer klog.Flush()
+
+	objs, err := utils.ReadObjectsFromFile("../../testdata/whocan")
+	if err != nil {
+		t.Fatalf("Failed to read resources - %v", err)
+	}
+
+	perms, err := rbac.NewPermissionsFromResourceList(objs)
+	if err != nil {
+		t.Fatalf("Failed to create permissions - %v", err)
+	}
+
+	srv := httptest.NewServer(NewServer(kube.NewOfflineClient(nil, nil), perms, analysis.DefaultAnalysisConfig()).Handler())
+	defer srv.Close()
+
+	get := func(path string, expectedCode int, v interface{}) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("Failed to get '%v' - %v", path, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != expectedCode {
+			t.Fatalf("Expecting status %v of '%v' got %v", expectedCode, path, resp.StatusCode)
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("Failed to decode '%v' - %v", path, err)
+		}
+	}
+
+	health := Health{}
+	get("/healthz", http.StatusOK, &health)
+	if health.Status != "ok" || health.Subjects == 0 {
+		t.Fatalf("Unexpected health %+v", health)
+	}
+
+	subjects := []rbac.SubjectGrants{}
+	get("/api/v1/who-can?verb=get&resource=secrets&namespace=test", http.StatusOK, &subjects)
+	names := map[string]bool{}
+	for _, s := range subjects {
+		names[s.Name] = true
+	}
+	if !names["test-secret-reader"] || !names["test-secret-reader-sa"] {
+		t.Fatalf("Expecting the secret readers in %+v", subjects)
+	}
+
+	failure := map[string]string{}
+	get("/api/v1/who-can?verb=get", http.StatusBadRequest, &failure)
+	if failure["error"] == "" {
+		t.Fatalf("Expecting an error message")
+	}
+
+	policies := []rbac.SubjectPolicyList{}
+	get("/api/v1/policy-rules?regex=^test-secret-reader$&implicitGroups=false", http.StatusOK, &policies)
+	if len(policies) != 1 || len(policies[0].AllowedTo) != 3 {
+		t.Fatalf("Unexpected policy rules %+v", policies)
+	}
+
+	bindings := []rbac.SubjectBinding{}
+	get("/api/v1/lookup?regex=^test-secret-reader-sa$", http.StatusOK, &bindings)
+	if len(bindings) != 1 || bindings[0].Binding != "read-secrets" || bindings[0].Namespace != "test" {
+		t.Fatalf("Unexpected lookup %+v", bindings)
+	}
+
+	report := analysis.AnalysisReport{}
+	get("/api/v1/analysis", http.StatusOK, &report)
+	if report.Stats.RuleCount == 0 {
+		t.Fatalf("Expecting the analysis report")
+	}
+}
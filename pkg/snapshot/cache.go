@@ -0,0 +1,48 @@
+package snapshot
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// CachePath returns the path of the cached snapshot of a cluster context in the user cache directory
+func CachePath(clusterContext string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "rbac-tool", url.PathEscape(clusterContext)+".tar.gz"), nil
+}
+
+// LoadCache loads the cached snapshot when it was captured within the TTL - nil when it is missing or expired
+func LoadCache(fname string, ttl time.Duration) (*Snapshot, error) {
+	if _, err := os.Stat(fname); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	s, err := Load(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	if age := time.Since(s.Metadata.CreatedAt.Time); age > ttl {
+		klog.V(3).Infof("Cached snapshot '%v' expired %v ago", fname, age-ttl)
+		return nil, nil
+	}
+
+	return s, nil
+}
+
+// SaveCache saves the snapshot to the cache path - creating the cache directory when needed
+func (s *Snapshot) SaveCache(fname string) error {
+	if err := os.MkdirAll(filepath.Dir(fname), 0700); err != nil {
+		return err
+	}
+
+	return s.Save(fname)
+}
@@ -0,0 +1,58 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/kube"
+)
+
+func Test__SnapshotCache(t *testing.T) {
+	defer klog.Flush()
+
+	fname := filepath.Join(t.TempDir(), "rbac-tool", "test.tar.gz")
+
+	if s, err := LoadCache(fname, time.Minute); s != nil || err != nil {
+		t.Fatalf("Expecting no cached snapshot got %v (%v)", s, err)
+	}
+
+	s := &Snapshot{
+		Metadata:                 Metadata{FormatVersion: FormatVersion, CreatedAt: metav1.NewTime(time.Now().Add(-2 * time.Minute)), ClusterContext: "test"},
+		ServerPreferredResources: kube.DefaultDiscovery(),
+	}
+
+	if err := s.SaveCache(fname); err != nil {
+		t.Fatalf("Failed to save the cached snapshot - %v", err)
+	}
+
+	if cached, err := LoadCache(fname, 5*time.Minute); cached == nil || err != nil || cached.Metadata.ClusterContext != "test" {
+		t.Fatalf("Expecting the cached snapshot got %v (%v)", cached, err)
+	}
+
+	if cached, err := LoadCache(fname, time.Minute); cached != nil || err != nil {
+		t.Fatalf("Expecting the cached snapshot to expire got %v (%v)", cached, err)
+	}
+
+	//Refreshing the cache replaces the archive - without leaving temporary files behind
+	s.Metadata.CreatedAt = metav1.Now()
+	if err := s.SaveCache(fname); err != nil {
+		t.Fatalf("Failed to refresh the cached snapshot - %v", err)
+	}
+
+	if cached, err := LoadCache(fname, time.Minute); cached == nil || err != nil {
+		t.Fatalf("Expecting the refreshed cached snapshot got %v (%v)", cached, err)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(fname))
+	if err != nil {
+		t.Fatalf("Failed to read the cache directory - %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != filepath.Base(fname) {
+		t.Fatalf("Expecting only the cached snapshot in the cache directory got %v", entries)
+	}
+}
@@ -0,0 +1,414 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/kube"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+	"github.com/alcideio/rbac-tool/pkg/utils"
+)
+
+// FormatVersion is the version of the snapshot archive layout
+const FormatVersion = "v1"
+
+// Archive entries
+const (
+	metadataEntry                 = "metadata.json"
+	versionEntry                  = "version.json"
+	serverPreferredResourcesEntry = "discovery/server-preferred-resources.json"
+	serverResourcesEntry          = "discovery/server-resources.json"
+	resourcesEntry                = "resources.json"
+)
+
+type Metadata struct {
+	//Snapshot archive format version
+	FormatVersion string `json:"formatVersion"`
+
+	CreatedAt      metav1.Time `json:"createdAt"`
+	ClusterContext string      `json:"clusterContext,omitempty"`
+	Server         string      `json:"server,omitempty"`
+
+	//rbac-tool version that captured the snapshot
+	ToolVersion string `json:"toolVersion,omitempty"`
+}
+
+// Snapshot is everything rbac-tool reads from a cluster captured into a single portable archive
+type Snapshot struct {
+	Metadata Metadata
+
+	ServerVersion *version.Info
+
+	//ServerPreferredResources discovery
+	ServerPreferredResources []*metav1.APIResourceList
+
+	//All the resources (all versions) served by the API server
+	ServerResources []*metav1.APIResourceList
+
+	//ServiceAccounts, Roles, ClusterRoles, RoleBindings, ClusterRoleBindings, Pods, ReplicaSets, Jobs & Namespaces (RBAC only - see CaptureRBAC)
+	Objects []runtime.Object
+}
+
+// Capture reads the cluster resources into a snapshot
+func Capture(client *kube.KubeClient, clusterContext string) (*Snapshot, error) {
+	return capture(client, clusterContext, true)
+}
+
+// CaptureRBAC reads the discovery data, ServiceAccounts, Roles, ClusterRoles, RoleBindings and ClusterRoleBindings into a snapshot -
+// without the Pods, ReplicaSets, Jobs and Namespaces
+func CaptureRBAC(client *kube.KubeClient, clusterContext string) (*Snapshot, error) {
+	return capture(client, clusterContext, false)
+}
+
+func capture(client *kube.KubeClient, clusterContext string, withWorkloads bool) (*Snapshot, error) {
+	s := &Snapshot{
+		Metadata: Metadata{
+			FormatVersion:  FormatVersion,
+			CreatedAt:      metav1.NewTime(time.Now().UTC()),
+			ClusterContext: clusterContext,
+		},
+		ServerVersion:            client.ServerVersion(),
+		ServerPreferredResources: client.ServerPreferredResources,
+		ServerResources:          client.AllServerResources(),
+		Objects:                  []runtime.Object{},
+	}
+
+	if client.Config != nil {
+		s.Metadata.Server = client.Config.Host
+	}
+
+	var sas []v1.ServiceAccount
+	var roles []rbacv1.Role
+	var clusterRoles []rbacv1.ClusterRole
+	var bindings []rbacv1.RoleBinding
+	var clusterBindings []rbacv1.ClusterRoleBinding
+	var pods []v1.Pod
+	var replicaSets []appsv1.ReplicaSet
+	var jobs []batchv1.Job
+	var namespaces []v1.Namespace
+
+	listErr := func(resource string, err error) error {
+		if err != nil {
+			return fmt.Errorf("Failed to list %v - %v", resource, err)
+		}
+		return nil
+	}
+
+	listers := []func() error{
+		func() (err error) {
+			sas, err = client.ListServiceAccounts(v1.NamespaceAll)
+			return listErr("ServiceAccounts", err)
+		},
+		func() (err error) {
+			roles, err = client.ListRoles(v1.NamespaceAll)
+			return listErr("Roles", err)
+		},
+		func() (err error) {
+			clusterRoles, err = client.ListClusterRoles()
+			return listErr("ClusterRoles", err)
+		},
+		func() (err error) {
+			bindings, err = client.ListRoleBindings(v1.NamespaceAll)
+			return listErr("RoleBindings", err)
+		},
+		func() (err error) {
+			clusterBindings, err = client.ListClusterRoleBindings()
+			return listErr("ClusterRoleBindings", err)
+		},
+	}
+
+	if withWorkloads {
+		listers = append(listers,
+			func() (err error) {
+				pods, err = client.ListPods(v1.NamespaceAll)
+				return listErr("Pods", err)
+			},
+			func() (err error) {
+				replicaSets, err = client.ListReplicaSets(v1.NamespaceAll)
+				return listErr("ReplicaSets", err)
+			},
+			func() (err error) {
+				jobs, err = client.ListJobs(v1.NamespaceAll)
+				return listErr("Jobs", err)
+			},
+			func() (err error) {
+				namespaces, err = client.ListNamespaces()
+				return listErr("Namespaces", err)
+			},
+		)
+	}
+
+	if err := utils.RunConcurrently(listers...); err != nil {
+		return nil, err
+	}
+
+	for i := range sas {
+		s.add(&sas[i])
+	}
+	for i := range roles {
+		s.add(&roles[i])
+	}
+	for i := range clusterRoles {
+		s.add(&clusterRoles[i])
+	}
+	for i := range bindings {
+		s.add(&bindings[i])
+	}
+	for i := range clusterBindings {
+		s.add(&clusterBindings[i])
+	}
+	for i := range pods {
+		s.add(&pods[i])
+	}
+	for i := range replicaSets {
+		s.add(&replicaSets[i])
+	}
+	for i := range jobs {
+		s.add(&jobs[i])
+	}
+	for i := range namespaces {
+		s.add(&namespaces[i])
+	}
+
+	return s, nil
+}
+
+func (s *Snapshot) add(obj runtime.Object) {
+	//Objects returned by List don't carry their kind
+	gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err == nil && len(gvks) > 0 {
+		obj.GetObjectKind().SetGroupVersionKind(gvks[0])
+	}
+
+	//Managed fields only inflate the archive
+	if o, ok := obj.(metav1.Object); ok {
+		o.SetManagedFields(nil)
+	}
+
+	s.Objects = append(s.Objects, obj)
+}
+
+// Client returns an offline client backed by the snapshot discovery data
+func (s *Snapshot) Client() *kube.KubeClient {
+	client := kube.NewOfflineClient(s.ServerPreferredResources, s.ServerVersion)
+	if len(s.ServerResources) > 0 {
+		client.ServerResources = s.ServerResources
+	}
+
+	return client
+}
+
+// Permissions builds the RBAC permissions model from the snapshot resources
+func (s *Snapshot) Permissions() (*rbac.Permissions, error) {
+	return rbac.NewPermissionsFromResourceList(s.Objects)
+}
+
+// Write the snapshot as a gzip compressed tar archive
+func (s *Snapshot) Write(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	items := make([]runtime.RawExtension, 0, len(s.Objects))
+	for _, obj := range s.Objects {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("Failed to encode %v - %v", obj.GetObjectKind().GroupVersionKind(), err)
+		}
+		items = append(items, runtime.RawExtension{Raw: data})
+	}
+
+	entries := []struct {
+		name string
+		v    interface{}
+	}{
+		{metadataEntry, s.Metadata},
+		{versionEntry, s.ServerVersion},
+		{serverPreferredResourcesEntry, s.ServerPreferredResources},
+		{serverResourcesEntry, s.ServerResources},
+		{resourcesEntry, &v1.List{TypeMeta: metav1.TypeMeta{Kind: "List", APIVersion: "v1"}, Items: items}},
+	}
+
+	for _, entry := range entries {
+		data, err := json.MarshalIndent(entry.v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Failed to encode %v - %v", entry.name, err)
+		}
+
+		hdr := &tar.Header{
+			Name:    entry.name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: s.Metadata.CreatedAt.Time,
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// Save writes the snapshot archive to a file.
+// The archive is written to a temporary file in the same directory and renamed into place,
+// so readers never see a partially written archive.
+func (s *Snapshot) Save(fname string) error {
+	f, err := os.CreateTemp(filepath.Dir(fname), "."+filepath.Base(fname)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := s.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), fname)
+}
+
+// Read a snapshot archive
+func Read(r io.Reader) (*Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read snapshot - %v", err)
+	}
+	defer gz.Close()
+
+	entries := map[string][]byte{}
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read snapshot - %v", err)
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read snapshot entry %v - %v", hdr.Name, err)
+		}
+
+		entries[hdr.Name] = data
+	}
+
+	s := &Snapshot{}
+
+	data, exist := entries[metadataEntry]
+	if !exist {
+		return nil, fmt.Errorf("Failed to read snapshot - missing %v", metadataEntry)
+	}
+
+	if err := json.Unmarshal(data, &s.Metadata); err != nil {
+		return nil, fmt.Errorf("Failed to decode %v - %v", metadataEntry, err)
+	}
+
+	if s.Metadata.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("Unsupported snapshot format version '%v' (expected '%v')", s.Metadata.FormatVersion, FormatVersion)
+	}
+
+	decode := []struct {
+		name string
+		v    interface{}
+	}{
+		{versionEntry, &s.ServerVersion},
+		{serverPreferredResourcesEntry, &s.ServerPreferredResources},
+		{serverResourcesEntry, &s.ServerResources},
+	}
+
+	for _, entry := range decode {
+		data, exist := entries[entry.name]
+		if !exist {
+			klog.V(5).Infof("Snapshot has no %v", entry.name)
+			continue
+		}
+
+		if err := json.Unmarshal(data, entry.v); err != nil {
+			return nil, fmt.Errorf("Failed to decode %v - %v", entry.name, err)
+		}
+	}
+
+	s.Objects = []runtime.Object{}
+	if data, exist := entries[resourcesEntry]; exist {
+		objs, err := utils.ReadObjectList(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode %v - %v", resourcesEntry, err)
+		}
+
+		s.Objects = objs
+	}
+
+	klog.V(5).Infof("Loaded snapshot (%v) of '%v' taken at %v with %v resources", s.Metadata.FormatVersion, s.Metadata.ClusterContext, s.Metadata.CreatedAt, len(s.Objects))
+
+	return s, nil
+}
+
+// Load a snapshot archive from a file
+func Load(fname string) (*Snapshot, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(bufio.NewReader(f))
+}
+
+// IsSnapshot reports whether the file looks like a snapshot archive (gzip compressed)
+func IsSnapshot(fname string) bool {
+	if fname == "" || fname == "-" {
+		return false
+	}
+
+	f, err := os.Open(fname)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	magic := make([]byte, 2)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false
+	}
+
+	return magic[0] == 0x1f && magic[1] == 0x8b
+}
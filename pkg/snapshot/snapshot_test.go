@@ -0,0 +1,71 @@
+package snapshot
+
+import (
+	"bytes"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/kube"
+	"github.com/alcideio/rbac-tool/pkg/utils"
+)
+
+func Test__SnapshotRoundTrip(t *testing.T) {
+	defer klog.Flush()
+
+	objs, err := utils.ReadObjectsFromFile("../../testdata/whocan")
+	if err != nil {
+		t.Fatalf("Failed to read resources - %v", err)
+	}
+
+	s := &Snapshot{
+		Metadata:                 Metadata{FormatVersion: FormatVersion, CreatedAt: metav1.Now(), ClusterContext: "test"},
+		ServerVersion:            &version.Info{GitVersion: "v1.26.0"},
+		ServerPreferredResources: kube.DefaultDiscovery(),
+	}
+
+	for _, obj := range objs {
+		s.add(obj)
+	}
+	s.add(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "test"},
+		Spec:       v1.PodSpec{ServiceAccountName: "test-sa"},
+	})
+
+	var buf bytes.Buffer
+	if err := s.Write(&buf); err != nil {
+		t.Fatalf("Failed to write snapshot - %v", err)
+	}
+
+	loaded, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot - %v", err)
+	}
+
+	if len(loaded.Objects) != len(s.Objects) {
+		t.Fatalf("Expecting %v resources, got %v", len(s.Objects), len(loaded.Objects))
+	}
+
+	if pod, ok := loaded.Objects[len(loaded.Objects)-1].(*v1.Pod); !ok || pod.Spec.ServiceAccountName != "test-sa" {
+		t.Fatalf("Expecting the pod to be restored, got %+v", loaded.Objects[len(loaded.Objects)-1])
+	}
+
+	if loaded.ServerVersion == nil || loaded.ServerVersion.GitVersion != "v1.26.0" {
+		t.Fatalf("Unexpected server version %+v", loaded.ServerVersion)
+	}
+
+	if len(loaded.ServerPreferredResources) != len(s.ServerPreferredResources) {
+		t.Fatalf("Expecting %v discovery groups, got %v", len(s.ServerPreferredResources), len(loaded.ServerPreferredResources))
+	}
+
+	if _, err := loaded.Client().Resolve("get", "deploy", ""); err != nil {
+		t.Fatalf("Failed to resolve kind from the snapshot discovery - %v", err)
+	}
+
+	if _, err := loaded.Permissions(); err != nil {
+		t.Fatalf("Failed to create permissions - %v", err)
+	}
+}
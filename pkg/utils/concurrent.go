@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/errors"
+)
+
+// RunConcurrently runs the functions concurrently and returns the aggregate of their errors
+func RunConcurrently(fns ...func() error) error {
+	errs := make([]error, len(fns))
+
+	var wg sync.WaitGroup
+	for i, fn := range fns {
+		wg.Add(1)
+		go func(i int, fn func() error) {
+			defer wg.Done()
+			errs[i] = fn()
+		}(i, fn)
+	}
+	wg.Wait()
+
+	return errors.NewAggregate(errs)
+}
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/yaml"
+)
+
+// ObjectLocation is where a resource is defined
+type ObjectLocation struct {
+	File string
+	//The first line of the resource document (1-based)
+	Line int
+}
+
+// ObjectLocationKey identifies a resource by kind, namespace and name
+func ObjectLocationKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%v/%v/%v", kind, namespace, name)
+}
+
+type locatedObject struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+
+	Items []locatedObject `json:"items"`
+}
+
+// LocateObjects maps the resources of a manifest file or directory (recursively) to where they are defined - see ObjectLocationKey.
+// Resources read from stdin ('-') cannot be located.
+func LocateObjects(filename string) (map[string]ObjectLocation, error) {
+	locations := map[string]ObjectLocation{}
+
+	if filename == "-" {
+		return locations, nil
+	}
+
+	err := filepath.Walk(filename, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		if path != filename {
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".yaml", ".yml", ".json":
+			default:
+				return nil
+			}
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		locateDocuments(path, data, locations)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+// locateDocuments records the first line of each YAML document - the items of a List are located at the List
+func locateDocuments(path string, data []byte, locations map[string]ObjectLocation) {
+	add := func(doc []byte, line int) {
+		obj := locatedObject{}
+		if err := yaml.Unmarshal(doc, &obj); err != nil {
+			klog.V(6).Infof("Skipping document at %v:%v - %v", path, line, err)
+			return
+		}
+
+		for _, o := range append([]locatedObject{obj}, obj.Items...) {
+			if o.Kind == "" || o.Metadata.Name == "" {
+				continue
+			}
+
+			key := ObjectLocationKey(o.Kind, o.Metadata.Namespace, o.Metadata.Name)
+			if _, exist := locations[key]; !exist {
+				locations[key] = ObjectLocation{File: path, Line: line}
+			}
+		}
+	}
+
+	var doc bytes.Buffer
+	start := 1
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+
+		if strings.HasPrefix(text, "---") {
+			add(doc.Bytes(), start)
+			doc.Reset()
+			start = line + 1
+			continue
+		}
+
+		//The document starts at its first line of content
+		if doc.Len() == 0 && (strings.TrimSpace(text) == "" || strings.HasPrefix(strings.TrimSpace(text), "#")) {
+			start = line + 1
+			continue
+		}
+
+		doc.WriteString(text)
+		doc.WriteByte('\n')
+	}
+
+	add(doc.Bytes(), start)
+}
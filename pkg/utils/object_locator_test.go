@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"testing"
+)
+
+func Test__LocateObjects(t *testing.T) {
+	locations, err := LocateObjects("../../testdata/whocan")
+	if err != nil {
+		t.Fatalf("Failed to locate - %v", err)
+	}
+
+	l, found := locations[ObjectLocationKey("RoleBinding", "test", "read-secrets")]
+	if !found || l.File != "../../testdata/whocan/secret-reader.yaml" || l.Line != 23 {
+		t.Fatalf("Unexpected location %+v (found=%v)", l, found)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 
@@ -78,23 +79,80 @@ func ReadObjectList(r io.Reader) ([]runtime.Object, error) {
 	}
 }
 
+// ReadObjectsFromFile reads kubernetes resources from a file, a directory (recursively) or stdin ('-')
 func ReadObjectsFromFile(filename string) ([]runtime.Object, error) {
+	if filename == "-" {
+		return readObjects(os.Stdin, filename)
+	}
+
+	fstat, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if fstat.IsDir() {
+		return readObjectsFromDir(filename)
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readObjects(f, filename)
+}
+
+func readObjectsFromDir(dir string) ([]runtime.Object, error) {
 	objs := []runtime.Object{}
 
-	var tee io.Reader
-	var buf bytes.Buffer
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			klog.V(6).Infof("Skipping %v", path)
+			return nil
+		}
 
-	if filename != "-" {
-		f, err := os.Open(filename)
+		f, err := os.Open(path)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		defer f.Close()
-		tee = io.TeeReader(f, &buf)
-	} else {
-		tee = io.TeeReader(os.Stdin, &buf)
+
+		l, err := readObjects(f, path)
+		if err != nil {
+			//Directories may contain files which are not kubernetes resources
+			klog.V(5).Infof("Skipping %v - %v", path, err)
+			return nil
+		}
+
+		objs = append(objs, l...)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
+	klog.V(6).Infof("Loaded from directory %v resources %v", dir, len(objs))
+	return objs, nil
+}
+
+func readObjects(r io.Reader, filename string) ([]runtime.Object, error) {
+	objs := []runtime.Object{}
+
+	var buf bytes.Buffer
+	tee := io.TeeReader(r, &buf)
+
 	if l, err := ReadObjectList(tee); err == nil {
 		klog.V(6).Infof("Loaded from Object List %v resources", len(l))
 		objs = l
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Progress prints the number of objects listed per resource on a single, updating, stderr line.
+// Nothing is printed when stderr is not a terminal.
+type Progress struct {
+	mu        sync.Mutex
+	enabled   bool
+	printed   bool
+	resources []string
+	listed    map[string]int
+}
+
+func NewProgress() *Progress {
+	enabled := false
+	if fi, err := os.Stderr.Stat(); err == nil {
+		enabled = fi.Mode()&os.ModeCharDevice != 0
+	}
+
+	return &Progress{enabled: enabled, listed: map[string]int{}}
+}
+
+// Update records the number of objects of the resource listed so far
+func (p *Progress) Update(resource string, listed int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, exist := p.listed[resource]; !exist {
+		p.resources = append(p.resources, resource)
+	}
+	p.listed[resource] = listed
+
+	if !p.enabled {
+		return
+	}
+
+	counts := make([]string, 0, len(p.resources))
+	for _, r := range p.resources {
+		counts = append(counts, fmt.Sprintf("%v %v", p.listed[r], r))
+	}
+
+	fmt.Fprintf(os.Stderr, "\r%v %v", rbacToolPrefix("[RAPID7-INSIGHTCLOUDSEC]"), lineMsg("Listed "+strings.Join(counts, ", ")))
+	p.printed = true
+}
+
+// Done terminates the progress line
+func (p *Progress) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.printed {
+		fmt.Fprintln(os.Stderr)
+		p.printed = false
+	}
+}
@@ -0,0 +1,442 @@
+package visualize
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/sprig"
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	"github.com/alcideio/rbac-tool/pkg/analysis"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+// AnalysisHtmlReport renders an analysis report as a self-contained HTML page - a severity summary, the findings by rule
+// and by subject, the exclusions and the RBAC subgraph of each flagged subject
+type AnalysisHtmlReport struct {
+	//What was analyzed (e.g. the cluster context or the manifests)
+	Title string
+
+	Report *analysis.AnalysisReport
+
+	//The RBAC resources the report was generated from - the subject subgraphs are skipped when not set
+	Permissions *rbac.Permissions
+}
+
+type analysisPage struct {
+	Title      string
+	ConfigName string
+	CreatedOn  string
+	Stats      string
+
+	Severities []analysisPageSeverity
+	Exclusions []analysisPageExclusion
+	Rules      []analysis.RuleSummary
+	Findings   []analysisPageFinding
+	Subjects   []*analysisPageSubject
+
+	//The DOT source of the subject subgraphs
+	Graphs []string
+}
+
+type analysisPageSeverity struct {
+	Severity string
+	Count    int
+}
+
+type analysisPageFinding struct {
+	Severity       string
+	Rank           int
+	RuleName       string
+	Kind           string
+	Namespace      string
+	Name           string
+	Message        string
+	Recommendation string
+	References     []string
+	GrantedBy      []string
+	SubjectId      string
+}
+
+type analysisPageSubject struct {
+	Id        string
+	Kind      string
+	Namespace string
+	Name      string
+	Severity  string
+	Rank      int
+	Findings  []analysisPageFinding
+
+	//The index of the subject subgraph - -1 when there is none
+	Graph int
+}
+
+type analysisPageExclusion struct {
+	RuleName  string
+	Kind      string
+	Namespace string
+	Name      string
+	Comment   string
+	Message   string
+	GrantedBy []string
+}
+
+// Generate renders the HTML page
+func (r *AnalysisHtmlReport) Generate() (string, error) {
+	funcs := sprig.HtmlFuncMap()
+	funcs["severityClass"] = severityClass
+	funcs["severityRank"] = analysis.SeverityRank
+
+	tmpl, err := template.New("analysis").Funcs(funcs).Parse(analysisReportTemplate)
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse the analysis report template - %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, r.newPage()); err != nil {
+		return "", fmt.Errorf("Failed to render the analysis report - %v", err)
+	}
+
+	body := buf.String()
+	report := HtmlReport{
+		body: func() string { return body },
+	}
+
+	return report.Generate()
+}
+
+func (r *AnalysisHtmlReport) newPage() *analysisPage {
+	report := r.Report
+
+	page := &analysisPage{
+		Title:      r.Title,
+		ConfigName: report.AnalysisConfigInfo.Name,
+		CreatedOn:  report.CreatedOn,
+		Stats:      analysis.StatsSummary(report),
+		Rules:      analysis.Summarize(map[string]*analysis.AnalysisReport{"": report}),
+		Graphs:     []string{},
+	}
+
+	counts := map[string]int{}
+	for _, f := range report.Findings {
+		counts[strings.ToUpper(f.Finding.Severity)]++
+	}
+
+	for _, severity := range []string{analysis.SEVERITY_CRIT, analysis.SEVERITY_HIGH, analysis.SEVERITY_MED, analysis.SEVERITY_LOW, analysis.SEVERITY_INFO} {
+		page.Severities = append(page.Severities, analysisPageSeverity{Severity: severity, Count: counts[severity]})
+	}
+
+	subjects := map[string]*analysisPageSubject{}
+	grantedBy := map[string][]rbac.BindingRef{}
+
+	for _, f := range report.Findings {
+		key := subjectKey(*f.Subject)
+
+		s, exist := subjects[key]
+		if !exist {
+			s = &analysisPageSubject{Kind: f.Subject.Kind, Namespace: f.Subject.Namespace, Name: f.Subject.Name, Rank: -1, Graph: -1}
+			subjects[key] = s
+			page.Subjects = append(page.Subjects, s)
+		}
+
+		finding := analysisPageFinding{
+			Severity:       strings.ToUpper(f.Finding.Severity),
+			Rank:           analysis.SeverityRank(f.Finding.Severity),
+			RuleName:       f.Finding.RuleName,
+			Kind:           f.Subject.Kind,
+			Namespace:      f.Subject.Namespace,
+			Name:           f.Subject.Name,
+			Message:        f.Finding.Message,
+			Recommendation: f.Finding.Recommendation,
+			References:     f.Finding.References,
+			GrantedBy:      bindingNames(f.GrantedBy),
+		}
+
+		if finding.Rank > s.Rank {
+			s.Rank = finding.Rank
+			s.Severity = finding.Severity
+		}
+
+		s.Findings = append(s.Findings, finding)
+		grantedBy[key] = append(grantedBy[key], f.GrantedBy...)
+	}
+
+	// The most severe subjects first
+	sort.SliceStable(page.Subjects, func(i, j int) bool {
+		si, sj := page.Subjects[i], page.Subjects[j]
+		if si.Rank != sj.Rank {
+			return si.Rank > sj.Rank
+		}
+
+		return subjectKey(rbacv1.Subject{Kind: si.Kind, Namespace: si.Namespace, Name: si.Name}) <
+			subjectKey(rbacv1.Subject{Kind: sj.Kind, Namespace: sj.Namespace, Name: sj.Name})
+	})
+
+	for i, s := range page.Subjects {
+		s.Id = fmt.Sprintf("subject-%v", i)
+
+		sort.SliceStable(s.Findings, func(i, j int) bool {
+			return s.Findings[i].Rank > s.Findings[j].Rank
+		})
+
+		for j := range s.Findings {
+			s.Findings[j].SubjectId = s.Id
+			page.Findings = append(page.Findings, s.Findings[j])
+		}
+
+		if r.Permissions != nil {
+			subject := rbacv1.Subject{Kind: s.Kind, Namespace: s.Namespace, Name: s.Name}
+			g := SubjectGraph(r.Permissions, subject, uniqueBindings(grantedBy[subjectKey(subject)]))
+
+			s.Graph = len(page.Graphs)
+			page.Graphs = append(page.Graphs, g.String())
+		}
+	}
+
+	for _, e := range report.ExclusionsInfo {
+		page.Exclusions = append(page.Exclusions, analysisPageExclusion{
+			RuleName:  e.RuleName,
+			Kind:      e.Subject.Kind,
+			Namespace: e.Subject.Namespace,
+			Name:      e.Subject.Name,
+			Comment:   e.Comment,
+			Message:   e.Message,
+			GrantedBy: bindingNames(e.GrantedBy),
+		})
+	}
+
+	return page
+}
+
+// severityClass maps the severity to the Bootstrap contextual class
+func severityClass(severity string) string {
+	switch strings.ToUpper(severity) {
+	case analysis.SEVERITY_CRIT:
+		return "danger"
+	case analysis.SEVERITY_HIGH:
+		return "warning"
+	case analysis.SEVERITY_MED:
+		return "info"
+	case analysis.SEVERITY_LOW:
+		return "light"
+	default:
+		return "secondary"
+	}
+}
+
+func subjectKey(subject rbacv1.Subject) string {
+	return fmt.Sprintf("%v/%v/%v", subject.Kind, subject.Namespace, subject.Name)
+}
+
+func bindingNames(bindings []rbac.BindingRef) []string {
+	names := make([]string, 0, len(bindings))
+	for _, b := range bindings {
+		names = append(names, b.String())
+	}
+
+	return names
+}
+
+func uniqueBindings(bindings []rbac.BindingRef) []rbac.BindingRef {
+	seen := map[rbac.BindingRef]bool{}
+	result := []rbac.BindingRef{}
+
+	for _, b := range bindings {
+		if seen[b] {
+			continue
+		}
+
+		seen[b] = true
+		result = append(result, b)
+	}
+
+	return result
+}
+
+const analysisReportTemplate = `
+<style>
+  .sortable th { cursor: pointer; white-space: nowrap; }
+  .sortable th[data-order="asc"]::after { content: " \25B2"; }
+  .sortable th[data-order="desc"]::after { content: " \25BC"; }
+  .subject-graph svg { height: auto; width: 100%; }
+  details.subject { border: 1px solid #dee2e6; border-radius: 4px; padding: 8px 16px; margin-bottom: 8px; }
+  details.subject summary { font-weight: 600; }
+</style>
+
+<div class="container-fluid px-5">
+  <h3>RBAC Analysis{{ if .Title }} - {{ .Title }}{{ end }}</h3>
+  <p class="text-muted">{{ .ConfigName }} | {{ .CreatedOn }}<br>{{ .Stats }}</p>
+
+  <div class="row mb-4">
+    {{- range .Severities }}
+    <div class="col-md-2">
+      <div class="card text-center {{ severityClass .Severity | printf "border-%s" }}">
+        <div class="card-body">
+          <h2 class="{{ severityClass .Severity | printf "text-%s" }}">{{ .Count }}</h2>
+          <span>{{ .Severity }}</span>
+        </div>
+      </div>
+    </div>
+    {{- end }}
+    <div class="col-md-2">
+      <div class="card text-center">
+        <div class="card-body">
+          <h2 class="text-muted">{{ len .Exclusions }}</h2>
+          <span>EXCLUDED</span>
+        </div>
+      </div>
+    </div>
+  </div>
+
+  <h4>Findings by Rule</h4>
+  <table class="table table-sm table-hover sortable" id="rules">
+    <thead><tr><th onclick="rbacSortTable(this)">SEVERITY</th><th onclick="rbacSortTable(this)">RULE</th><th onclick="rbacSortTable(this)">FINDINGS</th></tr></thead>
+    <tbody>
+    {{- range .Rules }}
+      <tr>
+        <td data-sort="{{ severityRank .Severity }}"><span class="badge badge-{{ severityClass .Severity }}">{{ .Severity }}</span></td>
+        <td>{{ .RuleName }}</td>
+        <td data-sort="{{ .Findings }}">{{ .Findings }}</td>
+      </tr>
+    {{- end }}
+    </tbody>
+  </table>
+
+  <h4 class="mt-4">Findings</h4>
+  <div class="form-inline mb-2">
+    <input type="text" class="form-control form-control-sm mr-2" id="findings-filter" placeholder="Filter" onkeyup="rbacFilterTable('findings')">
+    <select class="form-control form-control-sm" id="findings-severity" onchange="rbacFilterTable('findings')">
+      <option value="">All severities</option>
+      {{- range .Severities }}
+      <option value="{{ .Severity }}">{{ .Severity }}</option>
+      {{- end }}
+    </select>
+  </div>
+  <table class="table table-sm table-hover sortable" id="findings">
+    <thead><tr>
+      <th onclick="rbacSortTable(this)">SEVERITY</th><th onclick="rbacSortTable(this)">RULE</th><th onclick="rbacSortTable(this)">TYPE</th>
+      <th onclick="rbacSortTable(this)">NAMESPACE</th><th onclick="rbacSortTable(this)">SUBJECT</th><th onclick="rbacSortTable(this)">INFO</th>
+      <th onclick="rbacSortTable(this)">GRANTED BY</th>
+    </tr></thead>
+    <tbody>
+    {{- range .Findings }}
+      <tr data-severity="{{ .Severity }}">
+        <td data-sort="{{ .Rank }}"><span class="badge badge-{{ severityClass .Severity }}">{{ .Severity }}</span></td>
+        <td>{{ .RuleName }}</td>
+        <td>{{ .Kind }}</td>
+        <td>{{ .Namespace }}</td>
+        <td><a href="#{{ .SubjectId }}" onclick="rbacOpenSubject('{{ .SubjectId }}')">{{ .Name }}</a></td>
+        <td>{{ .Message }}</td>
+        <td>{{ range .GrantedBy }}{{ . }}<br>{{ end }}</td>
+      </tr>
+    {{- end }}
+    </tbody>
+  </table>
+
+  <h4 class="mt-4">Findings by Subject</h4>
+  {{- range .Subjects }}
+  <details class="subject" id="{{ .Id }}" ontoggle="rbacRenderGraph(this, {{ .Graph }})">
+    <summary><span class="badge badge-{{ severityClass .Severity }}">{{ .Severity }}</span> {{ .Kind }} {{ if .Namespace }}{{ .Namespace }}/{{ end }}{{ .Name }} ({{ len .Findings }})</summary>
+    {{- range .Findings }}
+    <div class="mt-3">
+      <h6><span class="badge badge-{{ severityClass .Severity }}">{{ .Severity }}</span> {{ .RuleName }}</h6>
+      <p class="mb-1">{{ .Message }}</p>
+      <p class="mb-1 text-muted">{{ .Recommendation }}</p>
+      {{- if .GrantedBy }}
+      <p class="mb-1"><small>Granted by {{ join ", " .GrantedBy }}</small></p>
+      {{- end }}
+      {{- range .References }}
+      <small><a href="{{ . }}" target="_blank">{{ . }}</a></small><br>
+      {{- end }}
+    </div>
+    {{- end }}
+    {{- if ge .Graph 0 }}
+    <div class="subject-graph mt-3" id="graph-{{ .Id }}"></div>
+    {{- end }}
+  </details>
+  {{- end }}
+
+  <h4 class="mt-4">Exclusions</h4>
+  {{- if .Exclusions }}
+  <div class="form-inline mb-2">
+    <input type="text" class="form-control form-control-sm" id="exclusions-filter" placeholder="Filter" onkeyup="rbacFilterTable('exclusions')">
+  </div>
+  <table class="table table-sm table-hover sortable" id="exclusions">
+    <thead><tr>
+      <th onclick="rbacSortTable(this)">RULE</th><th onclick="rbacSortTable(this)">TYPE</th><th onclick="rbacSortTable(this)">NAMESPACE</th>
+      <th onclick="rbacSortTable(this)">SUBJECT</th><th onclick="rbacSortTable(this)">COMMENT</th><th onclick="rbacSortTable(this)">GRANTED BY</th>
+    </tr></thead>
+    <tbody>
+    {{- range .Exclusions }}
+      <tr>
+        <td>{{ .RuleName }}</td>
+        <td>{{ .Kind }}</td>
+        <td>{{ .Namespace }}</td>
+        <td>{{ .Name }}</td>
+        <td>{{ .Comment }}</td>
+        <td>{{ range .GrantedBy }}{{ . }}<br>{{ end }}</td>
+      </tr>
+    {{- end }}
+    </tbody>
+  </table>
+  {{- else }}
+  <p class="text-muted">No findings were excluded</p>
+  {{- end }}
+</div>
+
+<script>
+var subjectGraphs = {{ .Graphs }};
+
+function rbacSortKey(cell) {
+  var key = cell.getAttribute("data-sort");
+  if (key === null) {
+    return cell.textContent.trim().toLowerCase();
+  }
+  return Number(key);
+}
+
+function rbacSortTable(th) {
+  var table = th.closest("table");
+  var column = Array.prototype.indexOf.call(th.parentNode.children, th);
+  var asc = th.getAttribute("data-order") !== "asc";
+
+  Array.prototype.forEach.call(th.parentNode.children, function (h) { h.removeAttribute("data-order"); });
+  th.setAttribute("data-order", asc ? "asc" : "desc");
+
+  var body = table.tBodies[0];
+  var rows = Array.prototype.slice.call(body.rows);
+  rows.sort(function (a, b) {
+    var x = rbacSortKey(a.cells[column]), y = rbacSortKey(b.cells[column]);
+    return (x < y ? -1 : x > y ? 1 : 0) * (asc ? 1 : -1);
+  });
+  rows.forEach(function (row) { body.appendChild(row); });
+}
+
+function rbacFilterTable(id) {
+  var text = document.getElementById(id + "-filter").value.toLowerCase();
+  var severity = document.getElementById(id + "-severity");
+  severity = severity ? severity.value : "";
+
+  Array.prototype.forEach.call(document.getElementById(id).tBodies[0].rows, function (row) {
+    var match = row.textContent.toLowerCase().indexOf(text) >= 0 &&
+      (severity === "" || row.getAttribute("data-severity") === severity);
+    row.style.display = match ? "" : "none";
+  });
+}
+
+function rbacRenderGraph(details, graph) {
+  if (!details.open || graph < 0 || details.getAttribute("data-rendered")) {
+    return;
+  }
+  details.setAttribute("data-rendered", "true");
+  d3.select("#graph-" + details.id).graphviz().zoom(true).renderDot(subjectGraphs[graph]);
+}
+
+function rbacOpenSubject(id) {
+  document.getElementById(id).open = true;
+}
+</script>
+`
@@ -0,0 +1,91 @@
+package visualize
+
+import (
+	"strings"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/analysis"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+func Test__AnalysisHtmlReport(t *testing.T) {
+	defer klog.Flush()
+
+	objs := []runtime.Object{
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret-reader", Namespace: "payments"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get", "list"}}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "read-secrets", Namespace: "payments"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "payments", Name: "api"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "secret-reader"},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-reader"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"get"}}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "read-nodes"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "system:serviceaccounts:payments"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "node-reader"},
+		},
+	}
+
+	perms, err := rbac.NewPermissionsFromResourceList(objs)
+	if err != nil {
+		t.Fatalf("Failed to create permissions - %v", err)
+	}
+
+	api := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: "payments", Name: "api"}
+
+	// The bindings of the subject - but not those of its groups
+	g := SubjectGraph(perms, api, nil).String()
+	if !strings.Contains(g, "read-secrets") || !strings.Contains(g, "secret-reader") || strings.Contains(g, "read-nodes") {
+		t.Fatalf("Expecting the bindings of the subject got\n%v", g)
+	}
+
+	// A binding granted through a group membership
+	g = SubjectGraph(perms, api, []rbac.BindingRef{{Kind: "ClusterRoleBinding", Name: "read-nodes"}}).String()
+	if !strings.Contains(g, "system:serviceaccounts:payments") || !strings.Contains(g, "member of") || strings.Contains(g, "read-secrets") {
+		t.Fatalf("Expecting the group binding got\n%v", g)
+	}
+
+	policies := rbac.NewSubjectPermissionsList(rbac.NewEffectiveSubjectPermissions(perms))
+	report, err := analysis.CreateAnalyzer(analysis.DefaultAnalysisConfig(), policies).Analyze()
+	if err != nil {
+		t.Fatalf("Failed to analyze - %v", err)
+	}
+
+	if len(report.Findings) == 0 {
+		t.Fatalf("Expecting findings")
+	}
+
+	htmlReport := AnalysisHtmlReport{Title: "manifests/", Report: report, Permissions: perms}
+	out, err := htmlReport.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate the report - %v", err)
+	}
+
+	for _, expected := range []string{"RBAC Analysis - manifests/", "Secret Readers", `id="subject-0"`, "var subjectGraphs = [", "RoleBinding&gt;&gt;payments/read-secrets"} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expecting '%v' in the report", expected)
+		}
+	}
+
+	// Without the RBAC resources there are no subgraphs
+	htmlReport.Permissions = nil
+	out, err = htmlReport.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate the report - %v", err)
+	}
+
+	if !strings.Contains(out, "var subjectGraphs = []") || strings.Contains(out, `id="graph-subject-0"`) {
+		t.Fatalf("Expecting no subgraphs in the report")
+	}
+}
@@ -0,0 +1,106 @@
+package visualize
+
+import (
+	"fmt"
+
+	"github.com/emicklei/dot"
+	"github.com/fatih/color"
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	"github.com/alcideio/rbac-tool/pkg/escalation"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+	"github.com/alcideio/rbac-tool/pkg/utils"
+)
+
+const (
+	clusterAdminColor = "#e33a1f"
+	escalationEdge    = "#9a9a9a"
+)
+
+// CreateEscalationGraph renders the escalation paths - the edges of the highest scored paths are highlighted
+func CreateEscalationGraph(paths []escalation.Path, outfile string, outformat string) error {
+	g := renderEscalationGraph(paths)
+
+	utils.ConsolePrinter(fmt.Sprintf("Generating Graph and Saving as '%v'", color.HiBlueString(outfile)))
+
+	return GenerateOutput(outfile, outformat, g, EscalationGraphLegend(), nil)
+}
+
+func renderEscalationGraph(paths []escalation.Path) *dot.Graph {
+	g := newGraph()
+	g.Attr("rankdir", "LR")
+
+	topScore := 0
+	for _, p := range paths {
+		if p.Score > topScore {
+			topScore = p.Score
+		}
+	}
+
+	admin := newClusterAdminNode(g)
+
+	for _, p := range paths {
+		highlight := p.Score == topScore
+
+		from := newEscalationSubjectNode(g, p.Subject, highlight)
+
+		for _, step := range p.Steps {
+			to := admin
+			if step.To != nil {
+				to = newEscalationSubjectNode(g, *step.To, highlight)
+			}
+
+			label := step.Description
+			if step.GrantedBy.Name != "" {
+				label = fmt.Sprintf("%v\n(%v)", label, step.GrantedBy.String())
+			}
+
+			e := edge(from, to)
+			e.Attr("label", label).
+				Attr("fontsize", "10").
+				Attr("fontname", fontName)
+
+			if highlight {
+				e.Attr("color", redOutline).Attr("penwidth", "2.0")
+			} else if e.GetAttr("color") == nil {
+				e.Attr("color", escalationEdge)
+			}
+
+			from = to
+		}
+	}
+
+	return g
+}
+
+func newEscalationSubjectNode(g *dot.Graph, subject rbacv1.Subject, highlight bool) dot.Node {
+	return newSubjectNode0(g, subject.Kind, rbac.SubjectName(subject), true, highlight)
+}
+
+func newClusterAdminNode(g *dot.Graph) dot.Node {
+	return g.Node("cluster-admin").
+		Attr("label", formatLabel("cluster-admin", true)).
+		Attr("shape", "doubleoctagon").
+		Attr("style", "filled").
+		Attr("penwidth", "2.0").
+		Attr("fillcolor", clusterAdminColor).
+		Attr("color", roleColorOutline).
+		Attr("fontcolor", "white").
+		Attr("fontname", fontName)
+}
+
+// EscalationGraphLegend describes the escalation graph nodes and edges
+func EscalationGraphLegend() *dot.Graph {
+	g := newGraph()
+
+	legend := g.Subgraph("LEGEND", dot.ClusterOption{})
+
+	subject := newSubjectNode0(legend, "Kind", "Subject", true, false)
+	start := newSubjectNode0(legend, "Kind", "Easiest Escalation", true, true)
+	admin := newClusterAdminNode(legend)
+
+	edge(subject, admin).Attr("label", "step\n(granting binding)").Attr("fontsize", "10").Attr("color", escalationEdge)
+	edge(start, admin).Attr("label", "highest scored path").Attr("fontsize", "10").Attr("color", redOutline).Attr("penwidth", "2.0")
+
+	return g
+}
@@ -40,6 +40,9 @@ type HtmlReport struct {
 	Legend  *dot.Graph
 	opts    *Opts
 	counter int64
+
+	//Renders the body instead of the graph and its legend (optional)
+	body func() string
 }
 
 func (r *HtmlReport) generateHeader() string {
@@ -163,6 +166,10 @@ func (r *HtmlReport) generateFooter() string {
 }
 
 func (r *HtmlReport) generateBody() string {
+	if r.body != nil {
+		return r.body()
+	}
+
 	data := `
 		<div class="container-fluid">
 			<div class="row">
@@ -9,11 +9,13 @@ import (
 	"github.com/fatih/color"
 	v1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog"
 
 	"github.com/alcideio/rbac-tool/pkg/kube"
 	"github.com/alcideio/rbac-tool/pkg/rbac"
+	"github.com/alcideio/rbac-tool/pkg/snapshot"
 	"github.com/alcideio/rbac-tool/pkg/utils"
 )
 
@@ -81,25 +83,29 @@ func (r *RbacViz) initialize(opts *Opts) error {
 				return err
 			}
 
-			for _, pod := range pods {
-				if r.permissions.Pods[pod.Namespace] == nil {
-					r.permissions.Pods[pod.Namespace] = make(map[string]v1.Pod)
-				}
-
-				r.permissions.Pods[pod.Namespace][pod.Name] = pod
-
-				r.permissions.ServiceAccountsUsed.Insert(fmt.Sprintf("%s/%s", pod.Namespace, pod.Spec.ServiceAccountName))
-				klog.V(6).Infof("Pod %v/%v use ServiceAccount %v/%v", pod.Namespace, pod.Name, pod.Namespace, pod.Spec.ServiceAccountName)
-			}
+			r.addPods(pods)
 		}
 
 	} else {
 		utils.ConsolePrinter(fmt.Sprintf("Loading Resources from '%v'", color.HiBlueString(opts.Infile)))
 
-		// Load from file/stdin
-		objs, err := utils.ReadObjectsFromFile(opts.Infile)
-		if err != nil {
-			return err
+		var objs []runtime.Object
+		var err error
+
+		if snapshot.IsSnapshot(opts.Infile) {
+			// Replay a snapshot archive
+			snap, err := snapshot.Load(opts.Infile)
+			if err != nil {
+				return err
+			}
+
+			objs = snap.Objects
+		} else {
+			// Load from file/stdin
+			objs, err = utils.ReadObjectsFromFile(opts.Infile)
+			if err != nil {
+				return err
+			}
 		}
 
 		klog.V(5).Infof("Loaded %v resources", len(objs))
@@ -112,6 +118,17 @@ func (r *RbacViz) initialize(opts *Opts) error {
 		r.permissions.Permissions = *perms
 		r.permissions.Pods = make(map[string]map[string]v1.Pod)
 		r.permissions.ServiceAccountsUsed = sets.NewString()
+
+		if opts.ShowPodsOnly {
+			pods := []v1.Pod{}
+			for _, obj := range objs {
+				if pod, ok := obj.(*v1.Pod); ok {
+					pods = append(pods, *pod)
+				}
+			}
+
+			r.addPods(pods)
+		}
 	}
 
 	var err error
@@ -124,6 +141,19 @@ func (r *RbacViz) initialize(opts *Opts) error {
 	return nil
 }
 
+func (r *RbacViz) addPods(pods []v1.Pod) {
+	for _, pod := range pods {
+		if r.permissions.Pods[pod.Namespace] == nil {
+			r.permissions.Pods[pod.Namespace] = make(map[string]v1.Pod)
+		}
+
+		r.permissions.Pods[pod.Namespace][pod.Name] = pod
+
+		r.permissions.ServiceAccountsUsed.Insert(fmt.Sprintf("%s/%s", pod.Namespace, pod.Spec.ServiceAccountName))
+		klog.V(6).Infof("Pod %v/%v use ServiceAccount %v/%v", pod.Namespace, pod.Name, pod.Namespace, pod.Spec.ServiceAccountName)
+	}
+}
+
 func (r *RbacViz) isBindingUsed(binding rbacv1.RoleBinding) bool {
 	klog.V(5).Infof(">>> [process][ClusterRole/Role Binding %v/%v]", binding.Namespace, binding.Name)
 	for _, subject := range binding.Subjects {
@@ -0,0 +1,120 @@
+package visualize
+
+import (
+	"sort"
+
+	"github.com/emicklei/dot"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+// SubjectGraph renders the RBAC subgraph of a subject - the bindings that grant it access, their roles and rules.
+// When bindings are specified only these are rendered (e.g. the bindings behind a finding), otherwise every binding of the subject.
+func SubjectGraph(perms *rbac.Permissions, subject rbacv1.Subject, bindings []rbac.BindingRef) *dot.Graph {
+	r := RbacViz{
+		opts:        &Opts{ShowRules: true},
+		permissions: Permissions{Permissions: *perms},
+	}
+
+	return r.renderSubjectGraph(subject, bindings)
+}
+
+func (r *RbacViz) renderSubjectGraph(subject rbacv1.Subject, bindings []rbac.BindingRef) *dot.Graph {
+	g := newGraph()
+
+	subjectNode := newSubjectNode0(newNamespaceSubgraph(g, subject.Namespace), subject.Kind, subject.Name, r.subjectExists(subject.Kind, subject.Namespace, subject.Name), true)
+
+	if len(bindings) == 0 {
+		bindings = r.subjectBindings(subject)
+	}
+
+	//The groups the subject is a member of (e.g. system:serviceaccounts)
+	groups := sets.NewString(rbac.SubjectUser(subject, true).GetGroups()...)
+
+	for _, ref := range bindings {
+		binding, found := r.permissions.RoleBindings[ref.Namespace][ref.Name]
+		if !found {
+			continue
+		}
+
+		//
+		//  [Subject] <-- [Group] <----[Binding]--->[Role]--->[Rules]
+		//
+		nsSubGraph := newNamespaceSubgraph(g, binding.Namespace)
+
+		bindingNode := r.newBindingNode(nsSubGraph, binding)
+		roleNode, _ := r.newRoleAndRulesNodePair(nsSubGraph, binding.Namespace, binding.RoleRef)
+
+		newBindingToRoleEdge(bindingNode, roleNode)
+
+		bound := false
+		for _, s := range binding.Subjects {
+			switch {
+			case isSubject(s, subject, binding.Namespace):
+				newSubjectToBindingEdge(subjectNode, bindingNode)
+				bound = true
+
+			case s.Kind == rbacv1.GroupKind && groups.Has(s.Name):
+				groupNode := newSubjectNode0(g, s.Kind, s.Name, true, false)
+				edge(subjectNode, groupNode).Attr("dir", "back").Attr("style", "dashed").Attr("label", "member of")
+				newSubjectToBindingEdge(groupNode, bindingNode)
+				bound = true
+			}
+		}
+
+		//The binding was reported as granting the access - keep it connected
+		if !bound {
+			newSubjectToBindingEdge(subjectNode, bindingNode)
+		}
+	}
+
+	return g
+}
+
+// subjectBindings returns the bindings that name the subject, sorted
+func (r *RbacViz) subjectBindings(subject rbacv1.Subject) []rbac.BindingRef {
+	refs := []rbac.BindingRef{}
+
+	for _, bindings := range r.permissions.RoleBindings {
+		for _, binding := range bindings {
+			for _, s := range binding.Subjects {
+				if !isSubject(s, subject, binding.Namespace) {
+					continue
+				}
+
+				ref := rbac.BindingRef{Kind: "RoleBinding", Namespace: binding.Namespace, Name: binding.Name}
+				if binding.Namespace == "" {
+					ref = rbac.BindingRef{Kind: "ClusterRoleBinding", Name: binding.Name}
+				}
+				refs = append(refs, ref)
+				break
+			}
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].String() < refs[j].String()
+	})
+
+	return refs
+}
+
+// isSubject returns true when the binding subject is the subject - ServiceAccounts without a namespace default to the binding namespace
+func isSubject(bindingSubject rbacv1.Subject, subject rbacv1.Subject, bindingNamespace string) bool {
+	if bindingSubject.Kind != subject.Kind || bindingSubject.Name != subject.Name {
+		return false
+	}
+
+	if subject.Kind != rbacv1.ServiceAccountKind {
+		return true
+	}
+
+	ns := bindingSubject.Namespace
+	if ns == "" {
+		ns = bindingNamespace
+	}
+
+	return ns == subject.Namespace
+}
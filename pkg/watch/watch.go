@@ -0,0 +1,138 @@
+package watch
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/analysis"
+	"github.com/alcideio/rbac-tool/pkg/diff"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+const (
+	// PermissionsChanged - a subject gained or lost permissions, or is granted them through other bindings
+	PermissionsChanged = "PermissionsChanged"
+
+	// NewFinding - an analysis rule produced a finding that was not reported before the change
+	NewFinding = "NewFinding"
+)
+
+// Event is a structured change notification
+type Event struct {
+	Time metav1.Time `json:"time"`
+	Type string      `json:"type"`
+
+	//Set for PermissionsChanged events - the verbs/resources the subject gained (added) or lost (removed) and the granting bindings
+	Change *diff.SubjectDiff `json:"change,omitempty"`
+
+	//Set for NewFinding events
+	Finding *analysis.AnalysisReportFinding `json:"finding,omitempty"`
+}
+
+// Watcher turns Permissions model updates into events
+type Watcher struct {
+	implicitGroups bool
+
+	//nil when the analysis rules are not evaluated
+	analysisConfig *analysis.AnalysisConfig
+
+	policies []rbac.SubjectPolicyList
+	findings sets.String
+}
+
+// NewWatcher creates a watcher of the initial permissions - analysisConfig is optional
+func NewWatcher(perms *rbac.Permissions, implicitGroups bool, analysisConfig *analysis.AnalysisConfig) (*Watcher, error) {
+	w := &Watcher{
+		implicitGroups: implicitGroups,
+		analysisConfig: analysisConfig,
+		findings:       sets.NewString(),
+	}
+
+	w.policies = w.subjectPolicies(perms)
+
+	if analysisConfig != nil {
+		report, err := w.analyze()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range report.Findings {
+			w.findings.Insert(findingKey(&f))
+		}
+	}
+
+	return w, nil
+}
+
+// Update computes the events of the updated permissions - the subjects whose effective permissions changed,
+// followed by the new findings
+func (w *Watcher) Update(perms *rbac.Permissions) []Event {
+	now := metav1.NewTime(time.Now().UTC())
+	events := []Event{}
+
+	policies := w.subjectPolicies(perms)
+	changes := diff.DiffGrants(w.policies, policies)
+	w.policies = policies
+
+	for i := range changes {
+		events = append(events, Event{Time: now, Type: PermissionsChanged, Change: &changes[i]})
+	}
+
+	if w.analysisConfig == nil || len(changes) == 0 {
+		return events
+	}
+
+	report, err := w.analyze()
+	if err != nil {
+		klog.Errorf("Failed to analyze permissions - %v", err)
+		return events
+	}
+
+	findings := sets.NewString()
+	for i := range report.Findings {
+		f := report.Findings[i]
+		key := findingKey(&f)
+		findings.Insert(key)
+
+		if !w.findings.Has(key) {
+			events = append(events, Event{Time: now, Type: NewFinding, Finding: &f})
+		}
+	}
+	w.findings = findings
+
+	return events
+}
+
+// findingKey identifies a finding of a subject by the rule and the granting bindings - a finding granted through another binding is new
+func findingKey(f *analysis.AnalysisReportFinding) string {
+	bindings := make([]string, 0, len(f.GrantedBy))
+	for _, b := range f.GrantedBy {
+		bindings = append(bindings, b.String())
+	}
+	sort.Strings(bindings)
+
+	return f.Key() + "|" + strings.Join(bindings, ",")
+}
+
+func (w *Watcher) subjectPolicies(perms *rbac.Permissions) []rbac.SubjectPolicyList {
+	if w.implicitGroups {
+		return rbac.NewSubjectPermissionsList(rbac.NewEffectiveSubjectPermissions(perms))
+	}
+
+	return rbac.NewSubjectPermissionsList(rbac.NewSubjectPermissions(perms))
+}
+
+func (w *Watcher) analyze() (*analysis.AnalysisReport, error) {
+	analyzer := analysis.CreateAnalyzer(w.analysisConfig, w.policies)
+	if analyzer == nil {
+		return nil, fmt.Errorf("Failed to create analyzer")
+	}
+
+	return analyzer.Analyze()
+}
@@ -0,0 +1,155 @@
+package watch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/analysis"
+	"github.com/alcideio/rbac-tool/pkg/cache"
+	"github.com/alcideio/rbac-tool/pkg/diff"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+func Test__Watch(t *testing.T) {
+	defer klog.Flush()
+
+	client := fake.NewSimpleClientset(
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "secrets-reader"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}}},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "admin-all"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "reader"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "alice"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "secrets-reader"},
+		},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := cache.NewCache(client, 0)
+	c.Debounce = 10 * time.Millisecond
+
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("Failed to start cache - %v", err)
+	}
+
+	if len(c.Permissions().RoleBindings[""]) != 1 {
+		t.Fatalf("Expecting 1 ClusterRoleBinding in the initial permissions")
+	}
+
+	w, err := NewWatcher(c.Permissions(), false, analysis.DefaultAnalysisConfig())
+	if err != nil {
+		t.Fatalf("Failed to create watcher - %v", err)
+	}
+
+	updates := make(chan []Event, 1)
+	go c.Run(ctx, func(old *rbac.Permissions, updated *rbac.Permissions) {
+		updates <- w.Update(updated)
+	})
+
+	_, err = client.RbacV1().ClusterRoleBindings().Create(ctx, &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "bob-admin"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "bob"}},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "admin-all"},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create binding - %v", err)
+	}
+
+	var events []Event
+	select {
+	case events = <-updates:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Timed out waiting for a permissions change")
+	}
+
+	changes := 0
+	findings := 0
+	for _, e := range events {
+		switch e.Type {
+		case PermissionsChanged:
+			changes++
+			if e.Change.Name != "bob" || e.Change.Status != diff.SubjectAdded || len(e.Change.Added) != 1 {
+				t.Fatalf("Unexpected change %+v", e.Change)
+			}
+
+			if e.Change.Added[0].Verb != "*" || e.Change.Added[0].GrantedBy.Name != "bob-admin" {
+				t.Fatalf("Unexpected added rule %+v", e.Change.Added[0])
+			}
+		case NewFinding:
+			findings++
+			if e.Finding.Subject.Name != "bob" {
+				t.Fatalf("Unexpected finding %+v", e.Finding)
+			}
+		}
+	}
+
+	if changes != 1 {
+		t.Fatalf("Expecting 1 change got %v", changes)
+	}
+
+	if findings == 0 {
+		t.Fatalf("Expecting new findings of 'bob'")
+	}
+}
+
+func Test__WatchBindingMove(t *testing.T) {
+	defer klog.Flush()
+
+	role := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "secrets-reader"},
+		Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}}},
+	}
+
+	binding := func(name string) *rbacv1.ClusterRoleBinding {
+		return &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "alice"}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "secrets-reader"},
+		}
+	}
+
+	before, err := rbac.NewPermissionsFromResourceList([]runtime.Object{role, binding("reader")})
+	if err != nil {
+		t.Fatalf("Failed to create permissions - %v", err)
+	}
+
+	after, err := rbac.NewPermissionsFromResourceList([]runtime.Object{role, binding("reader-v2")})
+	if err != nil {
+		t.Fatalf("Failed to create permissions - %v", err)
+	}
+
+	w, err := NewWatcher(before, false, nil)
+	if err != nil {
+		t.Fatalf("Failed to create watcher - %v", err)
+	}
+
+	// The effective permissions are the same - but alice is granted them through another binding
+	events := w.Update(after)
+	if len(events) != 1 || events[0].Type != PermissionsChanged {
+		t.Fatalf("Expecting 1 change got %+v", events)
+	}
+
+	change := events[0].Change
+	if change.Name != "alice" || change.Status != diff.SubjectChanged || len(change.Added) != 1 || len(change.Removed) != 1 ||
+		change.Added[0].GrantedBy.Name != "reader-v2" || change.Removed[0].GrantedBy.Name != "reader" {
+		t.Fatalf("Unexpected change %+v", change)
+	}
+
+	if events := w.Update(after); len(events) != 0 {
+		t.Fatalf("Expecting no changes got %+v", events)
+	}
+}
@@ -0,0 +1,155 @@
+package whoami
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/authentication/v1"
+	authz "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/alcideio/rbac-tool/pkg/analysis"
+	"github.com/alcideio/rbac-tool/pkg/rbac"
+)
+
+// Access is what the current identity can do in a namespace
+type Access struct {
+	User *v1.UserInfo
+
+	Namespace string
+
+	//The effective rules in the namespace - cluster-wide rules included
+	Rules []rbacv1.PolicyRule
+
+	//The API server could not evaluate all the rules (e.g. rules of webhook authorizers)
+	Incomplete      bool   `json:",omitempty"`
+	EvaluationError string `json:",omitempty"`
+
+	//The RoleBindings and ClusterRoleBindings that grant rules to the identity - when the identity can read RBAC resources
+	Bindings []string `json:",omitempty"`
+
+	//The risky capabilities - findings of the analysis rules
+	Risks []analysis.AnalysisReportFinding
+}
+
+// SelfSubjectRules lists the rules the API server evaluates for the current identity in the namespace
+func SelfSubjectRules(client clientset.Interface, namespace string) (*authz.SubjectRulesReviewStatus, []rbacv1.PolicyRule, error) {
+	review, err := client.AuthorizationV1().SelfSubjectRulesReviews().Create(
+		context.Background(),
+		&authz.SelfSubjectRulesReview{
+			Spec: authz.SelfSubjectRulesReviewSpec{
+				Namespace: namespace,
+			},
+		},
+		metav1.CreateOptions{},
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to review the rules of the current identity - %v", err)
+	}
+
+	rules := []rbacv1.PolicyRule{}
+	for _, r := range review.Status.ResourceRules {
+		rules = append(rules, rbacv1.PolicyRule{
+			Verbs:         r.Verbs,
+			APIGroups:     r.APIGroups,
+			Resources:     r.Resources,
+			ResourceNames: r.ResourceNames,
+		})
+	}
+
+	for _, r := range review.Status.NonResourceRules {
+		rules = append(rules, rbacv1.PolicyRule{
+			Verbs:           r.Verbs,
+			NonResourceURLs: r.NonResourceURLs,
+		})
+	}
+
+	return &review.Status, rules, nil
+}
+
+// UserSubject is the RBAC subject of the user - a ServiceAccount for service account usernames
+func UserSubject(userInfo *v1.UserInfo) rbacv1.Subject {
+	if namespace, name, err := serviceaccount.SplitUsername(userInfo.Username); err == nil {
+		return rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: namespace, Name: name}
+	}
+
+	return rbacv1.Subject{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: userInfo.Username}
+}
+
+// Risks evaluates the rules of the user in the namespace with the analysis rules.
+// The rules of cluster-scoped resources and non-resource URLs are evaluated as cluster-wide rules.
+// The exclusions are ignored - the identity asked about itself.
+func Risks(userInfo *v1.UserInfo, namespace string, rules []rbacv1.PolicyRule, isNamespaced func(gr schema.GroupResource) bool, config *analysis.AnalysisConfig) ([]analysis.AnalysisReportFinding, error) {
+	policies := []rbac.SubjectPermissions{
+		{
+			Subject: UserSubject(userInfo),
+			Rules:   scopeRules(namespace, rules, isNamespaced),
+		},
+	}
+
+	noExclusions := *config
+	noExclusions.GlobalExclusions = nil
+	noExclusions.Rules = make([]analysis.Rule, len(config.Rules))
+	for i, r := range config.Rules {
+		r.Exclusions = nil
+		noExclusions.Rules[i] = r
+	}
+
+	analyzer := analysis.CreateAnalyzer(&noExclusions, rbac.NewSubjectPermissionsList(policies))
+	if analyzer == nil {
+		return nil, fmt.Errorf("Failed to create analyzer")
+	}
+
+	report, err := analyzer.Analyze()
+	if err != nil {
+		return nil, err
+	}
+
+	return report.Findings, nil
+}
+
+// scopeRules files the rules under the namespace - and the rules of cluster-scoped resources and non-resource URLs under "" (cluster-wide)
+func scopeRules(namespace string, rules []rbacv1.PolicyRule, isNamespaced func(gr schema.GroupResource) bool) map[string][]rbac.PolicyRule {
+	scoped := map[string][]rbac.PolicyRule{}
+
+	for _, r := range rules {
+		if len(r.NonResourceURLs) > 0 {
+			scoped[""] = append(scoped[""], rbac.PolicyRule{PolicyRule: r})
+			continue
+		}
+
+		for _, group := range r.APIGroups {
+			var namespaced, clusterScoped []string
+			for _, resource := range r.Resources {
+				//pods/exec is scoped like pods - wildcards and unknown resources are kept in the namespace
+				gr := schema.GroupResource{Group: group, Resource: strings.SplitN(resource, "/", 2)[0]}
+				if isNamespaced(gr) {
+					namespaced = append(namespaced, resource)
+				} else {
+					clusterScoped = append(clusterScoped, resource)
+				}
+			}
+
+			for _, split := range []struct {
+				namespace string
+				resources []string
+			}{{namespace, namespaced}, {"", clusterScoped}} {
+				if len(split.resources) == 0 {
+					continue
+				}
+
+				rule := *r.DeepCopy()
+				rule.APIGroups = []string{group}
+				rule.Resources = split.resources
+				scoped[split.namespace] = append(scoped[split.namespace], rbac.PolicyRule{PolicyRule: rule})
+			}
+		}
+	}
+
+	return scoped
+}
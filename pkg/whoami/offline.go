@@ -0,0 +1,335 @@
+package whoami
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/authentication/v1"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Credential expiry status
+const (
+	CredentialValid    = "Valid"
+	CredentialExpiring = "Expiring"
+	CredentialExpired  = "Expired"
+	//The credential has no expiry (e.g. legacy ServiceAccount tokens)
+	CredentialNoExpiry = "NoExpiry"
+)
+
+// Credentials is what can be learned about the identity of a kubeconfig context without contacting the API server
+type Credentials struct {
+	Context  string
+	Cluster  string
+	Server   string
+	AuthInfo string
+
+	//The user the API server would authenticate - when it can be derived from the credentials (client certificates and ServiceAccount tokens)
+	User *v1.UserInfo `json:",omitempty"`
+
+	Certificate  *CertificateInfo  `json:",omitempty"`
+	Token        *TokenInfo        `json:",omitempty"`
+	Exec         *ExecInfo         `json:",omitempty"`
+	AuthProvider *AuthProviderInfo `json:",omitempty"`
+
+	//Basic auth username
+	Username string `json:",omitempty"`
+
+	Impersonate       string   `json:",omitempty"`
+	ImpersonateGroups []string `json:",omitempty"`
+
+	//Expired credentials and credentials about to expire
+	Warnings []string `json:",omitempty"`
+}
+
+type CertificateInfo struct {
+	Subject       string
+	CommonName    string
+	Organizations []string `json:",omitempty"`
+	Issuer        string
+	NotBefore     time.Time
+	NotAfter      time.Time
+	Status        string
+}
+
+type TokenInfo struct {
+	//Opaque tokens (not a JWT) cannot be decoded
+	Opaque bool `json:",omitempty"`
+
+	Subject   string     `json:",omitempty"`
+	Issuer    string     `json:",omitempty"`
+	Audience  []string   `json:",omitempty"`
+	Groups    []string   `json:",omitempty"`
+	Email     string     `json:",omitempty"`
+	IssuedAt  *time.Time `json:",omitempty"`
+	ExpiresAt *time.Time `json:",omitempty"`
+
+	//Set for ServiceAccount tokens
+	ServiceAccount string `json:",omitempty"`
+
+	Status string `json:",omitempty"`
+}
+
+type ExecInfo struct {
+	APIVersion      string
+	Command         string
+	Args            []string `json:",omitempty"`
+	Env             []string `json:",omitempty"`
+	InteractiveMode string   `json:",omitempty"`
+}
+
+type AuthProviderInfo struct {
+	Name string
+
+	//The decoded id-token of the oidc auth provider
+	IDToken *TokenInfo `json:",omitempty"`
+}
+
+// InspectCredentials decodes the credentials of the kubeconfig context ("" is the current context) - nothing is sent to the API server.
+// Credentials that expire within the warning period are flagged.
+func InspectCredentials(clientConfig clientcmd.ClientConfig, context string, now time.Time, warnWithin time.Duration) (*Credentials, error) {
+	raw, err := clientConfig.RawConfig()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load kubeconfig - %v", err)
+	}
+
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load kubeconfig - %v", err)
+	}
+
+	creds := &Credentials{
+		Context:           context,
+		Server:            config.Host,
+		Username:          config.Username,
+		Impersonate:       config.Impersonate.UserName,
+		ImpersonateGroups: config.Impersonate.Groups,
+	}
+
+	if creds.Context == "" {
+		creds.Context = raw.CurrentContext
+	}
+
+	if kubeContext, exist := raw.Contexts[creds.Context]; exist {
+		creds.Cluster = kubeContext.Cluster
+		creds.AuthInfo = kubeContext.AuthInfo
+	}
+
+	if err := creds.inspectCertificate(config, now, warnWithin); err != nil {
+		return nil, err
+	}
+
+	if err := creds.inspectToken(config, now, warnWithin); err != nil {
+		return nil, err
+	}
+
+	if config.ExecProvider != nil {
+		creds.Exec = &ExecInfo{
+			APIVersion:      config.ExecProvider.APIVersion,
+			Command:         config.ExecProvider.Command,
+			Args:            config.ExecProvider.Args,
+			InteractiveMode: string(config.ExecProvider.InteractiveMode),
+		}
+
+		//Only the names - the values may be secrets
+		for _, env := range config.ExecProvider.Env {
+			creds.Exec.Env = append(creds.Exec.Env, env.Name)
+		}
+	}
+
+	if config.AuthProvider != nil {
+		creds.AuthProvider = &AuthProviderInfo{Name: config.AuthProvider.Name}
+
+		if idToken := config.AuthProvider.Config["id-token"]; idToken != "" {
+			creds.AuthProvider.IDToken = decodeToken(idToken, now, warnWithin)
+			creds.warn("auth-provider id-token", creds.AuthProvider.IDToken.Status, creds.AuthProvider.IDToken.ExpiresAt, now)
+		}
+	}
+
+	return creds, nil
+}
+
+func (c *Credentials) inspectCertificate(config *restclient.Config, now time.Time, warnWithin time.Duration) error {
+	data := config.CertData
+	if len(data) == 0 && config.CertFile != "" {
+		var err error
+		if data, err = os.ReadFile(config.CertFile); err != nil {
+			return fmt.Errorf("Failed to read client certificate - %v", err)
+		}
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("Failed to decode client certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("Failed to parse client certificate - %v", err)
+	}
+
+	c.Certificate = &CertificateInfo{
+		Subject:       cert.Subject.String(),
+		CommonName:    cert.Subject.CommonName,
+		Organizations: cert.Subject.Organization,
+		Issuer:        cert.Issuer.String(),
+		NotBefore:     cert.NotBefore,
+		NotAfter:      cert.NotAfter,
+		Status:        expiryStatus(&cert.NotAfter, now, warnWithin),
+	}
+
+	//The organizations are the groups of the user - the API server adds system:authenticated to every authenticated user
+	c.User = &v1.UserInfo{
+		Username: cert.Subject.CommonName,
+		Groups:   append(append([]string{}, cert.Subject.Organization...), user.AllAuthenticated),
+	}
+	c.warn("client certificate", c.Certificate.Status, &cert.NotAfter, now)
+
+	return nil
+}
+
+func (c *Credentials) inspectToken(config *restclient.Config, now time.Time, warnWithin time.Duration) error {
+	token := config.BearerToken
+	if token == "" && config.BearerTokenFile != "" {
+		data, err := os.ReadFile(config.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("Failed to read token file - %v", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	if token == "" {
+		return nil
+	}
+
+	c.Token = decodeToken(token, now, warnWithin)
+	c.warn("bearer token", c.Token.Status, c.Token.ExpiresAt, now)
+
+	// Token based authentication has preference over certificate auth
+	if c.Token.ServiceAccount != "" {
+		namespace, _, _ := serviceaccount.SplitUsername(c.Token.ServiceAccount)
+		c.User = &v1.UserInfo{
+			Username: c.Token.ServiceAccount,
+			Groups:   []string{serviceaccount.AllServiceAccountsGroup, serviceaccount.MakeNamespaceGroupName(namespace), user.AllAuthenticated},
+		}
+	} else if c.User != nil {
+		//The identity of the token is up to the API server authenticator
+		c.User = nil
+	}
+
+	return nil
+}
+
+func (c *Credentials) warn(credential string, status string, expiresAt *time.Time, now time.Time) {
+	switch status {
+	case CredentialExpired:
+		c.Warnings = append(c.Warnings, fmt.Sprintf("The %v expired on %v", credential, expiresAt.Format(time.RFC3339)))
+	case CredentialExpiring:
+		c.Warnings = append(c.Warnings, fmt.Sprintf("The %v expires in %v (%v)", credential, expiresAt.Sub(now).Round(time.Minute), expiresAt.Format(time.RFC3339)))
+	case CredentialNoExpiry:
+		c.Warnings = append(c.Warnings, fmt.Sprintf("The %v never expires", credential))
+	}
+}
+
+func expiryStatus(expiresAt *time.Time, now time.Time, warnWithin time.Duration) string {
+	switch {
+	case expiresAt == nil:
+		return CredentialNoExpiry
+	case !now.Before(*expiresAt):
+		return CredentialExpired
+	case expiresAt.Sub(now) <= warnWithin:
+		return CredentialExpiring
+	default:
+		return CredentialValid
+	}
+}
+
+// decodeToken decodes the claims of a JWT - the signature is not verified
+func decodeToken(token string, now time.Time, warnWithin time.Duration) *TokenInfo {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return &TokenInfo{Opaque: true}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return &TokenInfo{Opaque: true}
+	}
+
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return &TokenInfo{Opaque: true}
+	}
+
+	info := &TokenInfo{
+		Subject:   stringClaim(claims, "sub"),
+		Issuer:    stringClaim(claims, "iss"),
+		Email:     stringClaim(claims, "email"),
+		Audience:  stringsClaim(claims, "aud"),
+		Groups:    stringsClaim(claims, "groups"),
+		IssuedAt:  timeClaim(claims, "iat"),
+		ExpiresAt: timeClaim(claims, "exp"),
+	}
+
+	// Bound ServiceAccount tokens
+	if k8s, ok := claims["kubernetes.io"].(map[string]interface{}); ok {
+		if sa, ok := k8s["serviceaccount"].(map[string]interface{}); ok {
+			info.ServiceAccount = serviceaccount.MakeUsername(stringClaim(k8s, "namespace"), stringClaim(sa, "name"))
+		}
+	}
+
+	// Legacy ServiceAccount tokens
+	if name := stringClaim(claims, "kubernetes.io/serviceaccount/service-account.name"); name != "" {
+		info.ServiceAccount = serviceaccount.MakeUsername(stringClaim(claims, "kubernetes.io/serviceaccount/namespace"), name)
+	}
+
+	info.Status = expiryStatus(info.ExpiresAt, now, warnWithin)
+
+	return info
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
+
+// stringsClaim reads a claim that is either a string or a list of strings
+func stringsClaim(claims map[string]interface{}, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		l := []string{}
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				l = append(l, s)
+			}
+		}
+		return l
+	default:
+		return nil
+	}
+}
+
+func timeClaim(claims map[string]interface{}, name string) *time.Time {
+	v, ok := claims[name].(float64)
+	if !ok {
+		return nil
+	}
+
+	t := time.Unix(int64(v), 0).UTC()
+	return &t
+}
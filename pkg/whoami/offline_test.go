@@ -0,0 +1,128 @@
+package whoami
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func testCertificate(t *testing.T, notAfter time.Time) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key - %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "alice", Organization: []string{"devs", "auditors"}},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate - %v", err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to encode key - %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func testToken(t *testing.T, claims map[string]interface{}) string {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("Failed to encode claims - %v", err)
+	}
+
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2lnbmF0dXJl"
+}
+
+func Test__InspectCredentials(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters["prod"] = &clientcmdapi.Cluster{Server: "https://prod:6443"}
+	cert, key := testCertificate(t, now.Add(48*time.Hour))
+	config.AuthInfos["cert"] = &clientcmdapi.AuthInfo{ClientCertificateData: cert, ClientKeyData: key}
+	config.AuthInfos["sa"] = &clientcmdapi.AuthInfo{Token: testToken(t, map[string]interface{}{
+		"iss": "https://kubernetes.default.svc",
+		"sub": "system:serviceaccount:ci:deployer",
+		"aud": []string{"https://kubernetes.default.svc"},
+		"exp": now.Add(-time.Hour).Unix(),
+		"kubernetes.io": map[string]interface{}{
+			"namespace":      "ci",
+			"serviceaccount": map[string]interface{}{"name": "deployer"},
+		},
+	})}
+	config.AuthInfos["oidc"] = &clientcmdapi.AuthInfo{Token: testToken(t, map[string]interface{}{
+		"iss":    "https://login.example.com",
+		"sub":    "bob",
+		"aud":    "kubernetes",
+		"groups": []string{"admins"},
+		"exp":    now.Add(30 * 24 * time.Hour).Unix(),
+	})}
+	config.AuthInfos["exec"] = &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{
+		APIVersion:      "client.authentication.k8s.io/v1beta1",
+		Command:         "aws",
+		Args:            []string{"eks", "get-token"},
+		Env:             []clientcmdapi.ExecEnvVar{{Name: "AWS_PROFILE", Value: "secret"}},
+		InteractiveMode: clientcmdapi.IfAvailableExecInteractiveMode,
+	}}
+
+	for _, user := range []string{"cert", "sa", "oidc", "exec"} {
+		config.Contexts[user] = &clientcmdapi.Context{Cluster: "prod", AuthInfo: user}
+	}
+	config.CurrentContext = "cert"
+
+	inspect := func(context string) *Credentials {
+		clientConfig := clientcmd.NewNonInteractiveClientConfig(*config, context, &clientcmd.ConfigOverrides{}, nil)
+		creds, err := InspectCredentials(clientConfig, context, now, 7*24*time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to inspect '%v' - %v", context, err)
+		}
+		return creds
+	}
+
+	creds := inspect("")
+	if creds.Context != "cert" || creds.Server != "https://prod:6443" || creds.User == nil || creds.User.Username != "alice" ||
+		strings.Join(creds.User.Groups, ",") != "devs,auditors,system:authenticated" {
+		t.Fatalf("Unexpected credentials %+v", creds)
+	}
+
+	if creds.Certificate.Status != CredentialExpiring || len(creds.Warnings) != 1 {
+		t.Fatalf("Expecting the certificate to expire soon got %+v %v", creds.Certificate, creds.Warnings)
+	}
+
+	creds = inspect("sa")
+	if creds.User == nil || creds.User.Username != "system:serviceaccount:ci:deployer" || creds.Token.Status != CredentialExpired ||
+		strings.Join(creds.User.Groups, ",") != "system:serviceaccounts,system:serviceaccounts:ci,system:authenticated" {
+		t.Fatalf("Expecting an expired ServiceAccount token got %+v %+v", creds.User, creds.Token)
+	}
+
+	creds = inspect("oidc")
+	if creds.User != nil || creds.Token.Subject != "bob" || creds.Token.Audience[0] != "kubernetes" || creds.Token.Groups[0] != "admins" ||
+		creds.Token.Status != CredentialValid || len(creds.Warnings) != 0 {
+		t.Fatalf("Unexpected OIDC token %+v %v", creds.Token, creds.Warnings)
+	}
+
+	creds = inspect("exec")
+	if creds.Exec == nil || creds.Exec.Command != "aws" || strings.Join(creds.Exec.Env, ",") != "AWS_PROFILE" {
+		t.Fatalf("Unexpected exec plugin %+v", creds.Exec)
+	}
+}
@@ -13,7 +13,11 @@ import (
 	v1 "k8s.io/api/authentication/v1"
 	authz "k8s.io/api/authorization/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
 	clientset "k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/transport"
 	"k8s.io/klog"
 )
@@ -51,7 +55,13 @@ func extractToken(client *kube.KubeClient) (string, error) {
 		return "", err
 	}
 
-	_, err = k8sClient.AuthorizationV1().SelfSubjectAccessReviews().Create(
+	_ = selfSubjectAccessReview(k8sClient)
+
+	return tokenExtractor.token, nil
+}
+
+func selfSubjectAccessReview(k8sClient clientset.Interface) error {
+	_, err := k8sClient.AuthorizationV1().SelfSubjectAccessReviews().Create(
 		context.Background(),
 		&authz.SelfSubjectAccessReview{
 			Spec: authz.SelfSubjectAccessReviewSpec{
@@ -63,7 +73,38 @@ func extractToken(client *kube.KubeClient) (string, error) {
 		metav1.CreateOptions{},
 	)
 
-	return tokenExtractor.token, nil
+	return err
+}
+
+// impersonatedUserInfo is the user the API server acts as when impersonating - including the groups it adds
+func impersonatedUserInfo(impersonate restclient.ImpersonationConfig) *v1.UserInfo {
+	userInfo := &v1.UserInfo{
+		Username: impersonate.UserName,
+		Groups:   append([]string{}, impersonate.Groups...),
+	}
+
+	// Impersonated service accounts get their groups unless groups are impersonated as well
+	if namespace, _, err := serviceaccount.SplitUsername(impersonate.UserName); err == nil && len(impersonate.Groups) == 0 {
+		userInfo.Groups = append(userInfo.Groups, serviceaccount.AllServiceAccountsGroup, serviceaccount.MakeNamespaceGroupName(namespace))
+	}
+
+	group := user.AllAuthenticated
+	if impersonate.UserName == user.Anonymous {
+		group = user.AllUnauthenticated
+	}
+
+	if !sets.NewString(userInfo.Groups...).Has(group) {
+		userInfo.Groups = append(userInfo.Groups, group)
+	}
+
+	for k, v := range impersonate.Extra {
+		if userInfo.Extra == nil {
+			userInfo.Extra = map[string]v1.ExtraValue{}
+		}
+		userInfo.Extra[k] = v
+	}
+
+	return userInfo
 }
 
 func ExtractUserInfo(client *kube.KubeClient) (*v1.UserInfo, error) {
@@ -79,6 +120,16 @@ func ExtractUserInfo(client *kube.KubeClient) (*v1.UserInfo, error) {
 
 	klog.V(9).Infof("Config:\n%v\n", pretty.Sprint(config))
 
+	// The API server acts as the impersonated user - verify the impersonation is permitted
+	if config.Impersonate.UserName != "" {
+		if err := selfSubjectAccessReview(client.Client); err != nil {
+			return nil, fmt.Errorf("Failed to impersonate '%v' - %v", config.Impersonate.UserName, err)
+		}
+
+		klog.V(5).Infof("impersonating '%v'", config.Impersonate.UserName)
+		return impersonatedUserInfo(config.Impersonate), nil
+	}
+
 	// Token based authentication has preference over basic auth and certificate auth
 	if c.HasTokenAuth() {
 		if config.BearerTokenFile != "" {
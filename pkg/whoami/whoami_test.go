@@ -0,0 +1,113 @@
+package whoami
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/authentication/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/klog"
+
+	"github.com/alcideio/rbac-tool/pkg/analysis"
+	"github.com/alcideio/rbac-tool/pkg/kube"
+)
+
+func Test__ImpersonatedUserInfo(t *testing.T) {
+	tests := []struct {
+		impersonate restclient.ImpersonationConfig
+		groups      []string
+	}{
+		{
+			impersonate: restclient.ImpersonationConfig{UserName: "auditor"},
+			groups:      []string{"system:authenticated"},
+		},
+		{
+			impersonate: restclient.ImpersonationConfig{UserName: "auditor", Groups: []string{"readers", "system:authenticated"}},
+			groups:      []string{"readers", "system:authenticated"},
+		},
+		{
+			impersonate: restclient.ImpersonationConfig{UserName: "system:serviceaccount:payments:api"},
+			groups:      []string{"system:serviceaccounts", "system:serviceaccounts:payments", "system:authenticated"},
+		},
+		{
+			impersonate: restclient.ImpersonationConfig{UserName: "system:serviceaccount:payments:api", Groups: []string{"readers"}},
+			groups:      []string{"readers", "system:authenticated"},
+		},
+		{
+			impersonate: restclient.ImpersonationConfig{UserName: "system:anonymous"},
+			groups:      []string{"system:unauthenticated"},
+		},
+	}
+
+	for _, test := range tests {
+		userInfo := impersonatedUserInfo(test.impersonate)
+		if userInfo.Username != test.impersonate.UserName || !reflect.DeepEqual(userInfo.Groups, test.groups) {
+			t.Fatalf("Expecting '%v' with groups %v got %+v", test.impersonate.UserName, test.groups, userInfo)
+		}
+	}
+}
+
+func Test__Risks(t *testing.T) {
+	defer klog.Flush()
+
+	rules := []rbacv1.PolicyRule{
+		{Verbs: []string{"get", "list"}, APIGroups: []string{""}, Resources: []string{"secrets"}},
+		{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"configmaps"}},
+	}
+
+	isNamespaced := kube.NewOfflineClient(nil, nil).IsNamespaced
+
+	//kube-system is excluded by the default analysis config - but not when the identity asks about itself
+	userInfo := &v1.UserInfo{Username: "system:serviceaccount:kube-system:ci"}
+
+	subject := UserSubject(userInfo)
+	if subject.Kind != rbacv1.ServiceAccountKind || subject.Namespace != "kube-system" || subject.Name != "ci" {
+		t.Fatalf("Unexpected subject %+v", subject)
+	}
+
+	findings, err := Risks(userInfo, "kube-system", rules, isNamespaced, analysis.DefaultAnalysisConfig())
+	if err != nil {
+		t.Fatalf("Failed to analyze - %v", err)
+	}
+
+	if len(findings) != 1 || findings[0].Finding.RuleName != "Secret Readers" {
+		t.Fatalf("Expecting a 'Secret Readers' finding got %+v", findings)
+	}
+
+	findings, err = Risks(userInfo, "kube-system", rules[1:], isNamespaced, analysis.DefaultAnalysisConfig())
+	if err != nil || len(findings) != 0 {
+		t.Fatalf("Expecting no findings got %+v (%v)", findings, err)
+	}
+}
+
+func Test__ScopeRules(t *testing.T) {
+	rules := []rbacv1.PolicyRule{
+		{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"secrets", "nodes", "nodes/proxy", "pods/exec"}},
+		{Verbs: []string{"get"}, APIGroups: []string{"rbac.authorization.k8s.io"}, Resources: []string{"clusterroles"}, ResourceNames: []string{"admin"}},
+		{Verbs: []string{"get"}, NonResourceURLs: []string{"/metrics"}},
+	}
+
+	scoped := scopeRules("payments", rules, kube.NewOfflineClient(nil, nil).IsNamespaced)
+
+	resources := func(namespace string) []string {
+		res := []string{}
+		for _, r := range scoped[namespace] {
+			res = append(res, r.Resources...)
+			res = append(res, r.NonResourceURLs...)
+		}
+		return res
+	}
+
+	if got := resources("payments"); !reflect.DeepEqual(got, []string{"secrets", "pods/exec"}) {
+		t.Fatalf("Expecting the namespaced resources in the namespace got %v", got)
+	}
+
+	if got := resources(""); !reflect.DeepEqual(got, []string{"nodes", "nodes/proxy", "clusterroles", "/metrics"}) {
+		t.Fatalf("Expecting the cluster-scoped resources and the non-resource URLs cluster-wide got %v", got)
+	}
+
+	if names := scoped[""][1].ResourceNames; !reflect.DeepEqual(names, []string{"admin"}) {
+		t.Fatalf("Expecting the resource names to be kept got %v", names)
+	}
+}